@@ -0,0 +1,368 @@
+// Package client is a typed Go client for the wheel API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client talks to the wheel API over HTTP.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithToken sends token as a bearer token on every request.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient makes the client use httpClient instead of http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New creates a client for the API served at baseURL, e.g. "https://wheel.example.com".
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Team is a team of people sharing a rota.
+type Team struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Version   int32     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TeamWithPeople is a team along with its members. ETag is the entity tag
+// GetTeam read it with, to send along with changes based on it.
+type TeamWithPeople struct {
+	Team
+	People []Person `json:"people"`
+	ETag   string   `json:"-"`
+}
+
+// Person is a member of a team. ETag is the entity tag GetPerson or
+// UpdatePerson read it with, to send along with changes based on it, it's
+// empty for the people of lists.
+type Person struct {
+	ID             int64     `json:"id"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Email          string    `json:"email"`
+	TeamID         int64     `json:"team_id"`
+	EmailReminders bool      `json:"email_reminders"`
+	Version        int32     `json:"version"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	ETag           string    `json:"-"`
+}
+
+// PersonInput holds the fields of a new person, email reminders are on
+// unless EmailReminders is false.
+type PersonInput struct {
+	FirstName      string `json:"first_name"`
+	LastName       string `json:"last_name"`
+	Email          string `json:"email"`
+	EmailReminders *bool  `json:"email_reminders,omitempty"`
+}
+
+// PersonPatch holds the fields to change on a person, nil fields are left as they are.
+// Version is the version of the person the changes are based on, it's required.
+type PersonPatch struct {
+	FirstName      *string `json:"first_name,omitempty"`
+	LastName       *string `json:"last_name,omitempty"`
+	Email          *string `json:"email,omitempty"`
+	TeamID         *int64  `json:"team_id,omitempty"`
+	EmailReminders *bool   `json:"email_reminders,omitempty"`
+	Version        int32   `json:"version,omitempty"`
+}
+
+// Turn is the assignment of a person to a date.
+type Turn struct {
+	ID        int64     `json:"id"`
+	PersonID  int64     `json:"person_id"`
+	Date      time.Time `json:"date"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   int32     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Pick is the person the wheel picked, along with the turn they were
+// assigned unless it was a dry run.
+type Pick struct {
+	Person Person `json:"person"`
+	Turn   *Turn  `json:"turn,omitempty"`
+}
+
+// ListTurnsOptions filters and paginates ListTurns, zero values use the API defaults.
+type ListTurnsOptions struct {
+	Limit    int
+	Offset   int
+	DateFrom time.Time
+	DateTo   time.Time
+}
+
+// ListTeams lists all teams.
+func (c *Client) ListTeams(ctx context.Context) ([]Team, error) {
+	var teams []Team
+	err := c.do(ctx, http.MethodGet, "/api/teams", nil, &teams)
+	return teams, err
+}
+
+// GetTeam gets a team with its people and its ETag.
+func (c *Client) GetTeam(ctx context.Context, teamID int64) (*TeamWithPeople, error) {
+	team := &TeamWithPeople{}
+	tag, err := c.doIfMatch(ctx, http.MethodGet, teamPath(teamID), "", nil, team)
+	if err != nil {
+		return nil, err
+	}
+	team.ETag = tag
+	return team, nil
+}
+
+// AddTeam creates a team.
+func (c *Client) AddTeam(ctx context.Context, name string) (*Team, error) {
+	team := &Team{}
+	err := c.do(ctx, http.MethodPost, "/api/teams", map[string]string{"name": name}, team)
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// RenameTeam changes the name of a team read at version with the ETag etag,
+// as GetTeam returned them. It fails with a precondition_failed or
+// version_conflict error when the team changed since, an etag of "*" leaving
+// the check to the version.
+func (c *Client) RenameTeam(ctx context.Context, teamID int64, name string, version int32, etag string) (*Team, error) {
+	team := &Team{}
+	body := map[string]interface{}{"name": name, "version": version}
+	_, err := c.doIfMatch(ctx, http.MethodPut, teamPath(teamID), etag, body, team)
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// DeleteTeam deletes a team along with its people and turns, failing with a
+// precondition_failed error when the team changed since GetTeam returned
+// etag. An etag of "*" deletes it whatever its state.
+func (c *Client) DeleteTeam(ctx context.Context, teamID int64, etag string) error {
+	_, err := c.doIfMatch(ctx, http.MethodDelete, teamPath(teamID), etag, nil, nil)
+	return err
+}
+
+// ListPeople lists the people of a team.
+func (c *Client) ListPeople(ctx context.Context, teamID int64) ([]Person, error) {
+	var people []Person
+	err := c.do(ctx, http.MethodGet, teamPath(teamID)+"/people", nil, &people)
+	return people, err
+}
+
+// GetPerson gets a person of a team and its ETag.
+func (c *Client) GetPerson(ctx context.Context, teamID int64, personID int64) (*Person, error) {
+	person := &Person{}
+	tag, err := c.doIfMatch(ctx, http.MethodGet, personPath(teamID, personID), "", nil, person)
+	if err != nil {
+		return nil, err
+	}
+	person.ETag = tag
+	return person, nil
+}
+
+// AddPerson adds a person to a team.
+func (c *Client) AddPerson(ctx context.Context, teamID int64, input PersonInput) (*Person, error) {
+	person := &Person{}
+	err := c.do(ctx, http.MethodPost, teamPath(teamID)+"/people", input, person)
+	if err != nil {
+		return nil, err
+	}
+	return person, nil
+}
+
+// UpdatePerson applies patch to a person of a team, failing with a
+// version_required error when patch has no version and a version_conflict
+// error when the person changed since it.
+func (c *Client) UpdatePerson(ctx context.Context, teamID int64, personID int64, patch PersonPatch) (*Person, error) {
+	person := &Person{}
+	tag, err := c.doIfMatch(ctx, http.MethodPatch, personPath(teamID, personID), "", patch, person)
+	if err != nil {
+		return nil, err
+	}
+	person.ETag = tag
+	return person, nil
+}
+
+// DeletePerson removes a person from a team, failing with a
+// precondition_failed error when the person changed since GetPerson or
+// UpdatePerson returned etag. An etag of "*" removes it whatever its state.
+func (c *Client) DeletePerson(ctx context.Context, teamID int64, personID int64, etag string) error {
+	_, err := c.doIfMatch(ctx, http.MethodDelete, personPath(teamID, personID), etag, nil, nil)
+	return err
+}
+
+// ListTurns lists the turns of a team, most recent first.
+func (c *Client) ListTurns(ctx context.Context, teamID int64, options ListTurnsOptions) ([]Turn, error) {
+	query := url.Values{}
+	if options.Limit > 0 {
+		query.Set("limit", strconv.Itoa(options.Limit))
+	}
+	if options.Offset > 0 {
+		query.Set("offset", strconv.Itoa(options.Offset))
+	}
+	if !options.DateFrom.IsZero() {
+		query.Set("date_from", options.DateFrom.Format("2006-01-02"))
+	}
+	if !options.DateTo.IsZero() {
+		query.Set("date_to", options.DateTo.Format("2006-01-02"))
+	}
+
+	path := teamPath(teamID) + "/turns"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var turns []Turn
+	err := c.do(ctx, http.MethodGet, path, nil, &turns)
+	return turns, err
+}
+
+// AssignTurn assigns the next working day's turn of a team to a person.
+// version is the one of the turn already booked that day, 0 when there's
+// none. It fails with a version_required error when a turn is booked and
+// version is 0, the error's Current being the turn, and with a
+// version_conflict error when the turn changed since version.
+func (c *Client) AssignTurn(ctx context.Context, teamID int64, personID int64, version int32) (*Turn, error) {
+	body := struct {
+		PersonID int64 `json:"person_id"`
+		Version  int32 `json:"version,omitempty"`
+	}{PersonID: personID, Version: version}
+
+	turn := &Turn{}
+	err := c.do(ctx, http.MethodPost, teamPath(teamID)+"/turns", body, turn)
+	if err != nil {
+		return nil, err
+	}
+	return turn, nil
+}
+
+// PickTurn assigns the next working day's turn of a team to a random member
+// other than whoever had the latest turn, or only picks them when dryRun is
+// set. version is the one of the turn already booked that day, as for
+// AssignTurn.
+func (c *Client) PickTurn(ctx context.Context, teamID int64, version int32, dryRun bool) (*Pick, error) {
+	body := struct {
+		DryRun  bool  `json:"dry_run,omitempty"`
+		Version int32 `json:"version,omitempty"`
+	}{DryRun: dryRun, Version: version}
+
+	pick := &Pick{}
+	err := c.do(ctx, http.MethodPost, teamPath(teamID)+"/turns/pick", body, pick)
+	if err != nil {
+		return nil, err
+	}
+	return pick, nil
+}
+
+// do sends a request with in as JSON body and decodes the data member of
+// the response into out. Error responses are returned as *Error.
+func (c *Client) do(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	_, err := c.doIfMatch(ctx, method, path, "", in, out)
+	return err
+}
+
+// doIfMatch is do sending ifMatch as the If-Match header unless it's empty,
+// it returns the ETag of the response.
+func (c *Client) doIfMatch(
+	ctx context.Context,
+	method string,
+	path string,
+	ifMatch string,
+	in interface{},
+	out interface{},
+) (string, error) {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return "", err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		contentType := "application/json"
+		if method == http.MethodPatch {
+			contentType = "application/merge-patch+json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return "", newError(res)
+	}
+
+	tag := res.Header.Get("ETag")
+	if out == nil {
+		return tag, nil
+	}
+
+	envelope := struct {
+		Data interface{} `json:"data"`
+	}{Data: out}
+	if err = json.NewDecoder(res.Body).Decode(&envelope); err != nil {
+		return "", fmt.Errorf("cannot decode %s %s response: %w", method, path, err)
+	}
+	return tag, nil
+}
+
+// teamPath is the path of a team resource.
+func teamPath(teamID int64) string {
+	return "/api/teams/" + strconv.FormatInt(teamID, 10)
+}
+
+// personPath is the path of a person resource.
+func personPath(teamID int64, personID int64) string {
+	return teamPath(teamID) + "/people/" + strconv.FormatInt(personID, 10)
+}
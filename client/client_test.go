@@ -0,0 +1,658 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/handler"
+	"github.com/ezerw/wheel/util"
+)
+
+func TestTeams(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	team, err := c.AddTeam(ctx, "  Trading ")
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	if team.Name != "Trading" {
+		t.Errorf("AddTeam name = %q, want normalised %q", team.Name, "Trading")
+	}
+
+	_, err = c.AddTeam(ctx, "Trading")
+	if !IsConflict(err) || err.(*Error).Code != "team_name_taken" {
+		t.Errorf("AddTeam duplicate error = %v, want team_name_taken conflict", err)
+	}
+
+	read, err := c.GetTeam(ctx, team.ID)
+	if err != nil || read.ETag == "" {
+		t.Fatalf("GetTeam = %+v, %v, want the team with its ETag", read, err)
+	}
+
+	team, err = c.RenameTeam(ctx, team.ID, "Payments", read.Version, read.ETag)
+	if err != nil {
+		t.Fatalf("RenameTeam: %v", err)
+	}
+	if team.Name != "Payments" {
+		t.Errorf("RenameTeam name = %q, want %q", team.Name, "Payments")
+	}
+	if team.Version != 2 {
+		t.Errorf("RenameTeam version = %d, want 2", team.Version)
+	}
+
+	// Writes based on the team before the rename are stale.
+	err = c.DeleteTeam(ctx, team.ID, read.ETag)
+	if !hasStatus(err, http.StatusPreconditionFailed) {
+		t.Errorf("DeleteTeam with stale ETag error = %v, want precondition_failed", err)
+	}
+	_, err = c.RenameTeam(ctx, team.ID, "Lending", read.Version, read.ETag)
+	if !hasStatus(err, http.StatusPreconditionFailed) {
+		t.Errorf("RenameTeam with stale ETag error = %v, want precondition_failed", err)
+	}
+	_, err = c.RenameTeam(ctx, team.ID, "Lending", read.Version, "*")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusConflict || apiErr.Code != "version_conflict" {
+		t.Fatalf("RenameTeam at stale version error = %v, want version_conflict", err)
+	}
+	current := Team{}
+	if err = json.Unmarshal(apiErr.Current, &current); err != nil || current != *team {
+		t.Errorf("stale rename current = %s, want %+v", apiErr.Current, *team)
+	}
+
+	// The tag of a replacement is the one to send along with the next write.
+	read, err = c.GetTeam(ctx, team.ID)
+	if err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+	replaced := map[string]interface{}{"name": "Lending", "version": team.Version}
+	tag, err := c.doIfMatch(ctx, http.MethodPut, teamPath(team.ID), read.ETag, replaced, team)
+	if err != nil {
+		t.Fatalf("replace team: %v", err)
+	}
+	if read, _ = c.GetTeam(ctx, team.ID); tag == "" || tag != read.ETag {
+		t.Errorf("replace team ETag = %q, want the ETag of the team %q", tag, read.ETag)
+	}
+
+	teams, err := c.ListTeams(ctx)
+	if err != nil {
+		t.Fatalf("ListTeams: %v", err)
+	}
+	if len(teams) != 1 || teams[0] != *team {
+		t.Errorf("ListTeams = %+v, want [%+v]", teams, *team)
+	}
+
+	if err = c.DeleteTeam(ctx, team.ID, read.ETag); err != nil {
+		t.Fatalf("DeleteTeam: %v", err)
+	}
+
+	_, err = c.GetTeam(ctx, team.ID)
+	if !IsNotFound(err) || err.(*Error).Code != "team_not_found" {
+		t.Errorf("GetTeam after delete error = %v, want team_not_found", err)
+	}
+}
+
+func TestPeople(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	team, err := c.AddTeam(ctx, "Trading")
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+
+	_, err = c.AddPerson(ctx, team.ID, PersonInput{FirstName: "Bruce", Email: "not an email"})
+	if !IsValidation(err) {
+		t.Fatalf("AddPerson invalid error = %v, want validation error", err)
+	}
+	fields := map[string]string{}
+	for _, field := range err.(*Error).Errors {
+		fields[field.Field] = field.Code
+	}
+	if fields["last_name"] != "required" || fields["email"] != "invalid_email" {
+		t.Errorf("AddPerson field errors = %v, want last_name required and email invalid_email", fields)
+	}
+
+	person, err := c.AddPerson(ctx, team.ID, PersonInput{
+		FirstName: "Bruce",
+		LastName:  "Wayne",
+		Email:     "Not.Batman@VendHQ.com",
+	})
+	if err != nil {
+		t.Fatalf("AddPerson: %v", err)
+	}
+	if person.Email != "not.batman@vendhq.com" {
+		t.Errorf("AddPerson email = %q, want lower-cased", person.Email)
+	}
+	if !person.EmailReminders {
+		t.Error("AddPerson email_reminders = false, want on by default")
+	}
+
+	firstName, emailReminders := "Brucie", false
+	person, err = c.UpdatePerson(ctx, team.ID, person.ID, PersonPatch{
+		FirstName:      &firstName,
+		EmailReminders: &emailReminders,
+		Version:        person.Version,
+	})
+	if err != nil {
+		t.Fatalf("UpdatePerson: %v", err)
+	}
+	if person.FirstName != "Brucie" || person.LastName != "Wayne" || person.EmailReminders {
+		t.Errorf("UpdatePerson = %+v, want only first_name and email_reminders changed", person)
+	}
+
+	// Patches must tell which version they're based on.
+	lastName := "Kent"
+	_, err = c.UpdatePerson(ctx, team.ID, person.ID, PersonPatch{LastName: &lastName})
+	if !hasStatus(err, http.StatusUnprocessableEntity) || err.(*Error).Code != "version_required" {
+		t.Errorf("UpdatePerson without version error = %v, want version_required", err)
+	}
+	_, err = c.UpdatePerson(ctx, team.ID, person.ID, PersonPatch{LastName: &lastName, Version: 1})
+	if !IsConflict(err) || err.(*Error).Code != "version_conflict" {
+		t.Errorf("UpdatePerson at a stale version error = %v, want version_conflict", err)
+	}
+
+	withPeople, err := c.GetTeam(ctx, team.ID)
+	if err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+	listed := *person
+	listed.ETag = ""
+	if len(withPeople.People) != 1 || withPeople.People[0] != listed {
+		t.Errorf("GetTeam people = %+v, want [%+v]", withPeople.People, listed)
+	}
+
+	// The tag of an update is the one to delete it with.
+	if person.ETag == "" {
+		t.Error("UpdatePerson ETag is empty, want the tag of the person")
+	}
+	if err = c.DeletePerson(ctx, team.ID, person.ID, person.ETag); err != nil {
+		t.Fatalf("DeletePerson: %v", err)
+	}
+
+	_, err = c.GetPerson(ctx, team.ID, person.ID)
+	if !IsNotFound(err) {
+		t.Errorf("GetPerson after delete error = %v, want not found", err)
+	}
+}
+
+func TestReplacePerson(t *testing.T) {
+	store := newMemStore()
+	server, err := handler.NewServer(util.Config{AppTimezone: "UTC"}, store, util.NewLogger())
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	c := New(ts.URL)
+	ctx := context.Background()
+
+	trading, err := c.AddTeam(ctx, "Trading")
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	lending, err := c.AddTeam(ctx, "Lending")
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	off := false
+	person, err := c.AddPerson(ctx, trading.ID, PersonInput{
+		FirstName:      "Bruce",
+		LastName:       "Wayne",
+		Email:          "bruce@vendhq.com",
+		EmailReminders: &off,
+	})
+	if err != nil {
+		t.Fatalf("AddPerson: %v", err)
+	}
+	person, err = c.GetPerson(ctx, trading.ID, person.ID)
+	if err != nil {
+		t.Fatalf("GetPerson: %v", err)
+	}
+
+	// A replacement leaving email_reminders out keeps the current setting.
+	store.outbox = nil
+	replaced := map[string]interface{}{
+		"first_name": "Bruce",
+		"last_name":  "Wayne",
+		"email":      "bruce@vendhq.com",
+		"team_id":    lending.ID,
+		"version":    person.Version,
+	}
+	moved := &Person{}
+	_, err = c.doIfMatch(ctx, http.MethodPut, personPath(trading.ID, person.ID), person.ETag, replaced, moved)
+	if err != nil {
+		t.Fatalf("replace person: %v", err)
+	}
+	if moved.TeamID != lending.ID || moved.EmailReminders {
+		t.Errorf("replaced person = %+v, want moved to %d with email_reminders still off", moved, lending.ID)
+	}
+
+	// Both the team the person left and the one they joined are told.
+	teams := []int64{}
+	for _, event := range store.outbox {
+		if event.EventType == "person.updated" {
+			teams = append(teams, event.TeamID)
+		}
+	}
+	if len(teams) != 2 || teams[0] != trading.ID || teams[1] != lending.ID {
+		t.Errorf("person.updated recorded for teams %v, want [%d %d]", teams, trading.ID, lending.ID)
+	}
+}
+
+func TestTurns(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	team, err := c.AddTeam(ctx, "Trading")
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	bruce, err := c.AddPerson(ctx, team.ID, PersonInput{FirstName: "Bruce", LastName: "Wayne", Email: "bruce@vendhq.com"})
+	if err != nil {
+		t.Fatalf("AddPerson: %v", err)
+	}
+	diana, err := c.AddPerson(ctx, team.ID, PersonInput{FirstName: "Diana", LastName: "Prince", Email: "diana@vendhq.com"})
+	if err != nil {
+		t.Fatalf("AddPerson: %v", err)
+	}
+
+	turn, err := c.AssignTurn(ctx, team.ID, bruce.ID, 0)
+	if err != nil {
+		t.Fatalf("AssignTurn: %v", err)
+	}
+	if turn.PersonID != bruce.ID {
+		t.Errorf("AssignTurn person = %d, want %d", turn.PersonID, bruce.ID)
+	}
+
+	// Reassigning the booked turn requires its version, the error tells it.
+	_, err = c.AssignTurn(ctx, team.ID, diana.ID, 0)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnprocessableEntity || apiErr.Code != "version_required" {
+		t.Fatalf("reassign without version error = %v, want version_required", err)
+	}
+	current := Turn{}
+	if err = json.Unmarshal(apiErr.Current, &current); err != nil || current.ID != turn.ID || current.Version != 1 {
+		t.Errorf("reassign without version current = %s, want turn %d at version 1", apiErr.Current, turn.ID)
+	}
+
+	reassigned, err := c.AssignTurn(ctx, team.ID, diana.ID, turn.Version)
+	if err != nil {
+		t.Fatalf("AssignTurn again: %v", err)
+	}
+	if reassigned.ID != turn.ID || reassigned.PersonID != diana.ID || reassigned.Version != 2 {
+		t.Errorf("AssignTurn again = %+v, want turn %d reassigned to %d at version 2", reassigned, turn.ID, diana.ID)
+	}
+
+	_, err = c.AssignTurn(ctx, team.ID, bruce.ID, turn.Version)
+	if !IsConflict(err) || err.(*Error).Code != "version_conflict" {
+		t.Errorf("reassign at a stale version error = %v, want version_conflict", err)
+	}
+
+	turns, err := c.ListTurns(ctx, team.ID, ListTurnsOptions{Limit: 5})
+	if err != nil {
+		t.Fatalf("ListTurns: %v", err)
+	}
+	if len(turns) != 1 || turns[0].PersonID != diana.ID {
+		t.Errorf("ListTurns = %+v, want the reassigned turn", turns)
+	}
+
+	_, err = c.AssignTurn(ctx, team.ID, 0, 0)
+	if !IsValidation(err) {
+		t.Errorf("AssignTurn without person error = %v, want validation error", err)
+	}
+}
+
+func TestPickTurn(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	team, err := c.AddTeam(ctx, "Trading")
+	if err != nil {
+		t.Fatalf("AddTeam: %v", err)
+	}
+	bruce, err := c.AddPerson(ctx, team.ID, PersonInput{FirstName: "Bruce", LastName: "Wayne", Email: "bruce@vendhq.com"})
+	if err != nil {
+		t.Fatalf("AddPerson: %v", err)
+	}
+	diana, err := c.AddPerson(ctx, team.ID, PersonInput{FirstName: "Diana", LastName: "Prince", Email: "diana@vendhq.com"})
+	if err != nil {
+		t.Fatalf("AddPerson: %v", err)
+	}
+	turn, err := c.AssignTurn(ctx, team.ID, diana.ID, 0)
+	if err != nil {
+		t.Fatalf("AssignTurn: %v", err)
+	}
+
+	// The wheel never picks whoever had the latest turn while others are left.
+	pick, err := c.PickTurn(ctx, team.ID, 0, true)
+	if err != nil {
+		t.Fatalf("PickTurn dry run: %v", err)
+	}
+	if pick.Person.ID != bruce.ID || pick.Turn != nil {
+		t.Errorf("PickTurn dry run = %+v, want %d picked and no turn", pick, bruce.ID)
+	}
+	_, err = c.PickTurn(ctx, team.ID, 0, false)
+	if !hasStatus(err, http.StatusUnprocessableEntity) || err.(*Error).Code != "version_required" {
+		t.Errorf("PickTurn without version error = %v, want version_required", err)
+	}
+	pick, err = c.PickTurn(ctx, team.ID, turn.Version, false)
+	if err != nil {
+		t.Fatalf("PickTurn: %v", err)
+	}
+	if pick.Person.ID != bruce.ID || pick.Turn == nil || pick.Turn.ID != turn.ID || pick.Turn.PersonID != bruce.ID {
+		t.Errorf("PickTurn = %+v, want turn %d reassigned to %d", pick, turn.ID, bruce.ID)
+	}
+}
+
+func TestToken(t *testing.T) {
+	server, err := handler.NewServer(util.Config{AppTimezone: "UTC"}, newMemStore(), util.NewLogger())
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+
+	var authorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		server.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	_, err = New(ts.URL, WithToken("secret")).ListTeams(context.Background())
+	if err != nil {
+		t.Fatalf("ListTeams: %v", err)
+	}
+	if authorization != "Bearer secret" {
+		t.Errorf("Authorization = %q, want %q", authorization, "Bearer secret")
+	}
+}
+
+// newTestClient starts a handler.Server backed by an in-memory store.
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+
+	server, err := handler.NewServer(util.Config{AppTimezone: "UTC"}, newMemStore(), util.NewLogger())
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	return New(ts.URL)
+}
+
+// memStore is an in-memory db.Store implementing the queries used by the
+// teams, people and turns endpoints.
+type memStore struct {
+	db.Store
+
+	mu     sync.Mutex
+	nextID int64
+	teams  map[int64]db.Team
+	people map[int64]db.Person
+	turns  map[int64]db.Turn
+	outbox []db.CreateOutboxEventParams
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		teams:  map[int64]db.Team{},
+		people: map[int64]db.Person{},
+		turns:  map[int64]db.Turn{},
+	}
+}
+
+// result is the sql.Result of an insert or update.
+type result struct {
+	id    int64
+	stale bool
+}
+
+func (r result) LastInsertId() (int64, error) { return r.id, nil }
+
+func (r result) RowsAffected() (int64, error) {
+	if r.stale {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+var errDupEntry = &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+
+// ExecTx runs fn straight on the store, which is enough for the tests.
+func (s *memStore) ExecTx(_ context.Context, fn func(db.Querier) error) error {
+	return fn(s)
+}
+
+func (s *memStore) CreateOutboxEvent(_ context.Context, arg db.CreateOutboxEventParams) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outbox = append(s.outbox, arg)
+	return result{id: int64(len(s.outbox))}, nil
+}
+
+func (s *memStore) CreateTeam(_ context.Context, name string) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, team := range s.teams {
+		if team.Name == name {
+			return nil, errDupEntry
+		}
+	}
+	s.nextID++
+	s.teams[s.nextID] = db.Team{ID: s.nextID, Name: name, Version: 1}
+	return result{id: s.nextID}, nil
+}
+
+func (s *memStore) GetTeam(_ context.Context, id int64) (db.GetTeamRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	team, ok := s.teams[id]
+	if !ok {
+		return db.GetTeamRow{}, sql.ErrNoRows
+	}
+	return db.GetTeamRow{ID: team.ID, Name: team.Name, Version: team.Version}, nil
+}
+
+func (s *memStore) ListTeams(_ context.Context) ([]db.ListTeamsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	teams := []db.ListTeamsRow{}
+	for _, team := range s.teams {
+		teams = append(teams, db.ListTeamsRow{ID: team.ID, Name: team.Name, Version: team.Version})
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].ID < teams[j].ID })
+	return teams, nil
+}
+
+func (s *memStore) UpdateTeam(_ context.Context, arg db.UpdateTeamParams) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, team := range s.teams {
+		if team.Name == arg.Name && team.ID != arg.ID {
+			return nil, errDupEntry
+		}
+	}
+	team := s.teams[arg.ID]
+	if team.Version != arg.Version {
+		return result{id: arg.ID, stale: true}, nil
+	}
+	team.Name = arg.Name
+	team.Version++
+	s.teams[arg.ID] = team
+	return result{id: arg.ID}, nil
+}
+
+func (s *memStore) DeleteTeam(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.teams, id)
+	return nil
+}
+
+func (s *memStore) CreatePerson(_ context.Context, arg db.CreatePersonParams) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, person := range s.people {
+		if person.Email == arg.Email {
+			return nil, errDupEntry
+		}
+	}
+	s.nextID++
+	s.people[s.nextID] = db.Person{
+		ID:             s.nextID,
+		FirstName:      arg.FirstName,
+		LastName:       arg.LastName,
+		Email:          arg.Email,
+		TeamID:         arg.TeamID,
+		EmailReminders: arg.EmailReminders,
+		Version:        1,
+	}
+	return result{id: s.nextID}, nil
+}
+
+func (s *memStore) GetPerson(_ context.Context, arg db.GetPersonParams) (db.GetPersonRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	person, ok := s.people[arg.ID]
+	if !ok || person.TeamID != arg.TeamID {
+		return db.GetPersonRow{}, sql.ErrNoRows
+	}
+	return db.GetPersonRow(personRow(person)), nil
+}
+
+func (s *memStore) GetPersonByID(_ context.Context, id int64) (db.GetPersonByIDRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	person, ok := s.people[id]
+	if !ok {
+		return db.GetPersonByIDRow{}, sql.ErrNoRows
+	}
+	return db.GetPersonByIDRow(personRow(person)), nil
+}
+
+func (s *memStore) ListPeople(_ context.Context, teamID int64) ([]db.ListPeopleRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	people := []db.ListPeopleRow{}
+	for _, person := range s.people {
+		if person.TeamID == teamID {
+			people = append(people, personRow(person))
+		}
+	}
+	sort.Slice(people, func(i, j int) bool { return people[i].ID < people[j].ID })
+	return people, nil
+}
+
+func (s *memStore) UpdatePerson(_ context.Context, arg db.UpdatePersonParams) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	person := s.people[arg.ID]
+	if person.Version != arg.Version {
+		return result{id: arg.ID, stale: true}, nil
+	}
+	person.FirstName = arg.FirstName
+	person.LastName = arg.LastName
+	person.Email = arg.Email
+	person.TeamID = arg.TeamID
+	person.EmailReminders = arg.EmailReminders
+	person.Version++
+	s.people[arg.ID] = person
+	return result{id: arg.ID}, nil
+}
+
+func (s *memStore) DeletePerson(_ context.Context, arg db.DeletePersonParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.people[arg.ID].TeamID == arg.TeamID {
+		delete(s.people, arg.ID)
+	}
+	return nil
+}
+
+func (s *memStore) CreateTurn(_ context.Context, arg db.CreateTurnParams) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.turns[s.nextID] = db.Turn{ID: s.nextID, PersonID: arg.PersonID, Date: arg.Date, Version: 1}
+	return result{id: s.nextID}, nil
+}
+
+func (s *memStore) GetTurn(_ context.Context, arg db.GetTurnParams) (db.GetTurnRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	turn, ok := s.turns[arg.ID]
+	if !ok || s.people[turn.PersonID].TeamID != arg.TeamID {
+		return db.GetTurnRow{}, sql.ErrNoRows
+	}
+	return db.GetTurnRow{ID: turn.ID, PersonID: turn.PersonID, Date: turn.Date, Version: turn.Version}, nil
+}
+
+func (s *memStore) GetTurnByDateAndTeam(_ context.Context, arg db.GetTurnByDateAndTeamParams) (db.GetTurnByDateAndTeamRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, turn := range s.turns {
+		if turn.Date.Equal(arg.Date) && s.people[turn.PersonID].TeamID == arg.TeamID {
+			return db.GetTurnByDateAndTeamRow{ID: turn.ID, PersonID: turn.PersonID, Date: turn.Date, Version: turn.Version}, nil
+		}
+	}
+	return db.GetTurnByDateAndTeamRow{}, sql.ErrNoRows
+}
+
+func (s *memStore) ListTurns(_ context.Context, arg db.ListTurnsParams) ([]db.ListTurnsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	turns := []db.ListTurnsRow{}
+	for _, turn := range s.turns {
+		if s.people[turn.PersonID].TeamID == arg.TeamID {
+			turns = append(turns, db.ListTurnsRow{ID: turn.ID, PersonID: turn.PersonID, Date: turn.Date, Version: turn.Version})
+		}
+	}
+	sort.Slice(turns, func(i, j int) bool { return turns[i].Date.After(turns[j].Date) })
+	if int(arg.Offset) >= len(turns) {
+		return []db.ListTurnsRow{}, nil
+	}
+	turns = turns[arg.Offset:]
+	if int(arg.Limit) < len(turns) {
+		turns = turns[:arg.Limit]
+	}
+	return turns, nil
+}
+
+func (s *memStore) UpdateTurn(_ context.Context, arg db.UpdateTurnParams) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	turn := s.turns[arg.ID]
+	if turn.Version != arg.Version {
+		return result{id: arg.ID, stale: true}, nil
+	}
+	turn.PersonID = arg.PersonID
+	turn.Date = arg.Date
+	turn.Version++
+	s.turns[arg.ID] = turn
+	return result{id: arg.ID}, nil
+}
+
+// personRow converts a person to the columns selected by the people queries.
+func personRow(person db.Person) db.ListPeopleRow {
+	return db.ListPeopleRow{
+		ID:             person.ID,
+		FirstName:      person.FirstName,
+		LastName:       person.LastName,
+		Email:          person.Email,
+		TeamID:         person.TeamID,
+		EmailReminders: person.EmailReminders,
+		Version:        person.Version,
+	}
+}
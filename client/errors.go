@@ -0,0 +1,77 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// Error is an error response of the API, decoded from its RFC 7807
+// problem details.
+type Error struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail"`
+	Instance string       `json:"instance"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors"`
+	// Current is the current state of the resource when a write was
+	// based on a stale version of it.
+	Current json.RawMessage `json:"current"`
+}
+
+// FieldError describes why a single input field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("wheel api: %d %s: %s", e.Status, e.Code, e.Detail)
+	}
+	return fmt.Sprintf("wheel api: %d %s", e.Status, e.Code)
+}
+
+// IsNotFound reports whether err is an API error with status 404.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err is an API error with status 409.
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsValidation reports whether err is an API error with status 400.
+func IsValidation(err error) bool {
+	return hasStatus(err, http.StatusBadRequest)
+}
+
+// hasStatus reports whether err is an API error with the given status.
+func hasStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Status == status
+}
+
+// newError builds an *Error from an error response. Bodies which aren't
+// problem details, e.g. from a proxy, still yield the status.
+func newError(res *http.Response) error {
+	apiErr := &Error{}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err == nil {
+		_ = json.Unmarshal(body, apiErr)
+	}
+
+	apiErr.Status = res.StatusCode
+	if apiErr.Title == "" {
+		apiErr.Title = http.StatusText(res.StatusCode)
+	}
+	return apiErr
+}
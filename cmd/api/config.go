@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ezerw/wheel/util"
+)
+
+// checkConfig implements `api config check`: it loads the config the way
+// the API does and lists its problems, returning the exit code.
+func checkConfig(path string) int {
+	config, err := util.LoadConfig(path)
+	if problems, ok := err.(util.ConfigError); ok {
+		fmt.Fprintln(os.Stderr, "invalid config:")
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, "  -", problem)
+		}
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot load config:", err)
+		return 1
+	}
+
+	source := "the environment"
+	if config.File != "" {
+		source = config.File + " and the environment"
+	}
+	fmt.Println("config from", source, "is valid")
+	return 0
+}
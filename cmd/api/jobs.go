@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/scheduler"
+	"github.com/ezerw/wheel/service"
+	"github.com/ezerw/wheel/slack"
+	"github.com/ezerw/wheel/util"
+)
+
+// defaultPurgeAfterDays is how long turns and webhook deliveries are kept
+// unless PURGE_AFTER_DAYS is set.
+const defaultPurgeAfterDays = 365
+
+// registerJobs sets up the functions running each kind of job.
+func registerJobs(s *scheduler.Scheduler, store db.Store, notifier *slack.Notifier, config util.Config) {
+	turns := service.NewTurns(store)
+	jobs := service.NewJobs(store)
+
+	purgeAfterDays := defaultPurgeAfterDays
+	if config.PurgeAfterDays > 0 {
+		purgeAfterDays = config.PurgeAfterDays
+	}
+
+	s.Register(service.JobAssign, func(ctx context.Context, teamID int64, _ time.Time) (string, error) {
+		date, err := util.GetNextWorkingDay(config.AppTimezone)
+		if err != nil {
+			return "", errors.Wrap(err, "error getting next working day")
+		}
+
+		_, err = turns.GetTurnByDate(ctx, db.GetTurnByDateAndTeamParams{Date: *date, TeamID: teamID})
+		if err == nil {
+			return fmt.Sprintf("The turn of %s is already assigned.", date.Format("2006-01-02")), nil
+		}
+		if service.KindOf(err) != service.KindNotFound {
+			return "", err
+		}
+
+		person, err := turns.PickPerson(ctx, teamID)
+		if err != nil {
+			return "", err
+		}
+		turn, err := turns.AssignTurn(ctx, teamID, person.ID, *date)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Assigned %s to %s %s.", turn.Date.Format("2006-01-02"), person.FirstName, person.LastName), nil
+	})
+
+	s.Register(service.JobRemind, func(ctx context.Context, teamID int64, now time.Time) (string, error) {
+		posted, err := notifier.RemindTeam(ctx, teamID, now)
+		if err != nil {
+			return "", err
+		}
+		if !posted {
+			return "Nothing to remind, or already reminded today.", nil
+		}
+		return "Posted the reminder to Slack.", nil
+	})
+
+	s.Register(service.JobPurge, func(ctx context.Context, teamID int64, now time.Time) (string, error) {
+		year, month, day := now.Date()
+		before := time.Date(year, month, day-purgeAfterDays, 0, 0, 0, 0, now.Location())
+
+		purgedTurns, purgedDeliveries, err := jobs.Purge(ctx, teamID, before)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(
+			"Deleted %d turns and %d webhook deliveries before %s.",
+			purgedTurns,
+			purgedDeliveries,
+			before.Format("2006-01-02"),
+		), nil
+	})
+}
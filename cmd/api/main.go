@@ -1,35 +1,130 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/go-sql-driver/mysql"
 
 	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/email"
+	"github.com/ezerw/wheel/event"
 	"github.com/ezerw/wheel/handler"
+	"github.com/ezerw/wheel/health"
+	"github.com/ezerw/wheel/outbox"
+	"github.com/ezerw/wheel/scheduler"
+	"github.com/ezerw/wheel/slack"
+	"github.com/ezerw/wheel/tracing"
 	"github.com/ezerw/wheel/util"
+	"github.com/ezerw/wheel/webhook"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if len(os.Args) != 3 || os.Args[1] != "config" || os.Args[2] != "check" {
+			log.Fatal("usage: api [config check]")
+		}
+		os.Exit(checkConfig("."))
+	}
+
+	logger := util.NewLogger()
+
 	config, err := util.LoadConfig(".")
 	if err != nil {
 		log.Fatal("cannot load config:", err)
 	}
 
-	dBConn, err := db.Connect(config)
+	shutdownTracing, err := tracing.Setup(context.Background(), config)
+	if err != nil {
+		log.Fatal("cannot set up tracing:", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	dBConn, err := db.Connect(config, logger)
 	if err != nil {
 		log.Fatal("cannot connect to db:", err)
 	}
 	defer dBConn.Close()
 
+	if err = db.Ping(context.Background(), dBConn, config, logger); err != nil {
+		log.Fatal("cannot reach db:", err)
+	}
+
 	store := db.NewStore(dBConn)
-	server, err := handler.NewServer(config, store)
+
+	bus := event.NewBus()
+	webhookDispatcher := webhook.NewDispatcher(store, config, logger)
+	defer webhookDispatcher.Close()
+
+	notifier, err := slack.NewNotifier(store, config, logger)
+	if err != nil {
+		log.Fatal("cannot create slack notifier:", err)
+	}
+	defer notifier.Close()
+	notifier.StartReminders()
+
+	if config.SMTPHost != "" {
+		mailer, err := email.NewMailer(config)
+		if err != nil {
+			log.Fatal("cannot create mailer:", err)
+		}
+		emailNotifier, err := email.NewNotifier(store, mailer, config, logger)
+		if err != nil {
+			log.Fatal("cannot create email notifier:", err)
+		}
+		defer emailNotifier.Close()
+		emailNotifier.StartReminders()
+	}
+
+	jobScheduler, err := scheduler.New(store, config, logger)
+	if err != nil {
+		log.Fatal("cannot create scheduler:", err)
+	}
+	defer jobScheduler.Close()
+	registerJobs(jobScheduler, store, notifier, config)
+	jobScheduler.Start()
+
+	outboxCtx, stopOutbox := context.WithCancel(context.Background())
+	outboxDispatcher := outbox.NewDispatcher(store, config, logger)
+	// Each sink keeps its own deliveries, so the live events go out once
+	// whatever the webhooks and Slack do, and only the failing sink retries.
+	outboxDispatcher.Register("live", outbox.PublisherSink(bus))
+	outboxDispatcher.Register("webhooks", webhookDispatcher)
+	outboxDispatcher.Register("slack", notifier)
+	outboxDone := make(chan struct{})
+	go func() {
+		defer close(outboxDone)
+		outboxDispatcher.Run(outboxCtx)
+	}()
+	defer func() {
+		stopOutbox()
+		<-outboxDone
+	}()
+
+	server, err := handler.NewServer(config, store, logger)
 	if err != nil {
 		log.Panic("cannot create server:", err)
 	}
+	bus.Subscribe(server.Publish)
+
+	migrationsCheck, err := health.Migrations(config, logger)
+	if err != nil {
+		log.Fatal("cannot create migrations check:", err)
+	}
+	server.RegisterReadinessCheck("database", health.Database(dBConn))
+	server.RegisterReadinessCheck("migrations", migrationsCheck)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	err = server.Start(config.AppAddress, config.AppPort)
+	// Once the connections are drained the deferred calls stop the workers,
+	// then close the DB.
+	err = server.Start(ctx, config.AppAddress, config.AppPort)
 	if err != nil {
 		log.Panic("cannot start server:", err)
 	}
+	logger.Info("connections drained, shutting down")
 }
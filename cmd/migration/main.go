@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -28,12 +29,18 @@ func main() {
 		log.Fatal("cannot load config:", err)
 	}
 
-	dBConn, err := db.Connect(config)
+	// Migrations may take long, don't cut them.
+	config.DBQueryTimeout = -1
+	dBConn, err := db.Connect(config, logger)
 	if err != nil {
 		log.Fatal("cannot connect to db:", err)
 	}
 	defer dBConn.Close()
 
+	if err = db.Ping(context.Background(), dBConn, config, logger); err != nil {
+		log.Fatal("cannot reach db:", err)
+	}
+
 	driver, err := mysql.WithInstance(dBConn, &mysql.Config{})
 	if err != nil {
 		log.Panic("cannot get db instance for migration")
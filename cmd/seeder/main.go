@@ -19,7 +19,7 @@ func main() {
 		log.Fatal("cannot load config:", err)
 	}
 
-	dBConn, err := db.Connect(config)
+	dBConn, err := db.Connect(config, logger)
 	if err != nil {
 		log.Fatal("cannot connect to db:", err)
 	}
@@ -0,0 +1,158 @@
+// Command wheelctl manages teams, people and turns through the wheel API.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ezerw/wheel/client"
+)
+
+// app holds what every subcommand needs.
+type app struct {
+	client *client.Client
+	output string
+	out    io.Writer
+}
+
+// command is a subcommand such as "teams list".
+type command struct {
+	usage string
+	run   func(ctx context.Context, a *app, args []string) error
+}
+
+// commands maps resource and action names to their subcommand.
+var commands = map[string]map[string]command{
+	"teams": {
+		"list":   {"teams list", teamsList},
+		"show":   {"teams show <team-id>", teamsShow},
+		"add":    {"teams add <name>", teamsAdd},
+		"rename": {"teams rename <team-id> <name> -version <n> [-etag <etag>]", teamsRename},
+		"delete": {"teams delete <team-id> -etag <etag>", teamsDelete},
+	},
+	"people": {
+		"list":   {"people list <team-id>", peopleList},
+		"show":   {"people show <team-id> <person-id>", peopleShow},
+		"add":    {"people add <team-id> -first-name <name> -last-name <name> -email <email>", peopleAdd},
+		"update": {"people update <team-id> <person-id> [-first-name <name>] [-last-name <name>] [-email <email>] [-team-id <id>] [-email-reminders=false] -version <n>", peopleUpdate},
+		"delete": {"people delete <team-id> <person-id> -etag <etag>", peopleDelete},
+	},
+	"turns": {
+		"list":   {"turns list <team-id> [-limit <n>] [-offset <n>] [-from <YYYY-MM-DD>] [-to <YYYY-MM-DD>]", turnsList},
+		"assign": {"turns assign <team-id> <person-id> [-version <n>]", turnsAssign},
+		"pick":   {"turns pick <team-id> [-dry-run] [-version <n>]", turnsPick},
+	},
+}
+
+func main() {
+	flags := flag.NewFlagSet("wheelctl", flag.ExitOnError)
+	baseURL := flags.String("url", env("WHEEL_URL", "http://localhost:8080"), "API base URL [$WHEEL_URL]")
+	token := flags.String("token", os.Getenv("WHEEL_TOKEN"), "API bearer token [$WHEEL_TOKEN]")
+	output := flags.String("o", env("WHEEL_OUTPUT", "table"), "output format: table or json [$WHEEL_OUTPUT]")
+	timeout := flags.Duration("timeout", 30*time.Second, "request timeout")
+	flags.Usage = func() {
+		usage(flags.Output())
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(os.Args[1:])
+
+	if *output != "table" && *output != "json" {
+		fail(fmt.Errorf("unknown output format %q", *output))
+	}
+
+	args := flags.Args()
+	if len(args) < 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[args[0]][args[1]]
+	if !ok {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	a := &app{
+		client: client.New(*baseURL, client.WithToken(*token)),
+		output: *output,
+		out:    os.Stdout,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := cmd.run(ctx, a, args[2:]); err != nil {
+		fail(err)
+	}
+}
+
+// usage prints the list of subcommands.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: wheelctl [flags] <resource> <action> [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	var usages []string
+	for _, actions := range commands {
+		for _, cmd := range actions {
+			usages = append(usages, cmd.usage)
+		}
+	}
+	sort.Strings(usages)
+	for _, u := range usages {
+		fmt.Fprintln(w, "  "+u)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+}
+
+// fail reports err and exits.
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "wheelctl:", err)
+	os.Exit(1)
+}
+
+// env returns the environment variable key, or fallback when unset.
+func env(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// parseArgs parses the flags of a subcommand, which may follow its
+// positional arguments, and checks the number of positional arguments.
+func parseArgs(flags *flag.FlagSet, args []string, positional int) ([]string, error) {
+	var values []string
+	for len(args) > 0 {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) > 0 {
+			values = append(values, args[0])
+			args = args[1:]
+		}
+	}
+
+	if len(values) != positional {
+		return nil, fmt.Errorf("%s expects %d argument(s), got %d", flags.Name(), positional, len(values))
+	}
+	return values, nil
+}
+
+// parseID parses a team or person ID argument.
+func parseID(name string, value string) (int64, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", name, value)
+	}
+	return id, nil
+}
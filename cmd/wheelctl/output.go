@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ezerw/wheel/client"
+)
+
+// render writes v as indented JSON, or header and rows as an aligned table,
+// depending on the output format.
+func (a *app) render(v interface{}, header []string, rows [][]string) error {
+	if a.output == "json" {
+		encoder := json.NewEncoder(a.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// renderTeams writes a list of teams.
+func (a *app) renderTeams(v interface{}, teams ...client.Team) error {
+	rows := make([][]string, 0, len(teams))
+	for _, team := range teams {
+		rows = append(rows, []string{fmt.Sprint(team.ID), team.Name, fmt.Sprint(team.Version)})
+	}
+	return a.render(v, []string{"ID", "NAME", "VERSION"}, rows)
+}
+
+// renderPeople writes a list of people.
+func (a *app) renderPeople(v interface{}, people ...client.Person) error {
+	rows := make([][]string, 0, len(people))
+	for _, person := range people {
+		rows = append(rows, []string{
+			fmt.Sprint(person.ID),
+			person.FirstName,
+			person.LastName,
+			person.Email,
+			fmt.Sprint(person.TeamID),
+			fmt.Sprint(person.Version),
+		})
+	}
+	return a.render(v, []string{"ID", "FIRST NAME", "LAST NAME", "EMAIL", "TEAM", "VERSION"}, rows)
+}
+
+// renderTurns writes a list of turns.
+func (a *app) renderTurns(v interface{}, turns ...client.Turn) error {
+	rows := make([][]string, 0, len(turns))
+	for _, turn := range turns {
+		rows = append(rows, []string{
+			fmt.Sprint(turn.ID),
+			turn.Date.Format("2006-01-02"),
+			fmt.Sprint(turn.PersonID),
+			fmt.Sprint(turn.Version),
+		})
+	}
+	return a.render(v, []string{"ID", "DATE", "PERSON", "VERSION"}, rows)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/ezerw/wheel/client"
+)
+
+// peopleList handles "people list".
+func peopleList(ctx context.Context, a *app, args []string) error {
+	values, err := parseArgs(flag.NewFlagSet("people list", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	teamID, err := parseID("team-id", values[0])
+	if err != nil {
+		return err
+	}
+
+	people, err := a.client.ListPeople(ctx, teamID)
+	if err != nil {
+		return err
+	}
+	return a.renderPeople(people, people...)
+}
+
+// peopleShow handles "people show".
+func peopleShow(ctx context.Context, a *app, args []string) error {
+	values, err := parseArgs(flag.NewFlagSet("people show", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	teamID, personID, err := parseTeamAndPerson(values)
+	if err != nil {
+		return err
+	}
+
+	person, err := a.client.GetPerson(ctx, teamID, personID)
+	if err != nil {
+		return err
+	}
+	if a.output == "table" {
+		fmt.Fprintf(a.out, "ETag %s\n\n", person.ETag)
+	}
+	return a.renderPeople(person, *person)
+}
+
+// peopleAdd handles "people add".
+func peopleAdd(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("people add", flag.ContinueOnError)
+	input := client.PersonInput{}
+	flags.StringVar(&input.FirstName, "first-name", "", "first name")
+	flags.StringVar(&input.LastName, "last-name", "", "last name")
+	flags.StringVar(&input.Email, "email", "", "email address")
+
+	values, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	teamID, err := parseID("team-id", values[0])
+	if err != nil {
+		return err
+	}
+
+	person, err := a.client.AddPerson(ctx, teamID, input)
+	if err != nil {
+		return err
+	}
+	return a.renderPeople(person, *person)
+}
+
+// peopleUpdate handles "people update", only the flags given are changed.
+func peopleUpdate(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("people update", flag.ContinueOnError)
+	firstName := flags.String("first-name", "", "new first name")
+	lastName := flags.String("last-name", "", "new last name")
+	email := flags.String("email", "", "new email address")
+	newTeamID := flags.Int64("team-id", 0, "team to move the person to")
+	emailReminders := flags.Bool("email-reminders", true, "email the person the day before their turns")
+	version := flags.Int("version", 0, "version of the person the changes are based on, as shown by people show")
+
+	values, err := parseArgs(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	teamID, personID, err := parseTeamAndPerson(values)
+	if err != nil {
+		return err
+	}
+
+	patch := client.PersonPatch{Version: int32(*version)}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "first-name":
+			patch.FirstName = firstName
+		case "last-name":
+			patch.LastName = lastName
+		case "email":
+			patch.Email = email
+		case "team-id":
+			patch.TeamID = newTeamID
+		case "email-reminders":
+			patch.EmailReminders = emailReminders
+		}
+	})
+
+	person, err := a.client.UpdatePerson(ctx, teamID, personID, patch)
+	if err != nil {
+		return err
+	}
+	return a.renderPeople(person, *person)
+}
+
+// peopleDelete handles "people delete", the person must still have the ETag
+// given.
+func peopleDelete(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("people delete", flag.ContinueOnError)
+	etag := flags.String("etag", "", `ETag of the person as shown by people show, "*" to delete them whatever their state`)
+
+	values, err := parseArgs(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	teamID, personID, err := parseTeamAndPerson(values)
+	if err != nil {
+		return err
+	}
+
+	return a.client.DeletePerson(ctx, teamID, personID, *etag)
+}
+
+// parseTeamAndPerson parses <team-id> <person-id> arguments.
+func parseTeamAndPerson(values []string) (int64, int64, error) {
+	teamID, err := parseID("team-id", values[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	personID, err := parseID("person-id", values[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return teamID, personID, nil
+}
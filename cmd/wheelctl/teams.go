@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+)
+
+// teamsList handles "teams list".
+func teamsList(ctx context.Context, a *app, args []string) error {
+	if _, err := parseArgs(flag.NewFlagSet("teams list", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+
+	teams, err := a.client.ListTeams(ctx)
+	if err != nil {
+		return err
+	}
+	return a.renderTeams(teams, teams...)
+}
+
+// teamsShow handles "teams show".
+func teamsShow(ctx context.Context, a *app, args []string) error {
+	values, err := parseArgs(flag.NewFlagSet("teams show", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	teamID, err := parseID("team-id", values[0])
+	if err != nil {
+		return err
+	}
+
+	team, err := a.client.GetTeam(ctx, teamID)
+	if err != nil {
+		return err
+	}
+
+	if a.output == "json" {
+		return a.render(team, nil, nil)
+	}
+	fmt.Fprintf(a.out, "Team %d: %s (version %d, ETag %s)\n\n", team.ID, team.Name, team.Version, team.ETag)
+	return a.renderPeople(team, team.People...)
+}
+
+// teamsAdd handles "teams add".
+func teamsAdd(ctx context.Context, a *app, args []string) error {
+	values, err := parseArgs(flag.NewFlagSet("teams add", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	team, err := a.client.AddTeam(ctx, values[0])
+	if err != nil {
+		return err
+	}
+	return a.renderTeams(team, *team)
+}
+
+// teamsRename handles "teams rename", the team must still be at the version
+// given and, when it's given, have the ETag.
+func teamsRename(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("teams rename", flag.ContinueOnError)
+	version := flags.Int("version", 0, "version of the team the rename is based on, as shown by teams show")
+	etag := flags.String("etag", "*", "ETag of the team the rename is based on, as shown by teams show")
+
+	values, err := parseArgs(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	teamID, err := parseID("team-id", values[0])
+	if err != nil {
+		return err
+	}
+
+	team, err := a.client.RenameTeam(ctx, teamID, values[1], int32(*version), *etag)
+	if err != nil {
+		return err
+	}
+	return a.renderTeams(team, *team)
+}
+
+// teamsDelete handles "teams delete", the team must still have the ETag given.
+func teamsDelete(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("teams delete", flag.ContinueOnError)
+	etag := flags.String("etag", "", `ETag of the team as shown by teams show, "*" to delete it whatever its state`)
+
+	values, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	teamID, err := parseID("team-id", values[0])
+	if err != nil {
+		return err
+	}
+
+	return a.client.DeleteTeam(ctx, teamID, *etag)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/ezerw/wheel/client"
+)
+
+// turnsList handles "turns list".
+func turnsList(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("turns list", flag.ContinueOnError)
+	options := client.ListTurnsOptions{}
+	flags.IntVar(&options.Limit, "limit", 10, "maximum number of turns")
+	flags.IntVar(&options.Offset, "offset", 0, "number of turns to skip")
+	from := flags.String("from", "", "earliest date, YYYY-MM-DD")
+	to := flags.String("to", "", "latest date, YYYY-MM-DD")
+
+	values, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	teamID, err := parseID("team-id", values[0])
+	if err != nil {
+		return err
+	}
+	if options.DateFrom, err = parseDate("from", *from); err != nil {
+		return err
+	}
+	if options.DateTo, err = parseDate("to", *to); err != nil {
+		return err
+	}
+
+	turns, err := a.client.ListTurns(ctx, teamID, options)
+	if err != nil {
+		return err
+	}
+	return a.renderTurns(turns, turns...)
+}
+
+// turnsAssign handles "turns assign".
+func turnsAssign(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("turns assign", flag.ContinueOnError)
+	version := flags.Int("version", 0, "version of the turn booked that day, to reassign it")
+
+	values, err := parseArgs(flags, args, 2)
+	if err != nil {
+		return err
+	}
+	teamID, personID, err := parseTeamAndPerson(values)
+	if err != nil {
+		return err
+	}
+
+	turn, err := a.client.AssignTurn(ctx, teamID, personID, int32(*version))
+	if err != nil {
+		return err
+	}
+	return a.renderTurns(turn, *turn)
+}
+
+// turnsPick handles "turns pick", it assigns the next turn to a random
+// member of the team other than whoever had the latest turn.
+func turnsPick(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("turns pick", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print the pick without assigning it")
+	version := flags.Int("version", 0, "version of the turn booked that day, to reassign it")
+
+	values, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	teamID, err := parseID("team-id", values[0])
+	if err != nil {
+		return err
+	}
+
+	pick, err := a.client.PickTurn(ctx, teamID, int32(*version), *dryRun)
+	if err != nil {
+		return err
+	}
+	if pick.Turn == nil {
+		return a.renderPeople(pick.Person, pick.Person)
+	}
+	if a.output == "table" {
+		fmt.Fprintf(a.out, "Picked %s %s\n\n", pick.Person.FirstName, pick.Person.LastName)
+	}
+	return a.renderTurns(*pick.Turn, *pick.Turn)
+}
+
+// parseDate parses an optional YYYY-MM-DD flag value.
+func parseDate(name string, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("-%s must be a YYYY-MM-DD date, got %q", name, value)
+	}
+	return date, nil
+}
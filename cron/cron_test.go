@@ -0,0 +1,50 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	auckland, err := time.LoadLocation("Pacific/Auckland")
+	if err != nil {
+		t.Fatalf("cannot load location: %v", err)
+	}
+	// Monday 12 July 2021, 09:30.
+	monday := time.Date(2021, time.July, 12, 9, 30, 0, 0, auckland)
+
+	for _, tt := range []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"0 8 * * 1-5", monday, time.Date(2021, time.July, 13, 8, 0, 0, 0, auckland)},
+		{"0 8 * * mon-fri", monday.AddDate(0, 0, 4), time.Date(2021, time.July, 19, 8, 0, 0, 0, auckland)},
+		{"*/15 * * * *", monday, time.Date(2021, time.July, 12, 9, 45, 0, 0, auckland)},
+		{"30 9 * * *", monday, time.Date(2021, time.July, 13, 9, 30, 0, 0, auckland)},
+		{"0 3 1 * *", monday, time.Date(2021, time.August, 1, 3, 0, 0, 0, auckland)},
+		{"0 0 13 * 5", monday, time.Date(2021, time.July, 13, 0, 0, 0, 0, auckland)},
+		{"0 12 * * 7", monday, time.Date(2021, time.July, 18, 12, 0, 0, 0, auckland)},
+		{"@weekly", monday, time.Date(2021, time.July, 18, 0, 0, 0, 0, auckland)},
+		// 02:00 doesn't exist the day daylight saving starts.
+		{"0 2 * * *", time.Date(2021, time.September, 25, 12, 0, 0, 0, auckland), time.Date(2021, time.September, 27, 2, 0, 0, 0, auckland)},
+		{"0 0 30 2 *", monday, time.Time{}},
+	} {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next(%v) = %v, want %v", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
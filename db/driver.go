@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ezerw/wheel/util"
+)
+
+// connector wraps the MySQL connector so every statement run on its
+// connections gets the default timeout, and slow ones are logged. A timeout
+// or slow threshold which isn't positive is disabled.
+type connector struct {
+	driver.Connector
+	timeout time.Duration
+	slow    time.Duration
+	logger  *logrus.Logger
+}
+
+// Connect implements driver.Connector.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	inner, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: inner, connector: c}, nil
+}
+
+// start derives the context a statement runs with, limited to the default
+// timeout, if any, unless ctx ends sooner, and returns it along with the
+// function to call once the statement is done.
+func (c *connector) start(ctx context.Context, query string) (context.Context, func()) {
+	cancel := func() {}
+	if deadline, ok := ctx.Deadline(); c.timeout > 0 && (!ok || time.Until(deadline) > c.timeout) {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+	}
+
+	started := time.Now()
+	return ctx, func() {
+		cancel()
+		if elapsed := time.Since(started); c.slow > 0 && elapsed >= c.slow {
+			util.LoggerOr(ctx, c.logger).
+				WithField("query", queryName(query)).
+				WithField("duration_ms", elapsed.Milliseconds()).
+				Warn("slow query")
+		}
+	}
+}
+
+// conn is a connection whose statements are limited and logged by its
+// connector. The MySQL connection implements every optional interface
+// forwarded here.
+type conn struct {
+	driver.Conn
+	connector *connector
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	prepared, err := c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: prepared, query: query, connector: c.connector}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ctx, done := c.connector.start(ctx, query)
+	defer done()
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, done := c.connector.start(ctx, query)
+	result, err := c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return &rows{Rows: result, done: done}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	return c.Conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c *conn) IsValid() bool {
+	return c.Conn.(driver.Validator).IsValid()
+}
+
+func (c *conn) CheckNamedValue(value *driver.NamedValue) error {
+	return c.Conn.(driver.NamedValueChecker).CheckNamedValue(value)
+}
+
+// stmt is a prepared statement, which database/sql uses to run the queries
+// with arguments.
+type stmt struct {
+	driver.Stmt
+	query     string
+	connector *connector
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, done := s.connector.start(ctx, s.query)
+	defer done()
+	return s.Stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, done := s.connector.start(ctx, s.query)
+	result, err := s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return &rows{Rows: result, done: done}, nil
+}
+
+func (s *stmt) CheckNamedValue(value *driver.NamedValue) error {
+	return s.Stmt.(driver.NamedValueChecker).CheckNamedValue(value)
+}
+
+// rows ends the statement which returned them once closed, the query runs
+// until they're read.
+type rows struct {
+	driver.Rows
+	done   func()
+	closed bool
+}
+
+func (r *rows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		r.done()
+	}
+	return err
+}
+
+func (r *rows) HasNextResultSet() bool {
+	return r.Rows.(driver.RowsNextResultSet).HasNextResultSet()
+}
+
+func (r *rows) NextResultSet() error {
+	return r.Rows.(driver.RowsNextResultSet).NextResultSet()
+}
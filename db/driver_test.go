@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// fakeConnector hands out connections whose statements take delay, or until
+// their context ends.
+type fakeConnector struct {
+	delay time.Duration
+}
+
+func (f fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return fakeConn{delay: f.delay}, nil
+}
+
+func (f fakeConnector) Driver() driver.Driver { return nil }
+
+type fakeConn struct {
+	driver.Conn
+	delay time.Duration
+}
+
+func (f fakeConn) ExecContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Result, error) {
+	select {
+	case <-time.After(f.delay):
+		return driver.RowsAffected(0), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f fakeConn) ResetSession(context.Context) error { return nil }
+
+func (f fakeConn) IsValid() bool { return true }
+
+func (f fakeConn) Close() error { return nil }
+
+func TestConnector(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	conn := sql.OpenDB(&connector{
+		Connector: fakeConnector{delay: time.Hour},
+		timeout:   20 * time.Millisecond,
+		slow:      time.Hour,
+		logger:    logger,
+	})
+	defer conn.Close()
+	_, err := conn.ExecContext(context.Background(), "-- name: SlowOne :exec\nDO SLEEP(3600)")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want the statement to time out", err)
+	}
+
+	conn = sql.OpenDB(&connector{
+		Connector: fakeConnector{delay: 20 * time.Millisecond},
+		timeout:   time.Hour,
+		slow:      10 * time.Millisecond,
+		logger:    logger,
+	})
+	defer conn.Close()
+	if _, err = conn.ExecContext(context.Background(), "-- name: SlowOne :exec\nDO SLEEP(0.02)"); err != nil {
+		t.Fatalf("ExecContext: %v", err)
+	}
+	entry := hook.LastEntry()
+	if entry == nil || entry.Message != "slow query" {
+		t.Fatalf("got log entry %v, want the slow query logged", entry)
+	}
+	if got := entry.Data["query"]; got != "SlowOne" {
+		t.Errorf("logged query %v, want SlowOne", got)
+	}
+
+	// A negative threshold disables the log.
+	hook.Reset()
+	conn = sql.OpenDB(&connector{
+		Connector: fakeConnector{delay: time.Millisecond},
+		timeout:   time.Hour,
+		slow:      -1,
+		logger:    logger,
+	})
+	defer conn.Close()
+	if _, err = conn.ExecContext(context.Background(), "-- name: SlowOne :exec\nDO SLEEP(0.001)"); err != nil {
+		t.Fatalf("ExecContext: %v", err)
+	}
+	if entry = hook.LastEntry(); entry != nil {
+		t.Errorf("got log entry %v with a negative threshold, want none", entry)
+	}
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"embed"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// Migrations holds the migrations the code is written against.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// LatestMigration returns the version of the last migration, the one the
+// database must be at for the code to work.
+func LatestMigration() (uint, error) {
+	entries, err := Migrations.ReadDir("migrations")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, entry := range entries {
+		migration, err := source.Parse(entry.Name())
+		if err != nil {
+			return 0, err
+		}
+		if migration.Version > latest {
+			latest = migration.Version
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,5 @@
+ALTER TABLE `webhook_deliveries` DROP FOREIGN KEY `webhook_deliveries_webhook_id_fk`;
+ALTER TABLE `webhooks` DROP FOREIGN KEY `webhooks_team_id_fk`;
+
+DROP TABLE `webhook_deliveries`;
+DROP TABLE `webhooks`;
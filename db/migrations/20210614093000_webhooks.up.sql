@@ -0,0 +1,34 @@
+CREATE TABLE `webhooks`
+(
+    `id`         bigint AUTO_INCREMENT PRIMARY KEY,
+    `team_id`    bigint        NOT NULL,
+    `url`        varchar(2048) NOT NULL,
+    `secret`     varchar(100)  NOT NULL,
+    `events`     varchar(500)  NOT NULL,
+    `created_at` timestamp default now(),
+    `updated_at` timestamp default now()
+);
+
+CREATE TABLE `webhook_deliveries`
+(
+    `id`          bigint AUTO_INCREMENT PRIMARY KEY,
+    `webhook_id`  bigint       NOT NULL,
+    `event_id`    varchar(36)  NOT NULL,
+    `event_type`  varchar(50)  NOT NULL,
+    `attempt`     int          NOT NULL,
+    `succeeded`   boolean      NOT NULL,
+    `status_code` int,
+    `error`       varchar(500),
+    `duration_ms` int          NOT NULL,
+    `created_at`  timestamp default now()
+);
+
+ALTER TABLE `webhooks`
+    ADD CONSTRAINT webhooks_team_id_fk
+        FOREIGN KEY (`team_id`) REFERENCES `teams` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `webhook_deliveries`
+    ADD CONSTRAINT webhook_deliveries_webhook_id_fk
+        FOREIGN KEY (`webhook_id`) REFERENCES `webhooks` (`id`) ON DELETE CASCADE;
+
+CREATE INDEX `webhook_deliveries_index_0` ON `webhook_deliveries` (`webhook_id`, `id`);
@@ -0,0 +1,16 @@
+CREATE TABLE `outbox`
+(
+    `id`           bigint AUTO_INCREMENT PRIMARY KEY,
+    `event_id`     varchar(36) UNIQUE NOT NULL,
+    `event_type`   varchar(50)        NOT NULL,
+    `team_id`      bigint             NOT NULL,
+    `payload`      text               NOT NULL,
+    `status`       varchar(20)        NOT NULL DEFAULT 'pending',
+    `attempts`     int                NOT NULL DEFAULT 0,
+    `last_error`   varchar(500),
+    `available_at` timestamp          NOT NULL DEFAULT now(),
+    `created_at`   timestamp default now(),
+    `processed_at` timestamp          NULL
+);
+
+CREATE INDEX `outbox_index_0` ON `outbox` (`status`, `available_at`, `id`);
@@ -0,0 +1,3 @@
+ALTER TABLE `slack_settings` DROP FOREIGN KEY `slack_settings_team_id_fk`;
+
+DROP TABLE `slack_settings`;
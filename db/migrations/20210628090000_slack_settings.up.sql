@@ -0,0 +1,17 @@
+CREATE TABLE `slack_settings`
+(
+    `team_id`           bigint PRIMARY KEY,
+    `webhook_url`       varchar(2048) NOT NULL,
+    `notify_assigned`   boolean       NOT NULL DEFAULT true,
+    `reminder_enabled`  boolean       NOT NULL DEFAULT false,
+    `reminder_time`     char(5)       NOT NULL DEFAULT '09:00',
+    `assigned_template` text,
+    `reminder_template` text,
+    `last_reminder_on`  date,
+    `created_at`        timestamp default now(),
+    `updated_at`        timestamp default now()
+);
+
+ALTER TABLE `slack_settings`
+    ADD CONSTRAINT slack_settings_team_id_fk
+        FOREIGN KEY (`team_id`) REFERENCES `teams` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+ALTER TABLE `slack_channels` DROP FOREIGN KEY `slack_channels_team_id_fk`;
+
+DROP TABLE `slack_channels`;
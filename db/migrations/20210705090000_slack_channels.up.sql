@@ -0,0 +1,10 @@
+CREATE TABLE `slack_channels`
+(
+    `channel_id` varchar(50) PRIMARY KEY,
+    `team_id`    bigint NOT NULL,
+    `created_at` timestamp default now()
+);
+
+ALTER TABLE `slack_channels`
+    ADD CONSTRAINT slack_channels_team_id_fk
+        FOREIGN KEY (`team_id`) REFERENCES `teams` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+ALTER TABLE `turns` DROP COLUMN `email_reminded_at`;
+
+ALTER TABLE `people` DROP COLUMN `email_reminders`;
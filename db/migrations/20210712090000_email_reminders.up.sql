@@ -0,0 +1,5 @@
+ALTER TABLE `people`
+    ADD COLUMN `email_reminders` boolean NOT NULL DEFAULT true AFTER `team_id`;
+
+ALTER TABLE `turns`
+    ADD COLUMN `email_reminded_at` timestamp NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE `jobs` DROP FOREIGN KEY `jobs_team_id_fk`;
+
+DROP TABLE `jobs`;
@@ -0,0 +1,21 @@
+CREATE TABLE `jobs`
+(
+    `team_id`          bigint       NOT NULL,
+    `kind`             varchar(20)  NOT NULL,
+    `schedule`         varchar(100) NOT NULL,
+    `enabled`          boolean      NOT NULL DEFAULT true,
+    `next_run_at`      timestamp    NOT NULL DEFAULT now(),
+    `last_run_at`      timestamp    NULL,
+    `last_status`      varchar(20),
+    `last_result`      varchar(500),
+    `last_duration_ms` int,
+    `created_at`       timestamp default now(),
+    `updated_at`       timestamp default now(),
+    PRIMARY KEY (`team_id`, `kind`)
+);
+
+ALTER TABLE `jobs`
+    ADD CONSTRAINT jobs_team_id_fk
+        FOREIGN KEY (`team_id`) REFERENCES `teams` (`id`) ON DELETE CASCADE;
+
+CREATE INDEX `jobs_index_0` ON `jobs` (`enabled`, `next_run_at`);
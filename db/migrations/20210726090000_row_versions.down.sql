@@ -0,0 +1,8 @@
+ALTER TABLE `turns`
+    DROP COLUMN `version`;
+
+ALTER TABLE `people`
+    DROP COLUMN `version`;
+
+ALTER TABLE `teams`
+    DROP COLUMN `version`;
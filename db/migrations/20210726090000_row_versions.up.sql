@@ -0,0 +1,8 @@
+ALTER TABLE `teams`
+    ADD COLUMN `version` int NOT NULL DEFAULT 1;
+
+ALTER TABLE `people`
+    ADD COLUMN `version` int NOT NULL DEFAULT 1;
+
+ALTER TABLE `turns`
+    ADD COLUMN `version` int NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+ALTER TABLE `outbox`
+    ADD COLUMN `attempts` int NOT NULL DEFAULT 0,
+    ADD COLUMN `last_error` varchar(500);
+
+DROP TABLE `outbox_deliveries`;
@@ -0,0 +1,22 @@
+CREATE TABLE `outbox_deliveries`
+(
+    `outbox_id`    bigint      NOT NULL,
+    `sink`         varchar(50) NOT NULL,
+    `status`       varchar(20) NOT NULL DEFAULT 'pending',
+    `attempts`     int         NOT NULL DEFAULT 0,
+    `last_error`   varchar(500),
+    `available_at` timestamp   NOT NULL DEFAULT now(),
+    `created_at`   timestamp default now(),
+    `processed_at` timestamp   NULL,
+    PRIMARY KEY (`outbox_id`, `sink`)
+);
+
+ALTER TABLE `outbox_deliveries`
+    ADD CONSTRAINT outbox_deliveries_outbox_id_fk
+        FOREIGN KEY (`outbox_id`) REFERENCES `outbox` (`id`) ON DELETE CASCADE;
+
+CREATE INDEX `outbox_deliveries_index_0` ON `outbox_deliveries` (`sink`, `status`, `available_at`, `outbox_id`);
+
+ALTER TABLE `outbox`
+    DROP COLUMN `attempts`,
+    DROP COLUMN `last_error`;
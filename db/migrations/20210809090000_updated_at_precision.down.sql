@@ -0,0 +1,8 @@
+ALTER TABLE `teams`
+    MODIFY `updated_at` timestamp NULL DEFAULT now();
+
+ALTER TABLE `people`
+    MODIFY `updated_at` timestamp NULL DEFAULT now();
+
+ALTER TABLE `turns`
+    MODIFY `updated_at` timestamp NULL DEFAULT now();
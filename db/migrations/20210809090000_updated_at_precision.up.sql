@@ -0,0 +1,17 @@
+UPDATE `teams`
+SET `updated_at` = COALESCE(`updated_at`, `created_at`, now());
+
+UPDATE `people`
+SET `updated_at` = COALESCE(`updated_at`, `created_at`, now());
+
+UPDATE `turns`
+SET `updated_at` = COALESCE(`updated_at`, `created_at`, now());
+
+ALTER TABLE `teams`
+    MODIFY `updated_at` timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6);
+
+ALTER TABLE `people`
+    MODIFY `updated_at` timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6);
+
+ALTER TABLE `turns`
+    MODIFY `updated_at` timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6);
@@ -7,27 +7,111 @@ import (
 	"time"
 )
 
+type Job struct {
+	TeamID         int64          `json:"team_id"`
+	Kind           string         `json:"kind"`
+	Schedule       string         `json:"schedule"`
+	Enabled        bool           `json:"enabled"`
+	NextRunAt      time.Time      `json:"next_run_at"`
+	LastRunAt      sql.NullTime   `json:"last_run_at"`
+	LastStatus     sql.NullString `json:"last_status"`
+	LastResult     sql.NullString `json:"last_result"`
+	LastDurationMs sql.NullInt32  `json:"last_duration_ms"`
+	CreatedAt      sql.NullTime   `json:"created_at"`
+	UpdatedAt      sql.NullTime   `json:"updated_at"`
+}
+
+type Outbox struct {
+	ID          int64        `json:"id"`
+	EventID     string       `json:"event_id"`
+	EventType   string       `json:"event_type"`
+	TeamID      int64        `json:"team_id"`
+	Payload     string       `json:"payload"`
+	Status      string       `json:"status"`
+	AvailableAt time.Time    `json:"available_at"`
+	CreatedAt   sql.NullTime `json:"created_at"`
+	ProcessedAt sql.NullTime `json:"processed_at"`
+}
+
+type OutboxDelivery struct {
+	OutboxID    int64          `json:"outbox_id"`
+	Sink        string         `json:"sink"`
+	Status      string         `json:"status"`
+	Attempts    int32          `json:"attempts"`
+	LastError   sql.NullString `json:"last_error"`
+	AvailableAt time.Time      `json:"available_at"`
+	CreatedAt   sql.NullTime   `json:"created_at"`
+	ProcessedAt sql.NullTime   `json:"processed_at"`
+}
+
 type Person struct {
-	ID        int64        `json:"id"`
-	FirstName string       `json:"first_name"`
-	LastName  string       `json:"last_name"`
-	Email     string       `json:"email"`
+	ID             int64     `json:"id"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Email          string    `json:"email"`
+	TeamID         int64     `json:"team_id"`
+	EmailReminders bool      `json:"email_reminders"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Version        int32     `json:"version"`
+}
+
+type SlackChannel struct {
+	ChannelID string       `json:"channel_id"`
 	TeamID    int64        `json:"team_id"`
 	CreatedAt sql.NullTime `json:"created_at"`
-	UpdatedAt sql.NullTime `json:"updated_at"`
+}
+
+type SlackSetting struct {
+	TeamID           int64          `json:"team_id"`
+	WebhookUrl       string         `json:"webhook_url"`
+	NotifyAssigned   bool           `json:"notify_assigned"`
+	ReminderEnabled  bool           `json:"reminder_enabled"`
+	ReminderTime     string         `json:"reminder_time"`
+	AssignedTemplate sql.NullString `json:"assigned_template"`
+	ReminderTemplate sql.NullString `json:"reminder_template"`
+	LastReminderOn   sql.NullTime   `json:"last_reminder_on"`
+	CreatedAt        sql.NullTime   `json:"created_at"`
+	UpdatedAt        sql.NullTime   `json:"updated_at"`
 }
 
 type Team struct {
-	ID        int64        `json:"id"`
-	Name      string       `json:"name"`
-	CreatedAt sql.NullTime `json:"created_at"`
-	UpdatedAt sql.NullTime `json:"updated_at"`
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int32     `json:"version"`
 }
 
 type Turn struct {
+	ID              int64     `json:"id"`
+	PersonID        int64     `json:"person_id"`
+	Date            time.Time `json:"date"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	EmailRemindedAt time.Time `json:"email_reminded_at"`
+	Version         int32     `json:"version"`
+}
+
+type Webhook struct {
 	ID        int64        `json:"id"`
-	PersonID  int64        `json:"person_id"`
-	Date      time.Time    `json:"date"`
+	TeamID    int64        `json:"team_id"`
+	Url       string       `json:"url"`
+	Secret    string       `json:"secret"`
+	Events    string       `json:"events"`
 	CreatedAt sql.NullTime `json:"created_at"`
 	UpdatedAt sql.NullTime `json:"updated_at"`
 }
+
+type WebhookDelivery struct {
+	ID         int64          `json:"id"`
+	WebhookID  int64          `json:"webhook_id"`
+	EventID    string         `json:"event_id"`
+	EventType  string         `json:"event_type"`
+	Attempt    int32          `json:"attempt"`
+	Succeeded  bool           `json:"succeeded"`
+	StatusCode sql.NullInt32  `json:"status_code"`
+	Error      sql.NullString `json:"error"`
+	DurationMs int32          `json:"duration_ms"`
+	CreatedAt  sql.NullTime   `json:"created_at"`
+}
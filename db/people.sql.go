@@ -6,6 +6,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 const createPerson = `-- name: CreatePerson :execresult
@@ -13,17 +14,19 @@ INSERT INTO people (
     first_name,
     last_name,
     email,
-    team_id
+    team_id,
+    email_reminders
 ) VALUES (
-    ?, ?, ?, ?
+    ?, ?, ?, ?, ?
 )
 `
 
 type CreatePersonParams struct {
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Email     string `json:"email"`
-	TeamID    int64  `json:"team_id"`
+	FirstName      string `json:"first_name"`
+	LastName       string `json:"last_name"`
+	Email          string `json:"email"`
+	TeamID         int64  `json:"team_id"`
+	EmailReminders bool   `json:"email_reminders"`
 }
 
 func (q *Queries) CreatePerson(ctx context.Context, arg CreatePersonParams) (sql.Result, error) {
@@ -32,6 +35,7 @@ func (q *Queries) CreatePerson(ctx context.Context, arg CreatePersonParams) (sql
 		arg.LastName,
 		arg.Email,
 		arg.TeamID,
+		arg.EmailReminders,
 	)
 }
 
@@ -52,7 +56,7 @@ func (q *Queries) DeletePerson(ctx context.Context, arg DeletePersonParams) erro
 }
 
 const getPerson = `-- name: GetPerson :one
-SELECT id, first_name, last_name, email, team_id
+SELECT id, first_name, last_name, email, team_id, email_reminders, version, updated_at
 FROM people
 WHERE id = ?
   AND team_id = ?
@@ -65,11 +69,14 @@ type GetPersonParams struct {
 }
 
 type GetPersonRow struct {
-	ID        int64  `json:"id"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Email     string `json:"email"`
-	TeamID    int64  `json:"team_id"`
+	ID             int64     `json:"id"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Email          string    `json:"email"`
+	TeamID         int64     `json:"team_id"`
+	EmailReminders bool      `json:"email_reminders"`
+	Version        int32     `json:"version"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 func (q *Queries) GetPerson(ctx context.Context, arg GetPersonParams) (GetPersonRow, error) {
@@ -81,23 +88,63 @@ func (q *Queries) GetPerson(ctx context.Context, arg GetPersonParams) (GetPerson
 		&i.LastName,
 		&i.Email,
 		&i.TeamID,
+		&i.EmailReminders,
+		&i.Version,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getPersonByID = `-- name: GetPersonByID :one
+SELECT id, first_name, last_name, email, team_id, email_reminders, version, updated_at
+FROM people
+WHERE id = ?
+LIMIT 1
+`
+
+type GetPersonByIDRow struct {
+	ID             int64     `json:"id"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Email          string    `json:"email"`
+	TeamID         int64     `json:"team_id"`
+	EmailReminders bool      `json:"email_reminders"`
+	Version        int32     `json:"version"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (q *Queries) GetPersonByID(ctx context.Context, id int64) (GetPersonByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getPersonByID, id)
+	var i GetPersonByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.TeamID,
+		&i.EmailReminders,
+		&i.Version,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
 const listPeople = `-- name: ListPeople :many
-SELECT id, first_name, last_name, email, team_id
+SELECT id, first_name, last_name, email, team_id, email_reminders, version, updated_at
 FROM people
 WHERE team_id = ?
 ORDER BY id
 `
 
 type ListPeopleRow struct {
-	ID        int64  `json:"id"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Email     string `json:"email"`
-	TeamID    int64  `json:"team_id"`
+	ID             int64     `json:"id"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Email          string    `json:"email"`
+	TeamID         int64     `json:"team_id"`
+	EmailReminders bool      `json:"email_reminders"`
+	Version        int32     `json:"version"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 func (q *Queries) ListPeople(ctx context.Context, teamID int64) ([]ListPeopleRow, error) {
@@ -115,6 +162,9 @@ func (q *Queries) ListPeople(ctx context.Context, teamID int64) ([]ListPeopleRow
 			&i.LastName,
 			&i.Email,
 			&i.TeamID,
+			&i.EmailReminders,
+			&i.Version,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -131,16 +181,19 @@ func (q *Queries) ListPeople(ctx context.Context, teamID int64) ([]ListPeopleRow
 
 const updatePerson = `-- name: UpdatePerson :execresult
 UPDATE people
-SET first_name = ?, last_name = ?, email = ?, team_id = ?
+SET first_name = ?, last_name = ?, email = ?, team_id = ?, email_reminders = ?, updated_at = now(6), version = version + 1
 WHERE id = ?
+  AND version = ?
 `
 
 type UpdatePersonParams struct {
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Email     string `json:"email"`
-	TeamID    int64  `json:"team_id"`
-	ID        int64  `json:"id"`
+	FirstName      string `json:"first_name"`
+	LastName       string `json:"last_name"`
+	Email          string `json:"email"`
+	TeamID         int64  `json:"team_id"`
+	EmailReminders bool   `json:"email_reminders"`
+	ID             int64  `json:"id"`
+	Version        int32  `json:"version"`
 }
 
 func (q *Queries) UpdatePerson(ctx context.Context, arg UpdatePersonParams) (sql.Result, error) {
@@ -149,6 +202,8 @@ func (q *Queries) UpdatePerson(ctx context.Context, arg UpdatePersonParams) (sql
 		arg.LastName,
 		arg.Email,
 		arg.TeamID,
+		arg.EmailReminders,
 		arg.ID,
+		arg.Version,
 	)
 }
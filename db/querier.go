@@ -5,29 +5,68 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 type Querier interface {
+	ClaimEmailReminder(ctx context.Context, id int64) (sql.Result, error)
+	ClaimOutboxDeliveries(ctx context.Context, arg ClaimOutboxDeliveriesParams) ([]ClaimOutboxDeliveriesRow, error)
+	ClaimOutboxEvents(ctx context.Context, limit int32) ([]int64, error)
+	ClaimSlackReminder(ctx context.Context, arg ClaimSlackReminderParams) (sql.Result, error)
+	CreateOutboxDelivery(ctx context.Context, arg CreateOutboxDeliveryParams) error
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (sql.Result, error)
 	CreatePerson(ctx context.Context, arg CreatePersonParams) (sql.Result, error)
 	CreateTeam(ctx context.Context, name string) (sql.Result, error)
 	CreateTurn(ctx context.Context, arg CreateTurnParams) (sql.Result, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (sql.Result, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (sql.Result, error)
+	DeleteJob(ctx context.Context, arg DeleteJobParams) error
 	DeletePerson(ctx context.Context, arg DeletePersonParams) error
+	DeleteSlackChannel(ctx context.Context, arg DeleteSlackChannelParams) error
+	DeleteSlackSettings(ctx context.Context, teamID int64) error
 	DeleteTeam(ctx context.Context, id int64) error
 	DeleteTurn(ctx context.Context, arg DeleteTurnParams) error
+	DeleteTurnsBefore(ctx context.Context, arg DeleteTurnsBeforeParams) (sql.Result, error)
+	DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) error
+	DeleteWebhookDeliveriesBefore(ctx context.Context, arg DeleteWebhookDeliveriesBeforeParams) (sql.Result, error)
+	FinishJobRun(ctx context.Context, arg FinishJobRunParams) error
+	GetJob(ctx context.Context, arg GetJobParams) (Job, error)
 	GetPerson(ctx context.Context, arg GetPersonParams) (GetPersonRow, error)
+	GetPersonByID(ctx context.Context, id int64) (GetPersonByIDRow, error)
+	GetSlackChannel(ctx context.Context, channelID string) (SlackChannel, error)
+	GetSlackSettings(ctx context.Context, teamID int64) (SlackSetting, error)
 	GetTeam(ctx context.Context, id int64) (GetTeamRow, error)
 	GetTurn(ctx context.Context, arg GetTurnParams) (GetTurnRow, error)
 	GetTurnByDate(ctx context.Context, arg GetTurnByDateParams) (GetTurnByDateRow, error)
 	GetTurnByDateAndTeam(ctx context.Context, arg GetTurnByDateAndTeamParams) (GetTurnByDateAndTeamRow, error)
+	GetWebhook(ctx context.Context, arg GetWebhookParams) (Webhook, error)
+	LeaseOutboxDelivery(ctx context.Context, arg LeaseOutboxDeliveryParams) error
+	ListCalendarTurns(ctx context.Context, arg ListCalendarTurnsParams) ([]ListCalendarTurnsRow, error)
+	ListDueJobs(ctx context.Context, nextRunAt time.Time) ([]Job, error)
+	ListEmailReminders(ctx context.Context, date time.Time) ([]ListEmailRemindersRow, error)
+	ListJobs(ctx context.Context, teamID int64) ([]Job, error)
 	ListPeople(ctx context.Context, teamID int64) ([]ListPeopleRow, error)
+	ListSlackChannels(ctx context.Context, teamID int64) ([]SlackChannel, error)
+	ListSlackReminders(ctx context.Context) ([]SlackSetting, error)
 	ListTeams(ctx context.Context) ([]ListTeamsRow, error)
 	ListTurns(ctx context.Context, arg ListTurnsParams) ([]ListTurnsRow, error)
 	ListTurnsWithBothDates(ctx context.Context, arg ListTurnsWithBothDatesParams) ([]ListTurnsWithBothDatesRow, error)
 	ListTurnsWithDateFrom(ctx context.Context, arg ListTurnsWithDateFromParams) ([]ListTurnsWithDateFromRow, error)
 	ListTurnsWithDateTo(ctx context.Context, arg ListTurnsWithDateToParams) ([]ListTurnsWithDateToRow, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhooks(ctx context.Context, teamID int64) ([]Webhook, error)
+	MarkOutboxDeliveryDone(ctx context.Context, arg MarkOutboxDeliveryDoneParams) error
+	MarkOutboxDeliveryFailed(ctx context.Context, arg MarkOutboxDeliveryFailedParams) error
+	MarkOutboxEventDone(ctx context.Context, id int64) error
+	ReleaseEmailReminder(ctx context.Context, id int64) error
+	ReleaseSlackReminder(ctx context.Context, arg ReleaseSlackReminderParams) error
 	UpdatePerson(ctx context.Context, arg UpdatePersonParams) (sql.Result, error)
 	UpdateTeam(ctx context.Context, arg UpdateTeamParams) (sql.Result, error)
 	UpdateTurn(ctx context.Context, arg UpdateTurnParams) (sql.Result, error)
+	UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (sql.Result, error)
+	UpsertJob(ctx context.Context, arg UpsertJobParams) error
+	UpsertSlackChannel(ctx context.Context, arg UpsertSlackChannelParams) error
+	UpsertSlackSettings(ctx context.Context, arg UpsertSlackSettingsParams) error
 }
 
 var _ Querier = (*Queries)(nil)
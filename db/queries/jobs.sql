@@ -0,0 +1,43 @@
+-- name: ListJobs :many
+SELECT team_id, kind, schedule, enabled, next_run_at, last_run_at, last_status, last_result, last_duration_ms, created_at, updated_at
+FROM jobs
+WHERE team_id = ?
+ORDER BY kind;
+
+-- name: ListDueJobs :many
+SELECT team_id, kind, schedule, enabled, next_run_at, last_run_at, last_status, last_result, last_duration_ms, created_at, updated_at
+FROM jobs
+WHERE enabled = true
+  AND next_run_at <= ?
+ORDER BY next_run_at;
+
+-- name: GetJob :one
+SELECT team_id, kind, schedule, enabled, next_run_at, last_run_at, last_status, last_result, last_duration_ms, created_at, updated_at
+FROM jobs
+WHERE team_id = ?
+  AND kind = ?
+LIMIT 1;
+
+-- name: UpsertJob :exec
+INSERT INTO jobs (team_id, kind, schedule, enabled, next_run_at)
+VALUES (?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE schedule    = VALUES(schedule),
+                        enabled     = VALUES(enabled),
+                        next_run_at = VALUES(next_run_at),
+                        updated_at  = now();
+
+-- name: FinishJobRun :exec
+UPDATE jobs
+SET last_run_at      = ?,
+    last_status      = ?,
+    last_result      = ?,
+    last_duration_ms = ?,
+    next_run_at      = ?
+WHERE team_id = ?
+  AND kind = ?;
+
+-- name: DeleteJob :exec
+DELETE
+FROM jobs
+WHERE team_id = ?
+  AND kind = ?;
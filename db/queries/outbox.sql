@@ -0,0 +1,53 @@
+-- name: CreateOutboxEvent :execresult
+INSERT INTO outbox (event_id, event_type, team_id, payload)
+VALUES (?, ?, ?, ?);
+
+-- name: ClaimOutboxEvents :many
+SELECT id
+FROM outbox
+WHERE status = 'pending'
+ORDER BY id
+LIMIT ? FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventDone :exec
+UPDATE outbox
+SET status       = 'done',
+    processed_at = now()
+WHERE id = ?;
+
+-- name: CreateOutboxDelivery :exec
+INSERT IGNORE INTO outbox_deliveries (outbox_id, sink)
+VALUES (?, ?);
+
+-- name: ClaimOutboxDeliveries :many
+SELECT d.outbox_id, d.attempts, o.event_id, o.event_type, o.payload
+FROM outbox_deliveries d
+         JOIN outbox o ON o.id = d.outbox_id
+WHERE d.sink = ?
+  AND d.status = 'pending'
+  AND d.available_at <= now()
+ORDER BY d.outbox_id
+LIMIT ? FOR UPDATE OF d SKIP LOCKED;
+
+-- name: LeaseOutboxDelivery :exec
+UPDATE outbox_deliveries
+SET available_at = now() + INTERVAL sqlc.arg(lease_seconds) SECOND
+WHERE outbox_id = ?
+  AND sink = ?;
+
+-- name: MarkOutboxDeliveryDone :exec
+UPDATE outbox_deliveries
+SET status       = 'done',
+    attempts     = attempts + 1,
+    processed_at = now()
+WHERE outbox_id = ?
+  AND sink = ?;
+
+-- name: MarkOutboxDeliveryFailed :exec
+UPDATE outbox_deliveries
+SET status       = ?,
+    attempts     = attempts + 1,
+    last_error   = ?,
+    available_at = now() + INTERVAL sqlc.arg(delay_seconds) SECOND
+WHERE outbox_id = ?
+  AND sink = ?;
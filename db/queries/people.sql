@@ -1,30 +1,38 @@
 -- name: ListPeople :many
-SELECT id, first_name, last_name, email, team_id
+SELECT id, first_name, last_name, email, team_id, email_reminders, version, updated_at
 FROM people
 WHERE team_id = ?
 ORDER BY id;
 
 -- name: GetPerson :one
-SELECT id, first_name, last_name, email, team_id
+SELECT id, first_name, last_name, email, team_id, email_reminders, version, updated_at
 FROM people
 WHERE id = ?
   AND team_id = ?
 LIMIT 1;
 
+-- name: GetPersonByID :one
+SELECT id, first_name, last_name, email, team_id, email_reminders, version, updated_at
+FROM people
+WHERE id = ?
+LIMIT 1;
+
 -- name: CreatePerson :execresult
 INSERT INTO people (
     first_name,
     last_name,
     email,
-    team_id
+    team_id,
+    email_reminders
 ) VALUES (
-    ?, ?, ?, ?
+    ?, ?, ?, ?, ?
 );
 
 -- name: UpdatePerson :execresult
 UPDATE people
-SET first_name = ?, last_name = ?, email = ?, team_id = ?
-WHERE id = ?;
+SET first_name = ?, last_name = ?, email = ?, team_id = ?, email_reminders = ?, updated_at = now(6), version = version + 1
+WHERE id = ?
+  AND version = ?;
 
 -- name: DeletePerson :exec
 DELETE FROM people
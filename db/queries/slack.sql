@@ -0,0 +1,62 @@
+-- name: GetSlackSettings :one
+SELECT team_id, webhook_url, notify_assigned, reminder_enabled, reminder_time, assigned_template, reminder_template, last_reminder_on, created_at, updated_at
+FROM slack_settings
+WHERE team_id = ?
+LIMIT 1;
+
+-- name: ListSlackReminders :many
+SELECT team_id, webhook_url, notify_assigned, reminder_enabled, reminder_time, assigned_template, reminder_template, last_reminder_on, created_at, updated_at
+FROM slack_settings
+WHERE reminder_enabled = true
+ORDER BY team_id;
+
+-- name: UpsertSlackSettings :exec
+INSERT INTO slack_settings (team_id, webhook_url, notify_assigned, reminder_enabled, reminder_time, assigned_template, reminder_template)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE webhook_url       = VALUES(webhook_url),
+                        notify_assigned   = VALUES(notify_assigned),
+                        reminder_enabled  = VALUES(reminder_enabled),
+                        reminder_time     = VALUES(reminder_time),
+                        assigned_template = VALUES(assigned_template),
+                        reminder_template = VALUES(reminder_template),
+                        updated_at        = now();
+
+-- name: ClaimSlackReminder :execresult
+UPDATE slack_settings
+SET last_reminder_on = sqlc.arg(date)
+WHERE team_id = sqlc.arg(team_id)
+  AND (last_reminder_on IS NULL OR last_reminder_on < sqlc.arg(date));
+
+-- name: ReleaseSlackReminder :exec
+UPDATE slack_settings
+SET last_reminder_on = NULL
+WHERE team_id = ?
+  AND last_reminder_on = ?;
+
+-- name: DeleteSlackSettings :exec
+DELETE
+FROM slack_settings
+WHERE team_id = ?;
+
+-- name: ListSlackChannels :many
+SELECT channel_id, team_id, created_at
+FROM slack_channels
+WHERE team_id = ?
+ORDER BY channel_id;
+
+-- name: GetSlackChannel :one
+SELECT channel_id, team_id, created_at
+FROM slack_channels
+WHERE channel_id = ?
+LIMIT 1;
+
+-- name: UpsertSlackChannel :exec
+INSERT INTO slack_channels (channel_id, team_id)
+VALUES (?, ?)
+ON DUPLICATE KEY UPDATE team_id = VALUES(team_id);
+
+-- name: DeleteSlackChannel :exec
+DELETE
+FROM slack_channels
+WHERE channel_id = ?
+  AND team_id = ?;
@@ -1,11 +1,11 @@
 -- name: GetTeam :one
-SELECT id, name
+SELECT id, name, version, updated_at
 FROM teams
 WHERE id = ?
 LIMIT 1;
 
 -- name: ListTeams :many
-SELECT id, name
+SELECT id, name, version, updated_at
 FROM teams
 ORDER BY id;
 
@@ -15,8 +15,9 @@ VALUES ( ? );
 
 -- name: UpdateTeam :execresult
 UPDATE teams
-SET name = ?
-WHERE id = ?;
+SET name = ?, updated_at = now(6), version = version + 1
+WHERE id = ?
+  AND version = ?;
 
 -- name: DeleteTeam :exec
 DELETE FROM teams
@@ -1,5 +1,5 @@
 -- name: ListTurns :many
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE p.team_id = ?
@@ -7,7 +7,7 @@ ORDER BY t.date DESC
 LIMIT ? OFFSET ?;
 
 -- name: ListTurnsWithDateFrom :many
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE p.team_id = ?
@@ -16,7 +16,7 @@ ORDER BY t.date DESC
 LIMIT ? OFFSET ?;
 
 -- name: ListTurnsWithDateTo :many
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE p.team_id = ?
@@ -25,7 +25,7 @@ ORDER BY t.date DESC
 LIMIT ? OFFSET ?;
 
 -- name: ListTurnsWithBothDates :many
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE p.team_id = ?
@@ -35,7 +35,7 @@ ORDER BY t.date DESC
 LIMIT ? OFFSET ?;
 
 -- name: GetTurn :one
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE t.id = ?
@@ -43,7 +43,7 @@ WHERE t.id = ?
 LIMIT 1;
 
 -- name: GetTurnByDate :one
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE t.date = ?
@@ -51,7 +51,7 @@ WHERE t.date = ?
 LIMIT 1;
 
 -- name: GetTurnByDateAndTeam :one
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE t.date = ?
@@ -64,12 +64,52 @@ VALUES (?, ?);
 
 -- name: UpdateTurn :execresult
 UPDATE turns
-SET person_id = ?,
-    date      = ?
-WHERE id = ?;
+SET person_id         = ?,
+    date              = ?,
+    email_reminded_at = NULL,
+    updated_at        = now(6),
+    version           = version + 1
+WHERE id = ?
+  AND version = ?;
 
 -- name: DeleteTurn :exec
 DELETE
 FROM turns
 WHERE id = ?
-  AND person_id = ?;
\ No newline at end of file
+  AND person_id = ?;
+
+-- name: DeleteTurnsBefore :execresult
+DELETE t
+FROM turns t
+         JOIN people p ON t.person_id = p.id
+WHERE p.team_id = ?
+  AND t.date < ?;
+
+-- name: ListCalendarTurns :many
+SELECT t.id, t.date, t.updated_at, p.id AS person_id, p.first_name, p.last_name
+FROM turns t
+         JOIN people p ON t.person_id = p.id
+WHERE p.team_id = ?
+  AND t.date >= ?
+ORDER BY t.date;
+
+-- name: ListEmailReminders :many
+SELECT t.id, t.date, p.id AS person_id, p.first_name, p.last_name, p.email, tm.id AS team_id, tm.name AS team_name
+FROM turns t
+         JOIN people p ON t.person_id = p.id
+         JOIN teams tm ON p.team_id = tm.id
+WHERE t.date = ?
+  AND t.email_reminded_at IS NULL
+  AND p.email_reminders = true
+ORDER BY t.id;
+
+-- name: ClaimEmailReminder :execresult
+UPDATE turns
+SET email_reminded_at = now()
+WHERE id = ?
+  AND email_reminded_at IS NULL;
+
+-- name: ReleaseEmailReminder :exec
+UPDATE turns
+SET email_reminded_at = NULL
+WHERE id = ?;
\ No newline at end of file
@@ -0,0 +1,47 @@
+-- name: ListWebhooks :many
+SELECT id, team_id, url, secret, events, created_at, updated_at
+FROM webhooks
+WHERE team_id = ?
+ORDER BY id;
+
+-- name: GetWebhook :one
+SELECT id, team_id, url, secret, events, created_at, updated_at
+FROM webhooks
+WHERE id = ?
+  AND team_id = ?
+LIMIT 1;
+
+-- name: CreateWebhook :execresult
+INSERT INTO webhooks (team_id, url, secret, events)
+VALUES (?, ?, ?, ?);
+
+-- name: UpdateWebhook :execresult
+UPDATE webhooks
+SET url        = ?,
+    events     = ?,
+    updated_at = now()
+WHERE id = ?;
+
+-- name: DeleteWebhook :exec
+DELETE
+FROM webhooks
+WHERE id = ?
+  AND team_id = ?;
+
+-- name: CreateWebhookDelivery :execresult
+INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, attempt, succeeded, status_code, error, duration_ms)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListWebhookDeliveries :many
+SELECT id, webhook_id, event_id, event_type, attempt, succeeded, status_code, error, duration_ms, created_at
+FROM webhook_deliveries
+WHERE webhook_id = ?
+ORDER BY id DESC
+LIMIT ? OFFSET ?;
+
+-- name: DeleteWebhookDeliveriesBefore :execresult
+DELETE d
+FROM webhook_deliveries d
+         JOIN webhooks w ON d.webhook_id = w.id
+WHERE w.team_id = ?
+  AND d.created_at < ?;
@@ -1,15 +1,37 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"github.com/ezerw/wheel/util"
 	"net/url"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ezerw/wheel/util"
+)
+
+// Defaults of the connection pool unless set in the config.
+const (
+	defaultMaxOpenConns       = 20
+	defaultMaxIdleConns       = 10
+	defaultConnMaxLifetime    = 5 * time.Minute
+	defaultConnMaxIdleTime    = time.Minute
+	defaultConnectAttempts    = 10
+	defaultConnectBackoff     = time.Second
+	maxConnectBackoff         = 30 * time.Second
+	pingTimeout               = 5 * time.Second
+	defaultQueryTimeout       = 10 * time.Second
+	defaultSlowQueryThreshold = 500 * time.Millisecond
 )
 
 // Store defines all functions to execute db queries and transactions
 type Store interface {
 	Querier
+	ExecTx(ctx context.Context, fn func(Querier) error) error
+	TryLock(ctx context.Context, name string, fn func() error) (bool, error)
 }
 
 // SQLStore provides all functions to execute SQL queries and transactions
@@ -18,7 +40,11 @@ type SQLStore struct {
 	*Queries
 }
 
-func Connect(config util.Config) (*sql.DB, error) {
+// Connect opens the pool of connections to the database configured. Its
+// statements are limited to DB_QUERY_TIMEOUT unless their context ends
+// sooner, or it's negative, and those taking DB_SLOW_QUERY_THRESHOLD or
+// longer are logged.
+func Connect(config util.Config, logger *logrus.Logger) (*sql.DB, error) {
 	dbDSN := fmt.Sprintf(
 		"%s:%s@tcp(%s:%s)/%s?parseTime=true&loc=%s&multiStatements=true",
 		config.DBUser,
@@ -28,18 +54,140 @@ func Connect(config util.Config) (*sql.DB, error) {
 		config.DBName,
 		url.QueryEscape(config.AppTimezone),
 	)
-	conn, err := sql.Open("mysql", dbDSN)
+	mysqlConfig, err := mysql.ParseDSN(dbDSN)
 	if err != nil {
 		return nil, err
 	}
+	mysqlConnector, err := mysql.NewConnector(mysqlConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	queryTimeout := config.DBQueryTimeout
+	if queryTimeout == 0 {
+		queryTimeout = defaultQueryTimeout
+	}
+	slowQueryThreshold := config.DBSlowQueryThreshold
+	if slowQueryThreshold == 0 {
+		slowQueryThreshold = defaultSlowQueryThreshold
+	}
+
+	conn := sql.OpenDB(&connector{
+		Connector: mysqlConnector,
+		timeout:   queryTimeout,
+		slow:      slowQueryThreshold,
+		logger:    logger,
+	})
+	conn.SetMaxOpenConns(intOr(config.DBMaxOpenConns, defaultMaxOpenConns))
+	conn.SetMaxIdleConns(intOr(config.DBMaxIdleConns, defaultMaxIdleConns))
+	conn.SetConnMaxLifetime(durationOr(config.DBConnMaxLifetime, defaultConnMaxLifetime))
+	conn.SetConnMaxIdleTime(durationOr(config.DBConnMaxIdleTime, defaultConnMaxIdleTime))
 
 	return conn, nil
 }
 
-// NewStore creates a new store
+// Ping waits for the database to answer, trying DB_CONNECT_ATTEMPTS times
+// with a backoff starting at DB_CONNECT_BACKOFF and doubling, so the API
+// can start along with the database.
+func Ping(ctx context.Context, conn *sql.DB, config util.Config, logger *logrus.Logger) error {
+	attempts := intOr(config.DBConnectAttempts, defaultConnectAttempts)
+	backoff := durationOr(config.DBConnectBackoff, defaultConnectBackoff)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err = conn.PingContext(pingCtx)
+		cancel()
+		if err == nil || attempt >= attempts {
+			return err
+		}
+
+		logger.WithError(err).
+			WithField("attempt", attempt).
+			WithField("retry_in", backoff.String()).
+			Warn("cannot ping the database")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+}
+
 func NewStore(db *sql.DB) Store {
 	return &SQLStore{
 		db:      db,
-		Queries: New(db),
+		Queries: New(newTracedDB(db)),
+	}
+}
+
+// Stats returns the connection pool stats of the database.
+func (store *SQLStore) Stats() sql.DBStats {
+	return store.db.Stats()
+}
+
+// ExecTx runs fn with queries bound to a new transaction, which is committed
+// when fn succeeds and rolled back otherwise.
+func (store *SQLStore) ExecTx(ctx context.Context, fn func(Querier) error) error {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(New(newTracedDB(tx))); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// TryLock runs fn holding the MySQL named lock name, so only one instance
+// of the API runs it at a time. It doesn't wait for the lock: when another
+// connection holds it fn isn't run and TryLock returns false.
+func (store *SQLStore) TryLock(ctx context.Context, name string, fn func() error) (bool, error) {
+	// Named locks belong to the connection which got them.
+	conn, err := store.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&acquired)
+	if err != nil {
+		return false, err
+	}
+	if acquired.Int64 != 1 {
+		return false, nil
+	}
+
+	err = fn()
+
+	var released sql.NullInt64
+	if rlErr := conn.QueryRowContext(context.Background(), "SELECT RELEASE_LOCK(?)", name).Scan(&released); rlErr != nil && err == nil {
+		err = rlErr
+	}
+	return true, err
+}
+
+// durationOr returns d, or def when d isn't positive.
+func durationOr(d time.Duration, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// intOr returns n, or def when n isn't positive.
+func intOr(n int, def int) int {
+	if n <= 0 {
+		return def
 	}
+	return n
 }
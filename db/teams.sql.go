@@ -6,6 +6,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 const createTeam = `-- name: CreateTeam :execresult
@@ -28,33 +29,37 @@ func (q *Queries) DeleteTeam(ctx context.Context, id int64) error {
 }
 
 const getTeam = `-- name: GetTeam :one
-SELECT id, name
+SELECT id, name, version, updated_at
 FROM teams
 WHERE id = ?
 LIMIT 1
 `
 
 type GetTeamRow struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Version   int32     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 func (q *Queries) GetTeam(ctx context.Context, id int64) (GetTeamRow, error) {
 	row := q.db.QueryRowContext(ctx, getTeam, id)
 	var i GetTeamRow
-	err := row.Scan(&i.ID, &i.Name)
+	err := row.Scan(&i.ID, &i.Name, &i.Version, &i.UpdatedAt)
 	return i, err
 }
 
 const listTeams = `-- name: ListTeams :many
-SELECT id, name
+SELECT id, name, version, updated_at
 FROM teams
 ORDER BY id
 `
 
 type ListTeamsRow struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Version   int32     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 func (q *Queries) ListTeams(ctx context.Context) ([]ListTeamsRow, error) {
@@ -66,7 +71,7 @@ func (q *Queries) ListTeams(ctx context.Context) ([]ListTeamsRow, error) {
 	items := []ListTeamsRow{}
 	for rows.Next() {
 		var i ListTeamsRow
-		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+		if err := rows.Scan(&i.ID, &i.Name, &i.Version, &i.UpdatedAt); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -82,15 +87,17 @@ func (q *Queries) ListTeams(ctx context.Context) ([]ListTeamsRow, error) {
 
 const updateTeam = `-- name: UpdateTeam :execresult
 UPDATE teams
-SET name = ?
+SET name = ?, updated_at = now(6), version = version + 1
 WHERE id = ?
+  AND version = ?
 `
 
 type UpdateTeamParams struct {
-	Name string `json:"name"`
-	ID   int64  `json:"id"`
+	Name    string `json:"name"`
+	ID      int64  `json:"id"`
+	Version int32  `json:"version"`
 }
 
 func (q *Queries) UpdateTeam(ctx context.Context, arg UpdateTeamParams) (sql.Result, error) {
-	return q.db.ExecContext(ctx, updateTeam, arg.Name, arg.ID)
+	return q.db.ExecContext(ctx, updateTeam, arg.Name, arg.ID, arg.Version)
 }
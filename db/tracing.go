@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer traces the SQL statements.
+var tracer = otel.Tracer("github.com/ezerw/wheel/db")
+
+// tracedDB traces the statements run on db, each in a span named after the
+// sqlc query.
+type tracedDB struct {
+	db DBTX
+}
+
+// newTracedDB wraps db so its statements are traced.
+func newTracedDB(db DBTX) DBTX {
+	return &tracedDB{db: db}
+}
+
+func (t *tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startSpan(ctx, query)
+	defer span.End()
+
+	result, err := t.db.ExecContext(ctx, query, args...)
+	endSpan(span, err)
+	return result, err
+}
+
+func (t *tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := startSpan(ctx, query)
+	defer span.End()
+
+	stmt, err := t.db.PrepareContext(ctx, query)
+	endSpan(span, err)
+	return stmt, err
+}
+
+func (t *tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startSpan(ctx, query)
+	defer span.End()
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	endSpan(span, err)
+	return rows, err
+}
+
+func (t *tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startSpan(ctx, query)
+	defer span.End()
+
+	row := t.db.QueryRowContext(ctx, query, args...)
+	// sql.ErrNoRows is an expected outcome, not a failure of the statement.
+	if err := row.Err(); err != nil && err != sql.ErrNoRows {
+		endSpan(span, err)
+	}
+	return row
+}
+
+// startSpan starts the span of a statement.
+func startSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "sql "+queryName(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMySQL,
+			semconv.DBStatement(query),
+		),
+	)
+}
+
+// endSpan records err, if any, on span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// queryName returns the name sqlc gave query in its "-- name: GetTeam :one"
+// comment, or "query" for statements written by hand.
+func queryName(query string) string {
+	const prefix = "-- name: "
+	if !strings.HasPrefix(query, prefix) {
+		return "query"
+	}
+	name := strings.TrimPrefix(query, prefix)
+	if i := strings.IndexAny(name, " \n"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
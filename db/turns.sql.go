@@ -9,6 +9,17 @@ import (
 	"time"
 )
 
+const claimEmailReminder = `-- name: ClaimEmailReminder :execresult
+UPDATE turns
+SET email_reminded_at = now()
+WHERE id = ?
+  AND email_reminded_at IS NULL
+`
+
+func (q *Queries) ClaimEmailReminder(ctx context.Context, id int64) (sql.Result, error) {
+	return q.db.ExecContext(ctx, claimEmailReminder, id)
+}
+
 const createTurn = `-- name: CreateTurn :execresult
 INSERT INTO turns (person_id, date)
 VALUES (?, ?)
@@ -40,8 +51,25 @@ func (q *Queries) DeleteTurn(ctx context.Context, arg DeleteTurnParams) error {
 	return err
 }
 
+const deleteTurnsBefore = `-- name: DeleteTurnsBefore :execresult
+DELETE t
+FROM turns t
+         JOIN people p ON t.person_id = p.id
+WHERE p.team_id = ?
+  AND t.date < ?
+`
+
+type DeleteTurnsBeforeParams struct {
+	TeamID int64     `json:"team_id"`
+	Date   time.Time `json:"date"`
+}
+
+func (q *Queries) DeleteTurnsBefore(ctx context.Context, arg DeleteTurnsBeforeParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, deleteTurnsBefore, arg.TeamID, arg.Date)
+}
+
 const getTurn = `-- name: GetTurn :one
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE t.id = ?
@@ -59,6 +87,8 @@ type GetTurnRow struct {
 	PersonID  int64        `json:"person_id"`
 	Date      time.Time    `json:"date"`
 	CreatedAt sql.NullTime `json:"created_at"`
+	Version   int32        `json:"version"`
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
 func (q *Queries) GetTurn(ctx context.Context, arg GetTurnParams) (GetTurnRow, error) {
@@ -69,12 +99,14 @@ func (q *Queries) GetTurn(ctx context.Context, arg GetTurnParams) (GetTurnRow, e
 		&i.PersonID,
 		&i.Date,
 		&i.CreatedAt,
+		&i.Version,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
 const getTurnByDate = `-- name: GetTurnByDate :one
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE t.date = ?
@@ -92,6 +124,8 @@ type GetTurnByDateRow struct {
 	PersonID  int64        `json:"person_id"`
 	Date      time.Time    `json:"date"`
 	CreatedAt sql.NullTime `json:"created_at"`
+	Version   int32        `json:"version"`
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
 func (q *Queries) GetTurnByDate(ctx context.Context, arg GetTurnByDateParams) (GetTurnByDateRow, error) {
@@ -102,12 +136,14 @@ func (q *Queries) GetTurnByDate(ctx context.Context, arg GetTurnByDateParams) (G
 		&i.PersonID,
 		&i.Date,
 		&i.CreatedAt,
+		&i.Version,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
 const getTurnByDateAndTeam = `-- name: GetTurnByDateAndTeam :one
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE t.date = ?
@@ -125,6 +161,8 @@ type GetTurnByDateAndTeamRow struct {
 	PersonID  int64        `json:"person_id"`
 	Date      time.Time    `json:"date"`
 	CreatedAt sql.NullTime `json:"created_at"`
+	Version   int32        `json:"version"`
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
 func (q *Queries) GetTurnByDateAndTeam(ctx context.Context, arg GetTurnByDateAndTeamParams) (GetTurnByDateAndTeamRow, error) {
@@ -135,12 +173,121 @@ func (q *Queries) GetTurnByDateAndTeam(ctx context.Context, arg GetTurnByDateAnd
 		&i.PersonID,
 		&i.Date,
 		&i.CreatedAt,
+		&i.Version,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const listCalendarTurns = `-- name: ListCalendarTurns :many
+SELECT t.id, t.date, t.updated_at, p.id AS person_id, p.first_name, p.last_name
+FROM turns t
+         JOIN people p ON t.person_id = p.id
+WHERE p.team_id = ?
+  AND t.date >= ?
+ORDER BY t.date
+`
+
+type ListCalendarTurnsParams struct {
+	TeamID int64     `json:"team_id"`
+	Date   time.Time `json:"date"`
+}
+
+type ListCalendarTurnsRow struct {
+	ID        int64     `json:"id"`
+	Date      time.Time `json:"date"`
+	UpdatedAt time.Time `json:"updated_at"`
+	PersonID  int64     `json:"person_id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+}
+
+func (q *Queries) ListCalendarTurns(ctx context.Context, arg ListCalendarTurnsParams) ([]ListCalendarTurnsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listCalendarTurns, arg.TeamID, arg.Date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCalendarTurnsRow{}
+	for rows.Next() {
+		var i ListCalendarTurnsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.UpdatedAt,
+			&i.PersonID,
+			&i.FirstName,
+			&i.LastName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEmailReminders = `-- name: ListEmailReminders :many
+SELECT t.id, t.date, p.id AS person_id, p.first_name, p.last_name, p.email, tm.id AS team_id, tm.name AS team_name
+FROM turns t
+         JOIN people p ON t.person_id = p.id
+         JOIN teams tm ON p.team_id = tm.id
+WHERE t.date = ?
+  AND t.email_reminded_at IS NULL
+  AND p.email_reminders = true
+ORDER BY t.id
+`
+
+type ListEmailRemindersRow struct {
+	ID        int64     `json:"id"`
+	Date      time.Time `json:"date"`
+	PersonID  int64     `json:"person_id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Email     string    `json:"email"`
+	TeamID    int64     `json:"team_id"`
+	TeamName  string    `json:"team_name"`
+}
+
+func (q *Queries) ListEmailReminders(ctx context.Context, date time.Time) ([]ListEmailRemindersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listEmailReminders, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListEmailRemindersRow{}
+	for rows.Next() {
+		var i ListEmailRemindersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.PersonID,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.TeamID,
+			&i.TeamName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTurns = `-- name: ListTurns :many
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE p.team_id = ?
@@ -159,6 +306,8 @@ type ListTurnsRow struct {
 	PersonID  int64        `json:"person_id"`
 	Date      time.Time    `json:"date"`
 	CreatedAt sql.NullTime `json:"created_at"`
+	Version   int32        `json:"version"`
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
 func (q *Queries) ListTurns(ctx context.Context, arg ListTurnsParams) ([]ListTurnsRow, error) {
@@ -175,6 +324,8 @@ func (q *Queries) ListTurns(ctx context.Context, arg ListTurnsParams) ([]ListTur
 			&i.PersonID,
 			&i.Date,
 			&i.CreatedAt,
+			&i.Version,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -190,7 +341,7 @@ func (q *Queries) ListTurns(ctx context.Context, arg ListTurnsParams) ([]ListTur
 }
 
 const listTurnsWithBothDates = `-- name: ListTurnsWithBothDates :many
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE p.team_id = ?
@@ -213,6 +364,8 @@ type ListTurnsWithBothDatesRow struct {
 	PersonID  int64        `json:"person_id"`
 	Date      time.Time    `json:"date"`
 	CreatedAt sql.NullTime `json:"created_at"`
+	Version   int32        `json:"version"`
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
 func (q *Queries) ListTurnsWithBothDates(ctx context.Context, arg ListTurnsWithBothDatesParams) ([]ListTurnsWithBothDatesRow, error) {
@@ -235,6 +388,8 @@ func (q *Queries) ListTurnsWithBothDates(ctx context.Context, arg ListTurnsWithB
 			&i.PersonID,
 			&i.Date,
 			&i.CreatedAt,
+			&i.Version,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -250,7 +405,7 @@ func (q *Queries) ListTurnsWithBothDates(ctx context.Context, arg ListTurnsWithB
 }
 
 const listTurnsWithDateFrom = `-- name: ListTurnsWithDateFrom :many
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE p.team_id = ?
@@ -271,6 +426,8 @@ type ListTurnsWithDateFromRow struct {
 	PersonID  int64        `json:"person_id"`
 	Date      time.Time    `json:"date"`
 	CreatedAt sql.NullTime `json:"created_at"`
+	Version   int32        `json:"version"`
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
 func (q *Queries) ListTurnsWithDateFrom(ctx context.Context, arg ListTurnsWithDateFromParams) ([]ListTurnsWithDateFromRow, error) {
@@ -292,6 +449,8 @@ func (q *Queries) ListTurnsWithDateFrom(ctx context.Context, arg ListTurnsWithDa
 			&i.PersonID,
 			&i.Date,
 			&i.CreatedAt,
+			&i.Version,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -307,7 +466,7 @@ func (q *Queries) ListTurnsWithDateFrom(ctx context.Context, arg ListTurnsWithDa
 }
 
 const listTurnsWithDateTo = `-- name: ListTurnsWithDateTo :many
-SELECT t.id, t.person_id, t.date, t.created_at
+SELECT t.id, t.person_id, t.date, t.created_at, t.version, t.updated_at
 FROM turns t
          LEFT JOIN people p ON t.person_id = p.id
 WHERE p.team_id = ?
@@ -328,6 +487,8 @@ type ListTurnsWithDateToRow struct {
 	PersonID  int64        `json:"person_id"`
 	Date      time.Time    `json:"date"`
 	CreatedAt sql.NullTime `json:"created_at"`
+	Version   int32        `json:"version"`
+	UpdatedAt time.Time    `json:"updated_at"`
 }
 
 func (q *Queries) ListTurnsWithDateTo(ctx context.Context, arg ListTurnsWithDateToParams) ([]ListTurnsWithDateToRow, error) {
@@ -349,6 +510,8 @@ func (q *Queries) ListTurnsWithDateTo(ctx context.Context, arg ListTurnsWithDate
 			&i.PersonID,
 			&i.Date,
 			&i.CreatedAt,
+			&i.Version,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -363,19 +526,40 @@ func (q *Queries) ListTurnsWithDateTo(ctx context.Context, arg ListTurnsWithDate
 	return items, nil
 }
 
+const releaseEmailReminder = `-- name: ReleaseEmailReminder :exec
+UPDATE turns
+SET email_reminded_at = NULL
+WHERE id = ?
+`
+
+func (q *Queries) ReleaseEmailReminder(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, releaseEmailReminder, id)
+	return err
+}
+
 const updateTurn = `-- name: UpdateTurn :execresult
 UPDATE turns
-SET person_id = ?,
-    date      = ?
+SET person_id         = ?,
+    date              = ?,
+    email_reminded_at = NULL,
+    updated_at        = now(6),
+    version           = version + 1
 WHERE id = ?
+  AND version = ?
 `
 
 type UpdateTurnParams struct {
 	PersonID int64     `json:"person_id"`
 	Date     time.Time `json:"date"`
 	ID       int64     `json:"id"`
+	Version  int32     `json:"version"`
 }
 
 func (q *Queries) UpdateTurn(ctx context.Context, arg UpdateTurnParams) (sql.Result, error) {
-	return q.db.ExecContext(ctx, updateTurn, arg.PersonID, arg.Date, arg.ID)
+	return q.db.ExecContext(ctx, updateTurn,
+		arg.PersonID,
+		arg.Date,
+		arg.ID,
+		arg.Version,
+	)
 }
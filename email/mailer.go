@@ -0,0 +1,193 @@
+// Package email sends reminders to the people hosting the next day.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ezerw/wheel/util"
+)
+
+// defaultTimeout limits the exchange with the SMTP server unless
+// SMTP_TIMEOUT is set.
+const defaultTimeout = 30 * time.Second
+
+// Mailer sends emails through an SMTP server.
+type Mailer struct {
+	addr    string
+	host    string
+	auth    smtp.Auth
+	from    mail.Address
+	timeout time.Duration
+}
+
+// NewMailer creates a Mailer for the SMTP_* settings. Credentials are
+// optional, they're only sent over TLS or to localhost.
+func NewMailer(config util.Config) (*Mailer, error) {
+	from, err := mail.ParseAddress(config.SMTPFrom)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_FROM: %w", err)
+	}
+
+	port := config.SMTPPort
+	if port == "" {
+		port = "25"
+	}
+
+	m := &Mailer{
+		addr:    net.JoinHostPort(config.SMTPHost, port),
+		host:    config.SMTPHost,
+		from:    *from,
+		timeout: defaultTimeout,
+	}
+	if config.SMTPTimeout > 0 {
+		m.timeout = config.SMTPTimeout
+	}
+	if config.SMTPUsername != "" {
+		m.auth = smtp.PlainAuth("", config.SMTPUsername, config.SMTPPassword, config.SMTPHost)
+	}
+	return m, nil
+}
+
+// Send emails to with a plain text body and its HTML alternative. The whole
+// exchange with the server must fit in the timeout, and is cut short when
+// ctx is done.
+func (m *Mailer) Send(ctx context.Context, to mail.Address, subject string, text string, html string) error {
+	msg, err := m.compose(to, subject, text, html)
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: m.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(m.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err = conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	// Closing the connection interrupts whatever waits on it.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	return m.send(conn, to.Address, msg)
+}
+
+// send delivers msg to recipient over conn, the same as smtp.SendMail does
+// over the connection it dials.
+func (m *Mailer) send(conn net.Conn, recipient string, msg []byte) error {
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp server doesn't support AUTH")
+		}
+		if err = c.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+
+	if err = c.Mail(m.from.Address); err != nil {
+		return err
+	}
+	if err = c.Rcpt(recipient); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// compose builds a multipart/alternative message, text first so clients
+// prefer the HTML part.
+func (m *Mailer) compose(to mail.Address, subject string, text string, html string) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err = qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err = qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", to.String())
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), m.host)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n", parts.Boundary())
+	fmt.Fprintf(&msg, "\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,181 @@
+package email
+
+import (
+	"context"
+	"embed"
+	htmltemplate "html/template"
+	"net/mail"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/util"
+)
+
+// defaultReminderTime is when the reminders are emailed unless
+// EMAIL_REMINDER_TIME is set, defaultCheckInterval how often it's checked.
+const (
+	defaultReminderTime  = "15:00"
+	defaultCheckInterval = time.Minute
+)
+
+//go:embed templates
+var templates embed.FS
+
+// ReminderData is what the reminder templates are rendered with.
+type ReminderData struct {
+	Person   db.ListEmailRemindersRow
+	TeamName string
+	Date     time.Time
+}
+
+// Sender sends an email with a plain text body and its HTML alternative.
+type Sender interface {
+	Send(ctx context.Context, to mail.Address, subject string, text string, html string) error
+}
+
+// Notifier emails the people hosting tomorrow once a day, unless they have
+// turned email reminders off.
+type Notifier struct {
+	store         db.Store
+	sender        Sender
+	logger        *logrus.Logger
+	location      *time.Location
+	reminderTime  string
+	checkInterval time.Duration
+	text          *texttemplate.Template
+	html          *htmltemplate.Template
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewNotifier creates a Notifier sending reminders with sender at the
+// EMAIL_REMINDER_TIME of the APP_TIMEZONE.
+func NewNotifier(store db.Store, sender Sender, config util.Config, logger *logrus.Logger) (*Notifier, error) {
+	location, err := time.LoadLocation(config.AppTimezone)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := texttemplate.ParseFS(templates, "templates/reminder.txt")
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.ParseFS(templates, "templates/reminder.html")
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Notifier{
+		store:         store,
+		sender:        sender,
+		logger:        logger,
+		location:      location,
+		reminderTime:  defaultReminderTime,
+		checkInterval: defaultCheckInterval,
+		text:          text,
+		html:          html,
+	}
+	if config.EmailReminderTime != "" {
+		n.reminderTime = config.EmailReminderTime
+	}
+
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+	return n, nil
+}
+
+// StartReminders sends the reminders in the background until the notifier
+// is closed, checking every minute whether they're due.
+func (n *Notifier) StartReminders() {
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+
+		ticker := time.NewTicker(n.checkInterval)
+		defer ticker.Stop()
+		for {
+			n.SendReminders(n.ctx, time.Now())
+
+			select {
+			case <-n.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SendReminders emails the people with a turn the day after now, once the
+// reminder time has passed. Every reminder is claimed in the DB before it's
+// sent so only one instance of the API sends it, and released if sending
+// fails so it's retried.
+func (n *Notifier) SendReminders(ctx context.Context, now time.Time) {
+	now = now.In(n.location)
+	if now.Format("15:04") < n.reminderTime {
+		return
+	}
+
+	year, month, day := now.Date()
+	tomorrow := time.Date(year, month, day+1, 0, 0, 0, 0, n.location)
+
+	reminders, err := n.store.ListEmailReminders(ctx, tomorrow)
+	if err != nil {
+		util.LoggerOr(ctx, n.logger).WithError(err).Error("cannot list email reminders")
+		return
+	}
+
+	for _, reminder := range reminders {
+		result, err := n.store.ClaimEmailReminder(ctx, reminder.ID)
+		if err != nil {
+			util.LoggerOr(ctx, n.logger).WithError(err).WithField("turn_id", reminder.ID).Error("cannot claim email reminder")
+			continue
+		}
+		if claimed, err := result.RowsAffected(); err != nil || claimed == 0 {
+			continue
+		}
+
+		if err = n.remind(ctx, reminder, tomorrow); err != nil {
+			util.LoggerOr(ctx, n.logger).WithError(err).WithField("turn_id", reminder.ID).Error("cannot send email reminder")
+			// The claim must be released even when the notifier is closing.
+			if err = n.store.ReleaseEmailReminder(context.Background(), reminder.ID); err != nil {
+				util.LoggerOr(ctx, n.logger).WithError(err).WithField("turn_id", reminder.ID).Error("cannot release email reminder")
+			}
+		}
+	}
+}
+
+// Close stops sending reminders and waits for the ones being sent.
+func (n *Notifier) Close() {
+	n.cancel()
+	n.wg.Wait()
+}
+
+// remind emails the person of the turn on date.
+func (n *Notifier) remind(ctx context.Context, reminder db.ListEmailRemindersRow, date time.Time) error {
+	data := ReminderData{
+		Person:   reminder,
+		TeamName: reminder.TeamName,
+		Date:     date,
+	}
+
+	var text, html strings.Builder
+	if err := n.text.Execute(&text, data); err != nil {
+		return err
+	}
+	if err := n.html.Execute(&html, data); err != nil {
+		return err
+	}
+
+	to := mail.Address{
+		Name:    reminder.FirstName + " " + reminder.LastName,
+		Address: reminder.Email,
+	}
+	subject := "You're hosting " + reminder.TeamName + " tomorrow"
+	return n.sender.Send(ctx, to, subject, text.String(), html.String())
+}
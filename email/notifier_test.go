@@ -0,0 +1,270 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/util"
+)
+
+func TestNotifier(t *testing.T) {
+	server := newSMTPServer(t)
+	defer server.Close()
+
+	host, port, _ := net.SplitHostPort(server.Addr())
+	config := util.Config{
+		AppTimezone:       "UTC",
+		SMTPHost:          host,
+		SMTPPort:          port,
+		SMTPFrom:          "Wheel <wheel@example.com>",
+		EmailReminderTime: "15:00",
+	}
+	mailer, err := NewMailer(config)
+	if err != nil {
+		t.Fatalf("NewMailer: %v", err)
+	}
+
+	today := time.Date(2021, time.July, 12, 0, 0, 0, 0, time.UTC)
+	store := &notifierStore{
+		reminders: []db.ListEmailRemindersRow{{
+			ID:        1,
+			Date:      today.AddDate(0, 0, 1),
+			PersonID:  3,
+			FirstName: "Bruce",
+			LastName:  "Wayne",
+			Email:     "bruce@example.com",
+			TeamID:    7,
+			TeamName:  "Trading",
+		}},
+		claimed: map[int64]bool{},
+	}
+	notifier, err := NewNotifier(store, mailer, config, util.NewLogger())
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+	defer notifier.Close()
+
+	// Too early, then due twice: only one reminder must be sent.
+	notifier.SendReminders(context.Background(), today.Add(14*time.Hour))
+	notifier.SendReminders(context.Background(), today.Add(15*time.Hour))
+	notifier.SendReminders(context.Background(), today.Add(16*time.Hour))
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(messages))
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(messages[0]))
+	if err != nil {
+		t.Fatalf("cannot read email: %v", err)
+	}
+	if to := msg.Header.Get("To"); to != `"Bruce Wayne" <bruce@example.com>` {
+		t.Errorf("To = %q, want the host", to)
+	}
+	if subject := msg.Header.Get("Subject"); subject != "You're hosting Trading tomorrow" {
+		t.Errorf("Subject = %q, want the team name", subject)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []string{"text/plain", "text/html"} {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("cannot read %s part: %v", want, err)
+		}
+		if contentType := part.Header.Get("Content-Type"); !strings.HasPrefix(contentType, want) {
+			t.Errorf("part Content-Type = %q, want %s", contentType, want)
+		}
+		body, _ := io.ReadAll(part)
+		if !strings.Contains(string(body), "Tuesday 13 July") {
+			t.Errorf("%s body = %q, want the date of the turn", want, body)
+		}
+	}
+}
+
+func TestMailerGivesUpOnHungServers(t *testing.T) {
+	// The server accepts connections but never greets.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	mailer, err := NewMailer(util.Config{SMTPHost: host, SMTPPort: port, SMTPFrom: "wheel@example.com", SMTPTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewMailer: %v", err)
+	}
+	to := mail.Address{Address: "bruce@example.com"}
+
+	start := time.Now()
+	if err = mailer.Send(context.Background(), to, "Hi", "hi", "<p>hi</p>"); err == nil {
+		t.Error("Send to a hung server succeeded, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send took %v, want it to give up after the timeout", elapsed)
+	}
+
+	mailer.timeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start = time.Now()
+	if err = mailer.Send(ctx, to, "Hi", "hi", "<p>hi</p>"); err == nil {
+		t.Error("Send cancelled midway succeeded, want an error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send took %v, want it to stop once cancelled", elapsed)
+	}
+}
+
+// notifierStore is an in-memory db.Store implementing the queries used by the Notifier.
+type notifierStore struct {
+	db.Store
+
+	reminders []db.ListEmailRemindersRow
+	claimed   map[int64]bool
+}
+
+func (s *notifierStore) ListEmailReminders(_ context.Context, date time.Time) ([]db.ListEmailRemindersRow, error) {
+	var reminders []db.ListEmailRemindersRow
+	for _, reminder := range s.reminders {
+		if reminder.Date.Equal(date) && !s.claimed[reminder.ID] {
+			reminders = append(reminders, reminder)
+		}
+	}
+	return reminders, nil
+}
+
+func (s *notifierStore) ClaimEmailReminder(_ context.Context, id int64) (sql.Result, error) {
+	if s.claimed[id] {
+		return claimResult(0), nil
+	}
+	s.claimed[id] = true
+	return claimResult(1), nil
+}
+
+func (s *notifierStore) ReleaseEmailReminder(_ context.Context, id int64) error {
+	delete(s.claimed, id)
+	return nil
+}
+
+// claimResult is the sql.Result of ClaimEmailReminder.
+type claimResult int64
+
+func (r claimResult) LastInsertId() (int64, error) { return 0, nil }
+func (r claimResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+// smtpServer is a local SMTP stand-in accepting every email without
+// authentication and recording the messages it receives.
+type smtpServer struct {
+	t        *testing.T
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	s := &smtpServer{t: t, listener: listener}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *smtpServer) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.messages
+}
+
+func (s *smtpServer) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		if _, err := io.WriteString(conn, line+"\r\n"); err != nil {
+			s.t.Errorf("cannot reply: %v", err)
+		}
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.Fields(line + " ")[0])
+		switch command {
+		case "EHLO", "HELO", "MAIL", "RCPT", "RSET", "NOOP":
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
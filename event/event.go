@@ -0,0 +1,115 @@
+// Package event describes what happens to teams so other parts of the
+// system can react to it.
+package event
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Type identifies what happened.
+type Type string
+
+// Event types emitted by the services.
+const (
+	TeamCreated    Type = "team.created"
+	TeamUpdated    Type = "team.updated"
+	TeamDeleted    Type = "team.deleted"
+	PersonAdded    Type = "person.added"
+	PersonUpdated  Type = "person.updated"
+	PersonRemoved  Type = "person.removed"
+	TurnAssigned   Type = "turn.assigned"
+	TurnReassigned Type = "turn.reassigned"
+)
+
+// Types lists every event type.
+var Types = []Type{
+	TeamCreated,
+	TeamUpdated,
+	TeamDeleted,
+	PersonAdded,
+	PersonUpdated,
+	PersonRemoved,
+	TurnAssigned,
+	TurnReassigned,
+}
+
+// Event is something that happened to a team.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       Type            `json:"type"`
+	TeamID     int64           `json:"team_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// New creates an event of the given type for a team, data is stored as JSON.
+func New(eventType Type, teamID int64, data interface{}) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:         hex.EncodeToString(id),
+		Type:       eventType,
+		TeamID:     teamID,
+		OccurredAt: time.Now().UTC(),
+		Data:       payload,
+	}, nil
+}
+
+// IsValid reports whether t is a known event type.
+func (t Type) IsValid() bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Publisher is anything events can be published to.
+type Publisher interface {
+	Publish(ctx context.Context, e Event)
+}
+
+// Handler reacts to a published event. It is called synchronously by the
+// publisher so it must not block.
+type Handler func(ctx context.Context, e Event)
+
+// Bus is an in-process Publisher fanning events out to its subscribers.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewBus creates a Bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers h to be called for every published event.
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish calls every subscriber with e.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, h := range b.handlers {
+		h(ctx, e)
+	}
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/ical"
+	"github.com/ezerw/wheel/middleware"
+	"github.com/ezerw/wheel/service"
+)
+
+// Calendar feeds include the turns from calendarHistoryDays ago and
+// suggest clients refresh them every calendarRefreshInterval.
+const (
+	calendarHistoryDays     = 90
+	calendarRefreshInterval = time.Hour
+)
+
+// HandleShowTeamCalendar handles GET requests to /api/teams/:team-id/calendar
+// it responds with the URL calendar clients can subscribe to for the turns of the team.
+func (s *Server) HandleShowTeamCalendar(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	token, err := s.calendarService.FeedToken(teamID, 0)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	path := fmt.Sprintf("/api/teams/%d/turns.ics?token=%s", teamID, token)
+	c.JSON(http.StatusOK, gin.H{"data": service.CalendarFeedAPI{URL: absoluteURL(c, path)}})
+}
+
+// HandleShowPersonCalendar handles GET requests to /api/teams/:team-id/people/:person-id/calendar
+// it responds with the URL calendar clients can subscribe to for the turns of the person.
+func (s *Server) HandleShowPersonCalendar(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	personID, err := paramID(c, "person-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	_, err = s.peopleService.GetPerson(c.Request.Context(), db.GetPersonParams{ID: personID, TeamID: teamID})
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	token, err := s.calendarService.FeedToken(teamID, personID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	path := fmt.Sprintf("/api/teams/%d/people/%d/turns.ics?token=%s", teamID, personID, token)
+	c.JSON(http.StatusOK, gin.H{"data": service.CalendarFeedAPI{URL: absoluteURL(c, path)}})
+}
+
+// HandleTeamFeed handles GET requests to /api/teams/:team-id/turns.ics
+// authenticated by the token query param instead of the Authorization header.
+func (s *Server) HandleTeamFeed(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	s.serveFeed(c, teamID, 0)
+}
+
+// HandlePersonFeed handles GET requests to /api/teams/:team-id/people/:person-id/turns.ics
+// authenticated by the token query param instead of the Authorization header.
+func (s *Server) HandlePersonFeed(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	personID, err := paramID(c, "person-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	s.serveFeed(c, teamID, personID)
+}
+
+// serveFeed responds with the iCalendar feed of the turns of the team, only
+// the ones of the person when personID isn't 0.
+func (s *Server) serveFeed(c *gin.Context, teamID int64, personID int64) {
+	ctx := c.Request.Context()
+
+	err := s.calendarService.CheckFeedToken(teamID, personID, c.Query("token"))
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	team, err := s.teamsService.GetTeam(ctx, teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+	name := team.Name
+
+	if personID != 0 {
+		person, err := s.peopleService.GetPerson(ctx, db.GetPersonParams{ID: personID, TeamID: teamID})
+		if err != nil {
+			middleware.Abort(c, err)
+			return
+		}
+		name = fmt.Sprintf("%s: %s %s", team.Name, person.FirstName, person.LastName)
+	}
+
+	location, err := time.LoadLocation(s.config.AppTimezone)
+	if err != nil {
+		middleware.Abort(c, errors.Wrap(err, "failed to load location"))
+		return
+	}
+	year, month, day := time.Now().In(location).Date()
+	since := time.Date(year, month, day-calendarHistoryDays, 0, 0, 0, 0, location)
+
+	turns, err := s.calendarService.ListTurns(ctx, teamID, since)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	calendar := ical.Calendar{
+		Name:            name,
+		Timezone:        location.String(),
+		RefreshInterval: calendarRefreshInterval,
+	}
+	for _, turn := range turns {
+		if personID != 0 && turn.PersonID != personID {
+			continue
+		}
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:     fmt.Sprintf("turn-%d@wheel", turn.ID),
+			Date:    turn.Date.In(location),
+			Summary: fmt.Sprintf("%s %s hosts %s", turn.FirstName, turn.LastName, team.Name),
+			Stamp:   turn.UpdatedAt,
+		})
+	}
+
+	c.Header("Content-Type", ical.ContentType)
+	c.Header("Content-Disposition", `inline; filename="turns.ics"`)
+	c.Status(http.StatusOK)
+	if err = calendar.Encode(c.Writer); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// absoluteURL returns the URL of path on the host the request was sent to.
+func absoluteURL(c *gin.Context, path string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + path
+}
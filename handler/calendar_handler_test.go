@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/util"
+)
+
+func TestCalendarFeeds(t *testing.T) {
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1)
+	store := &calendarStore{
+		team:   db.GetTeamRow{ID: 1, Name: "Trading"},
+		person: db.GetPersonRow{ID: 3, FirstName: "Bruce", LastName: "Wayne", TeamID: 1},
+		turns: []db.ListCalendarTurnsRow{
+			{ID: 10, Date: tomorrow, PersonID: 3, FirstName: "Bruce", LastName: "Wayne"},
+			{ID: 11, Date: tomorrow.AddDate(0, 0, 1), PersonID: 4, FirstName: "Diana", LastName: "Prince"},
+		},
+	}
+	server, err := NewServer(util.Config{AppTimezone: "UTC", CalendarSecret: "secret"}, store, util.NewLogger())
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+	feedURL := func(url string) string {
+		w := get(url)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d, want 200", url, w.Code)
+		}
+		var body struct {
+			Data struct {
+				URL string `json:"url"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("cannot decode %s: %v", url, err)
+		}
+		return strings.TrimPrefix(body.Data.URL, "http://example.com")
+	}
+
+	teamFeed := feedURL("/api/teams/1/calendar")
+	personFeed := feedURL("/api/teams/1/people/3/calendar")
+
+	w := get(teamFeed)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/calendar") {
+		t.Fatalf("GET %s = %d %s, want a calendar", teamFeed, w.Code, w.Header().Get("Content-Type"))
+	}
+	if n := strings.Count(w.Body.String(), "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("team feed has %d events, want 2", n)
+	}
+	if !strings.Contains(w.Body.String(), "UID:turn-10@wheel\r\n") {
+		t.Errorf("team feed doesn't use the turn IDs as UIDs:\n%s", w.Body)
+	}
+
+	w = get(personFeed)
+	if n := strings.Count(w.Body.String(), "BEGIN:VEVENT"); n != 1 {
+		t.Errorf("person feed has %d events, want 1", n)
+	}
+	if !strings.Contains(w.Body.String(), "SUMMARY:Bruce Wayne hosts Trading\r\n") {
+		t.Errorf("person feed doesn't have the turn of the person:\n%s", w.Body)
+	}
+
+	// A token only opens its own feed.
+	token := teamFeed[strings.Index(teamFeed, "?"):]
+	for _, url := range []string{
+		"/api/teams/1/turns.ics",
+		"/api/teams/1/turns.ics?token=nope",
+		"/api/teams/1/people/3/turns.ics" + token,
+	} {
+		if w := get(url); w.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s = %d, want 401", url, w.Code)
+		}
+	}
+}
+
+// calendarStore is an in-memory db.Store implementing the queries used by the calendar feeds.
+type calendarStore struct {
+	db.Store
+
+	team   db.GetTeamRow
+	person db.GetPersonRow
+	turns  []db.ListCalendarTurnsRow
+}
+
+func (s *calendarStore) GetTeam(_ context.Context, id int64) (db.GetTeamRow, error) {
+	if id != s.team.ID {
+		return db.GetTeamRow{}, sql.ErrNoRows
+	}
+	return s.team, nil
+}
+
+func (s *calendarStore) GetPerson(_ context.Context, arg db.GetPersonParams) (db.GetPersonRow, error) {
+	if arg.ID != s.person.ID || arg.TeamID != s.person.TeamID {
+		return db.GetPersonRow{}, sql.ErrNoRows
+	}
+	return s.person, nil
+}
+
+func (s *calendarStore) ListCalendarTurns(_ context.Context, arg db.ListCalendarTurnsParams) ([]db.ListCalendarTurnsRow, error) {
+	var turns []db.ListCalendarTurnsRow
+	for _, turn := range s.turns {
+		if !turn.Date.Before(arg.Date) {
+			turns = append(turns, turn)
+		}
+	}
+	return turns, nil
+}
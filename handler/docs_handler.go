@@ -0,0 +1,50 @@
+package handler
+
+import (
+	_ "embed" // required for go:embed
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// openAPISpec is the OpenAPI 3 document describing every route of the API.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// swaggerUI is a page rendering openAPISpec with Swagger UI, whose files
+// are embedded in the binary by the pinned swaggo/files module rather than
+// loaded from a CDN.
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Wheel API</title>
+  <link rel="stylesheet" href="/api/docs/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/api/docs/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/api/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// HandleOpenAPI handles GET request to /api/openapi.json
+func (s *Server) HandleOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPISpec)
+}
+
+// HandleDocs handles GET request to /api/docs
+func (s *Server) HandleDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUI))
+}
+
+// HandleDocsAsset handles GET request to /api/docs/:asset
+// serving the files of Swagger UI.
+func (s *Server) HandleDocsAsset(c *gin.Context) {
+	c.FileFromFS(c.Param("asset"), http.FS(swaggerFiles.FS))
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/service"
+)
+
+// stamp is what the ETag of a row is derived from: the row's updated_at,
+// which every write of teams, people and turns sets to the microsecond,
+// along with its id and version.
+type stamp struct {
+	ID        int64
+	Version   int32
+	UpdatedAt time.Time
+}
+
+// etag returns the strong entity tag of a representation made of rows. It
+// covers the number of rows, so it changes when one is added or removed
+// too, not only when one is updated.
+func etag(rows []stamp) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d", len(rows))
+	for _, row := range rows {
+		fmt.Fprintf(hash, ";%d:%d:%d", row.ID, row.Version, row.UpdatedAt.UnixNano())
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// teamStamps returns the stamps of a list of teams.
+func teamStamps(teams []db.ListTeamsRow) []stamp {
+	rows := make([]stamp, 0, len(teams))
+	for _, team := range teams {
+		rows = append(rows, stamp{ID: team.ID, Version: team.Version, UpdatedAt: team.UpdatedAt})
+	}
+	return rows
+}
+
+// peopleStamps returns the stamps of a list of people.
+func peopleStamps(people []db.ListPeopleRow) []stamp {
+	rows := make([]stamp, 0, len(people))
+	for _, person := range people {
+		rows = append(rows, stamp{ID: person.ID, Version: person.Version, UpdatedAt: person.UpdatedAt})
+	}
+	return rows
+}
+
+// personStamps returns the stamp of a person.
+func personStamps(person *db.GetPersonRow) []stamp {
+	return []stamp{{ID: person.ID, Version: person.Version, UpdatedAt: person.UpdatedAt}}
+}
+
+// turnStamps returns the stamps of a list of turns.
+func turnStamps(turns []service.TurnAPI) []stamp {
+	rows := make([]stamp, 0, len(turns))
+	for _, turn := range turns {
+		rows = append(rows, stamp{ID: turn.ID, Version: turn.Version, UpdatedAt: turn.UpdatedAt})
+	}
+	return rows
+}
+
+// stamps returns the stamps of the team followed by those of its people.
+func (t *teamWithPeople) stamps() []stamp {
+	team := stamp{ID: t.TeamID, Version: t.TeamVersion, UpdatedAt: t.TeamUpdatedAt}
+	return append([]stamp{team}, peopleStamps(t.People)...)
+}
+
+// respondWithETag responds with data and the ETag of its rows, or with a
+// 304 when the If-None-Match header of the request has the tag already.
+func respondWithETag(c *gin.Context, data interface{}, rows []stamp) {
+	tag := etag(rows)
+	c.Header("ETag", tag)
+	if etagMatches(c.GetHeader("If-None-Match"), tag, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// setETag sets the ETag of rows, the new state a write responds with, so
+// the client can send it along with its next write.
+func setETag(c *gin.Context, rows []stamp) {
+	c.Header("ETag", etag(rows))
+}
+
+// checkIfMatch checks the If-Match header of the request against the tag of
+// rows, the state the client is about to replace or delete, so it doesn't
+// overwrite changes it hasn't seen. The header is required when required
+// is set.
+func checkIfMatch(c *gin.Context, rows []stamp, required bool) error {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		if !required {
+			return nil
+		}
+		return service.PreconditionRequired(
+			service.CodePreconditionRequired,
+			"If-Match must be set to the ETag of the resource.",
+		)
+	}
+
+	if !etagMatches(header, etag(rows), false) {
+		return service.PreconditionFailed(
+			service.CodePreconditionFailed,
+			"The resource has changed since it was read, fetch it again.",
+		)
+	}
+	return nil
+}
+
+// etagMatches reports whether the If-Match or If-None-Match header has tag
+// or is *. The weak comparison of If-None-Match ignores the W/ prefix,
+// which never matches in the strong comparison of If-Match.
+func etagMatches(header string, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/util"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	store := &calendarStore{
+		team:   db.GetTeamRow{ID: 1, Name: "Trading"},
+		person: db.GetPersonRow{ID: 2, FirstName: "Bruce", LastName: "Wayne", TeamID: 1},
+	}
+	server, err := NewServer(util.Config{AppTimezone: "UTC"}, store, util.NewLogger())
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+
+	send := func(method string, header string, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/teams/1/people/2", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(http.MethodGet, "", "")
+	tag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || tag == "" {
+		t.Fatalf("got status %d and ETag %q, want 200 and a tag", rec.Code, tag)
+	}
+
+	rec = send(http.MethodGet, "If-None-Match", `"stale", `+tag)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("got status %d and %d bytes for a fresh tag, want an empty 304", rec.Code, rec.Body.Len())
+	}
+
+	store.person.Email = "bruce@wayne.com"
+	store.person.UpdatedAt = store.person.UpdatedAt.Add(time.Microsecond)
+	if rec = send(http.MethodGet, "If-None-Match", tag); rec.Code != http.StatusOK {
+		t.Errorf("got status %d once changed, want 200", rec.Code)
+	}
+
+	if rec = send(http.MethodDelete, "", ""); rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("got status %d deleting without If-Match, want %d", rec.Code, http.StatusPreconditionRequired)
+	}
+	if rec = send(http.MethodDelete, "If-Match", tag); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("got status %d deleting with a stale tag, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+}
+
+func TestETagCoversRowCount(t *testing.T) {
+	updatedAt := time.Date(2021, 8, 9, 9, 0, 0, 0, time.UTC)
+	one := []stamp{{ID: 1, Version: 1, UpdatedAt: updatedAt}}
+	two := append(one, stamp{ID: 2, Version: 1, UpdatedAt: updatedAt})
+
+	if etag(one) == etag(two) {
+		t.Error("adding a row kept the ETag, want a new one")
+	}
+	if etag(nil) != etag([]stamp{}) {
+		t.Error("empty lists got different ETags, want the same")
+	}
+	touched := []stamp{{ID: 1, Version: 1, UpdatedAt: updatedAt.Add(time.Microsecond)}}
+	if etag(one) == etag(touched) {
+		t.Error("updating a row kept the ETag, want a new one")
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ezerw/wheel/event"
+	"github.com/ezerw/wheel/middleware"
+)
+
+// streamHeartbeat is how often a comment is sent on idle streams so
+// proxies don't close them.
+const streamHeartbeat = 15 * time.Second
+
+// streamRetry is how long clients wait before reconnecting, in milliseconds.
+const streamRetry = 3000
+
+// HandleStreamEvents handles GET requests to /api/teams/:team-id/events
+// it streams the events of the team as Server-Sent Events. Clients
+// reconnecting with the Last-Event-ID header, or the last_event_id query
+// param, receive the events they missed; a reset event tells them to reload
+// the team when those are no longer available.
+func (s *Server) HandleStreamEvents(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	sub, missed, ok := s.hub.Subscribe(teamID, lastEventID)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetry)
+	if !ok {
+		fmt.Fprint(c.Writer, "event: reset\ndata: {}\n\n")
+	}
+	for _, e := range missed {
+		if err = writeEvent(c, e); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, open := <-sub.Events():
+			// Closed when the client was too slow, it resumes by reconnecting.
+			if !open {
+				return
+			}
+			if err = writeEvent(c, e); err != nil {
+				return
+			}
+			if e.Type == event.TeamDeleted {
+				c.Writer.Flush()
+				return
+			}
+		case <-heartbeat.C:
+			if _, err = fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeEvent writes e as a Server-Sent Event named after its type.
+func writeEvent(c *gin.Context, e event.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
+	"github.com/ezerw/wheel/util"
+)
+
+func TestStreamEvents(t *testing.T) {
+	store := &calendarStore{team: db.GetTeamRow{ID: 1, Name: "Trading"}}
+	server, err := NewServer(util.Config{AppTimezone: "UTC"}, store, util.NewLogger())
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	publish := func(eventType event.Type) event.Event {
+		e, err := event.New(eventType, 1, map[string]int{"n": 1})
+		if err != nil {
+			t.Fatalf("cannot create event: %v", err)
+		}
+		server.Publish(context.Background(), e)
+		return e
+	}
+	missed := publish(event.PersonAdded)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/teams/1/events", nil)
+	req.Header.Set("Last-Event-ID", "unknown")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("cannot connect: %v", err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	// Reads the stream until the next event, skipping retry and comments.
+	lines := bufio.NewScanner(res.Body)
+	next := func() (name string, id string, data string) {
+		for lines.Scan() {
+			line := lines.Text()
+			switch {
+			case line == "" && name != "":
+				return name, id, data
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+		t.Fatalf("stream ended: %v", lines.Err())
+		return
+	}
+
+	// The unknown Last-Event-ID can't be resumed from.
+	if name, _, _ := next(); name != "reset" {
+		t.Fatalf("first event = %q, want reset", name)
+	}
+
+	live := publish(event.TurnAssigned)
+	name, id, data := next()
+	if name != string(event.TurnAssigned) || id != live.ID {
+		t.Errorf("event = %s %s, want %s %s", name, id, event.TurnAssigned, live.ID)
+	}
+	var e event.Event
+	if err = json.Unmarshal([]byte(data), &e); err != nil || e.TeamID != 1 {
+		t.Errorf("data = %s, want the event as JSON", data)
+	}
+	if id == missed.ID {
+		t.Errorf("received an event published before connecting")
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ezerw/wheel/health"
+	"github.com/ezerw/wheel/util"
+)
+
+// defaultReadinessTimeout limits the readiness checks unless
+// READINESS_TIMEOUT is set.
+const defaultReadinessTimeout = 2 * time.Second
+
+// Statuses of the health endpoints.
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+// readinessCheck is a check of a dependency the API needs to serve requests.
+type readinessCheck struct {
+	name  string
+	check health.Check
+}
+
+// RegisterReadinessCheck adds a check to /readyz, which fails while check
+// does.
+func (s *Server) RegisterReadinessCheck(name string, check health.Check) {
+	s.readinessChecks = append(s.readinessChecks, readinessCheck{name: name, check: check})
+}
+
+// HandleHealthz handles GET requests to /healthz, it succeeds as long as the
+// process serves requests.
+func (s *Server) HandleHealthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"status": statusOK}})
+}
+
+// HandleReadyz handles GET requests to /readyz, it fails with 503 while any
+// readiness check does. The reasons are logged, not exposed.
+func (s *Server) HandleReadyz(c *gin.Context) {
+	timeout := s.config.ReadinessTimeout
+	if timeout <= 0 {
+		timeout = defaultReadinessTimeout
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	status := http.StatusOK
+	report := gin.H{"status": statusOK}
+	checks := gin.H{}
+	for _, check := range s.readinessChecks {
+		if err := check.check(ctx); err != nil {
+			util.LoggerFrom(ctx).WithError(err).WithField("check", check.name).Warn("not ready")
+			status = http.StatusServiceUnavailable
+			report["status"] = statusUnavailable
+			checks[check.name] = statusUnavailable
+			continue
+		}
+		checks[check.name] = statusOK
+	}
+	report["checks"] = checks
+
+	c.JSON(status, gin.H{"data": report})
+}
+
+// HandleVersion handles GET requests to /version.
+func (s *Server) HandleVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": util.GetBuildInfo()})
+}
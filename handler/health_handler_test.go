@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ezerw/wheel/util"
+)
+
+func TestHandleReadyz(t *testing.T) {
+	server, err := NewServer(util.Config{}, nil, util.NewLogger())
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+
+	ready := true
+	server.RegisterReadinessCheck("database", func(context.Context) error { return nil })
+	server.RegisterReadinessCheck("migrations", func(context.Context) error {
+		if ready {
+			return nil
+		}
+		return errors.New("database at migration 1, want 2")
+	})
+
+	probe := func() (int, map[string]string) {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		body := struct {
+			Data struct {
+				Status string            `json:"status"`
+				Checks map[string]string `json:"checks"`
+			} `json:"data"`
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+		}
+		return rec.Code, body.Data.Checks
+	}
+
+	if code, checks := probe(); code != http.StatusOK || checks["migrations"] != statusOK {
+		t.Errorf("got %d %v while ready, want 200", code, checks)
+	}
+
+	ready = false
+	code, checks := probe()
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("got %d while not ready, want 503", code)
+	}
+	if checks["database"] != statusOK || checks["migrations"] != statusUnavailable {
+		t.Errorf("got checks %v, want the migrations unavailable", checks)
+	}
+}
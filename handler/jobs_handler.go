@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/ezerw/wheel/middleware"
+	"github.com/ezerw/wheel/service"
+)
+
+// HandleListJobs handles GET request to /api/teams/:team-id/jobs
+// the jobs include the outcome of their last run.
+func (s *Server) HandleListJobs(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	jobs, err := s.jobsService.ListJobs(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": jobs})
+}
+
+// HandleShowJob handles GET request to /api/teams/:team-id/jobs/:kind
+func (s *Server) HandleShowJob(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	job, err := s.jobsService.GetJob(c.Request.Context(), teamID, c.Param("kind"))
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": job})
+}
+
+// HandleUpdateJob handles PUT request to /api/teams/:team-id/jobs/:kind
+// it creates the job or replaces its schedule, the next run is scheduled from now.
+func (s *Server) HandleUpdateJob(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	binding := struct {
+		Schedule string `json:"schedule"`
+		Enabled  *bool  `json:"enabled"`
+	}{}
+	err = bindJSON(c, &binding)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	loc, err := time.LoadLocation(s.config.AppTimezone)
+	if err != nil {
+		middleware.Abort(c, errors.Wrap(err, "failed to load location"))
+		return
+	}
+
+	job, err := s.jobsService.PutJob(c.Request.Context(), service.JobAPI{
+		TeamID:   teamID,
+		Kind:     c.Param("kind"),
+		Schedule: binding.Schedule,
+		Enabled:  optIn(binding.Enabled),
+	}, time.Now().In(loc))
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": job})
+}
+
+// HandleDeleteJob handles DELETE request to /api/teams/:team-id/jobs/:kind
+func (s *Server) HandleDeleteJob(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.jobsService.DeleteJob(c.Request.Context(), teamID, c.Param("kind"))
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ezerw/wheel/service"
+)
+
+// mergePatchContentType is the media type of RFC 7396 JSON merge patches.
+const mergePatchContentType = "application/merge-patch+json"
+
+// bindVersionedPatch applies the JSON merge patch in the request body on top
+// of the JSON representation of current and decodes the result into out.
+// The patch must have the version it's based on, the one of current would
+// apply it on top of changes the client hasn't seen.
+func bindVersionedPatch(c *gin.Context, current interface{}, out interface{}) error {
+	patch, err := readMergePatch(c)
+	if err != nil {
+		return err
+	}
+	if patch["version"] == nil {
+		return service.VersionRequired(nil)
+	}
+	return applyMergePatch(current, patch, out)
+}
+
+// readMergePatch reads the JSON merge patch in the request body.
+func readMergePatch(c *gin.Context) (map[string]interface{}, error) {
+	contentType := c.ContentType()
+	if contentType != mergePatchContentType && contentType != gin.MIMEJSON {
+		return nil, service.Validation(
+			service.CodeInvalidBody,
+			"PATCH requests must use the "+mergePatchContentType+" content type.",
+		)
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var patch map[string]interface{}
+	if err = decodeJSON(body, &patch); err != nil || patch == nil {
+		e := service.Validation(service.CodeInvalidBody, "Request body must be a JSON object.")
+		e.Err = err
+		return nil, e
+	}
+	return patch, nil
+}
+
+// applyMergePatch applies patch on top of the JSON representation of current
+// and decodes the result into out.
+func applyMergePatch(current interface{}, patch map[string]interface{}, out interface{}) error {
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	var document interface{}
+	if err = decodeJSON(currentJSON, &document); err != nil {
+		return err
+	}
+
+	merged, err := json.Marshal(mergePatch(document, patch))
+	if err != nil {
+		return err
+	}
+
+	if err = decodeJSON(merged, out); err != nil {
+		e := service.Validation(service.CodeInvalidBody, "Request body has fields of the wrong type.")
+		e.Err = err
+		return e
+	}
+
+	return nil
+}
+
+// mergePatch implements the MergePatch algorithm of RFC 7396: members of the
+// patch replace those of the target, and null members remove them.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+
+	return targetObject
+}
+
+// decodeJSON unmarshals data keeping numbers exact.
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ezerw/wheel/util"
+)
+
+// ginParam matches gin path parameters such as :team-id.
+var ginParam = regexp.MustCompile(`:([^/]+)`)
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	server, err := NewServer(util.Config{}, nil, util.NewLogger())
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+
+	spec := struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}{}
+	if err = json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+
+	registered := map[string]bool{}
+	for _, route := range server.router.Routes() {
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+
+		if _, ok := spec.Paths[path][method]; !ok {
+			t.Errorf("route %s %s is missing from openapi.json", route.Method, route.Path)
+		}
+	}
+
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			if !registered[method+" "+path] {
+				t.Errorf("openapi.json describes %s %s which is not registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestDocsServeSwaggerUI(t *testing.T) {
+	server, err := NewServer(util.Config{}, nil, util.NewLogger())
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+
+	for _, path := range []string{"/api/docs", "/api/docs/swagger-ui.css", "/api/docs/swagger-ui-bundle.js"} {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK || w.Body.Len() == 0 {
+			t.Errorf("GET %s = %d with %d bytes, want it served", path, w.Code, w.Body.Len())
+		}
+	}
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	if strings.Contains(w.Body.String(), "https://") {
+		t.Error("the docs page loads files from another site")
+	}
+}
@@ -1,70 +1,55 @@
 package handler
 
 import (
-	"database/sql"
-	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/pkg/errors"
 
 	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/middleware"
+	"github.com/ezerw/wheel/service"
 )
 
 // HandleListPeople handles GET request to /api/teams/:team-id/people
 func (s *Server) HandleListPeople(c *gin.Context) {
-	queryTeamID := c.Param("team-id")
-
-	teamID, err := strconv.ParseInt(queryTeamID, 10, 64)
+	teamID, err := paramID(c, "team-id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	exists, err := s.teamExists(c.Request.Context(), teamID)
+	err = s.checkTeam(c.Request.Context(), teamID)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	if !exists {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Team not found."})
+		middleware.Abort(c, err)
 		return
 	}
 
 	people, err := s.peopleService.ListPeople(c.Request.Context(), teamID)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": people})
+	respondWithETag(c, people, peopleStamps(people))
 }
 
 // HandleShowPerson handles GET request to /api/teams/:team-id/people/:person-id
 func (s *Server) HandleShowPerson(c *gin.Context) {
-	queryTeamID := c.Param("team-id")
-	queryPersonID := c.Param("person-id")
-
-	teamID, err := strconv.ParseInt(queryTeamID, 10, 64)
+	teamID, err := paramID(c, "team-id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	personID, err := strconv.ParseInt(queryPersonID, 10, 64)
+	personID, err := paramID(c, "person-id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	exists, err := s.teamExists(c.Request.Context(), teamID)
+	err = s.checkTeam(c.Request.Context(), teamID)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	if !exists {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Team not found."})
+		middleware.Abort(c, err)
 		return
 	}
 
@@ -74,54 +59,50 @@ func (s *Server) HandleShowPerson(c *gin.Context) {
 	}
 	person, err := s.peopleService.GetPerson(c.Request.Context(), args)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": person})
+	respondWithETag(c, person, personStamps(person))
 }
 
 // HandleAddPerson handles POST request to /api/teams/:team-id/people
 func (s *Server) HandleAddPerson(c *gin.Context) {
-	queryTeamID := c.Param("team-id")
-
-	teamID, err := strconv.ParseInt(queryTeamID, 10, 64)
+	teamID, err := paramID(c, "team-id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	exists, err := s.teamExists(c.Request.Context(), teamID)
+	err = s.checkTeam(c.Request.Context(), teamID)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	if !exists {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Team not found."})
+		middleware.Abort(c, err)
 		return
 	}
 
 	binding := struct {
-		FirstName string `json:"first_name" binding:"required"`
-		LastName  string `json:"last_name" binding:"required"`
-		Email     string `json:"email" binding:"required"`
+		FirstName      string `json:"first_name"`
+		LastName       string `json:"last_name"`
+		Email          string `json:"email"`
+		EmailReminders *bool  `json:"email_reminders"`
 	}{}
-	err = c.BindJSON(&binding)
+	err = bindJSON(c, &binding)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
 	args := db.CreatePersonParams{
-		FirstName: binding.FirstName,
-		LastName:  binding.LastName,
-		Email:     binding.Email,
-		TeamID:    teamID,
+		FirstName:      binding.FirstName,
+		LastName:       binding.LastName,
+		Email:          binding.Email,
+		TeamID:         teamID,
+		EmailReminders: optIn(binding.EmailReminders),
 	}
 
 	person, err := s.peopleService.AddPerson(c.Request.Context(), args)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
@@ -129,40 +110,31 @@ func (s *Server) HandleAddPerson(c *gin.Context) {
 }
 
 // HandleUpdatePerson handles PUT request to /api/teams/:team-id/people/:person-id
+// it replaces the person, team_id defaults to the team in the path and
+// email_reminders to the current setting of the person.
 func (s *Server) HandleUpdatePerson(c *gin.Context) {
-	queryTeamID := c.Param("team-id")
-	teamID, err := strconv.ParseInt(queryTeamID, 10, 64)
+	teamID, err := paramID(c, "team-id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	exists, err := s.teamExists(c.Request.Context(), teamID)
+	err = s.checkTeam(c.Request.Context(), teamID)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	if !exists {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Team not found."})
+		middleware.Abort(c, err)
 		return
 	}
 
-	queryPersonID := c.Param("person-id")
-	personID, err := strconv.ParseInt(queryPersonID, 10, 64)
+	personID, err := paramID(c, "person-id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	binding := struct {
-		FirstName string `json:"first_name"`
-		LastName  string `json:"last_name"`
-		Email     string `json:"email"`
-		TeamID    int64  `json:"team_id"`
-	}{}
-	err = c.BindJSON(&binding)
+	binding := personBinding{}
+	err = bindJSON(c, &binding)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
@@ -173,72 +145,148 @@ func (s *Server) HandleUpdatePerson(c *gin.Context) {
 	}
 	person, err := s.peopleService.GetPerson(c.Request.Context(), getPersonArgs)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-				"error": "Person not found in the specified team.",
-			})
-			return
-		}
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
-	fmt.Printf("Person: %+v\n", person)
-	args := db.UpdatePersonParams{
-		ID:        person.ID,
-		FirstName: binding.FirstName,
-		LastName:  binding.LastName,
-		Email:     binding.Email,
-		TeamID:    binding.TeamID,
+
+	err = checkIfMatch(c, personStamps(person), true)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	if binding.TeamID == 0 {
+		binding.TeamID = teamID
+	}
+	if binding.EmailReminders == nil {
+		binding.EmailReminders = &person.EmailReminders
 	}
 
-	if args.FirstName == "" {
-		args.FirstName = person.FirstName
+	s.updatePerson(c, person.ID, binding)
+}
+
+// HandlePatchPerson handles PATCH request to /api/teams/:team-id/people/:person-id
+// the body is a JSON merge patch (RFC 7396) applied on top of the current person.
+func (s *Server) HandlePatchPerson(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
 	}
 
-	if args.LastName == "" {
-		args.LastName = person.LastName
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
 	}
 
-	if args.Email == "" {
-		args.Email = person.Email
+	personID, err := paramID(c, "person-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
 	}
 
-	if args.TeamID == 0 {
-		args.TeamID = teamID
+	getPersonArgs := db.GetPersonParams{
+		ID:     personID,
+		TeamID: teamID,
 	}
-	fmt.Printf("Args: %+v", args)
-	err = s.peopleService.UpdatePerson(c.Request.Context(), args)
+	person, err := s.peopleService.GetPerson(c.Request.Context(), getPersonArgs)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{})
+	// Patches are only conditional when asked to be.
+	err = checkIfMatch(c, personStamps(person), false)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	binding := personBinding{}
+	err = bindVersionedPatch(c, person, &binding)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	s.updatePerson(c, person.ID, binding)
+}
+
+// personBinding is the writable representation of a person, along with
+// the version the changes are based on.
+type personBinding struct {
+	FirstName      string `json:"first_name"`
+	LastName       string `json:"last_name"`
+	Email          string `json:"email"`
+	TeamID         int64  `json:"team_id"`
+	EmailReminders *bool  `json:"email_reminders"`
+	Version        int32  `json:"version"`
+}
+
+// updatePerson stores binding as the new state of the person and responds
+// with the updated person.
+func (s *Server) updatePerson(c *gin.Context, personID int64, binding personBinding) {
+	if binding.TeamID <= 0 {
+		middleware.Abort(c, service.InvalidFields(service.FieldError{
+			Field:   "team_id",
+			Code:    service.FieldRequired,
+			Message: "team_id must be a positive integer.",
+		}))
+		return
+	}
+
+	args := db.UpdatePersonParams{
+		ID:             personID,
+		FirstName:      binding.FirstName,
+		LastName:       binding.LastName,
+		Email:          binding.Email,
+		TeamID:         binding.TeamID,
+		EmailReminders: optIn(binding.EmailReminders),
+		Version:        binding.Version,
+	}
+	person, err := s.peopleService.UpdatePerson(c.Request.Context(), args)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	setETag(c, personStamps(person))
+	c.JSON(http.StatusOK, gin.H{"data": person})
 }
 
 // HandleDeletePerson handles DELETE request to /api/teams/:team-id/people/:person-id
 func (s *Server) HandleDeletePerson(c *gin.Context) {
-	queryTeamID := c.Param("team-id")
-	teamID, err := strconv.ParseInt(queryTeamID, 10, 64)
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	personID, err := paramID(c, "person-id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	queryPersonID := c.Param("person-id")
-	personID, err := strconv.ParseInt(queryPersonID, 10, 64)
+	err = s.checkTeam(c.Request.Context(), teamID)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	exists, err := s.teamExists(c.Request.Context(), teamID)
+	person, err := s.peopleService.GetPerson(c.Request.Context(), db.GetPersonParams{
+		ID:     personID,
+		TeamID: teamID,
+	})
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
-	if !exists {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Team not found."})
+
+	err = checkIfMatch(c, personStamps(person), true)
+	if err != nil {
+		middleware.Abort(c, err)
 		return
 	}
 
@@ -249,9 +297,14 @@ func (s *Server) HandleDeletePerson(c *gin.Context) {
 
 	err = s.peopleService.DeletePerson(c.Request.Context(), args)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{})
 }
+
+// optIn reads an optional boolean which is on unless explicitly turned off.
+func optIn(value *bool) bool {
+	return value == nil || *value
+}
@@ -2,42 +2,162 @@ package handler
 
 import (
 	"context"
-	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
 	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
+	"github.com/ezerw/wheel/live"
+	"github.com/ezerw/wheel/metrics"
 	"github.com/ezerw/wheel/middleware"
 	"github.com/ezerw/wheel/service"
+	"github.com/ezerw/wheel/session"
+	"github.com/ezerw/wheel/slack"
 	"github.com/ezerw/wheel/util"
 )
 
+// defaultSlackTimeout limits replies to Slack interactions unless
+// SLACK_TIMEOUT is set.
+const defaultSlackTimeout = 5 * time.Second
+
+// Defaults of the HTTP server unless set in the config.
+const (
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 30 * time.Second
+	defaultIdleTimeout       = 2 * time.Minute
+	defaultShutdownTimeout   = 15 * time.Second
+)
+
 // Server serves HTTP requests for our wheel api.
 type Server struct {
-	config        util.Config
-	router        *gin.Engine
-	peopleService *service.People
-	teamsService  *service.Teams
-	turnsService  *service.Turns
+	config          util.Config
+	logger          *logrus.Logger
+	router          *gin.Engine
+	peopleService   *service.People
+	teamsService    *service.Teams
+	turnsService    *service.Turns
+	webhooksService *service.Webhooks
+	slackService    *service.Slack
+	calendarService *service.Calendar
+	jobsService     *service.Jobs
+	slackClient     *http.Client
+	slackReplyURLs  string
+	hub             *live.Hub
+	sessions        *session.Hub
+	upgrader        *websocket.Upgrader
+	metrics         *metrics.Metrics
+	rateLimiter     *middleware.RateLimiter
+	readinessChecks []readinessCheck
 }
 
 // NewServer creates a new HTTP server and set up routing.
-func NewServer(config util.Config, store db.Store) (*Server, error) {
+func NewServer(config util.Config, store db.Store, logger *logrus.Logger) (*Server, error) {
 	server := &Server{
-		config:        config,
-		peopleService: service.NewPeople(store),
-		teamsService:  service.NewTeams(store),
-		turnsService:  service.NewTurns(store),
+		config:          config,
+		logger:          logger,
+		peopleService:   service.NewPeople(store),
+		teamsService:    service.NewTeams(store),
+		turnsService:    service.NewTurns(store),
+		webhooksService: service.NewWebhooks(store),
+		slackService:    service.NewSlack(store),
+		calendarService: service.NewCalendar(store, config.CalendarSecret),
+		jobsService:     service.NewJobs(store),
+		slackClient:     &http.Client{Timeout: defaultSlackTimeout},
+		slackReplyURLs:  slack.ResponseURLPrefix,
+		hub:             live.NewHub(0),
+		metrics:         metrics.New(),
+		rateLimiter:     middleware.NewRateLimiter(config),
+		upgrader:        newUpgrader(middleware.CorsOrigins(config)),
+	}
+	if config.SlackTimeout > 0 {
+		server.slackClient.Timeout = config.SlackTimeout
+	}
+
+	// The pool stats are only there for stores backed by a database.
+	if db, ok := store.(metrics.StatsGetter); ok {
+		server.metrics.RegisterDB(db)
 	}
 
+	server.sessions = session.NewHub(sessionWheel{server: server}, config.SpinDuration)
+
 	server.setupRouter()
 	return server, nil
 }
 
-// Start runs the HTTP server on a specific address.
-func (s *Server) Start(address string, port string) error {
-	return s.router.Run(address + ":" + port)
+// Start serves HTTP requests on a specific address, over TLS when
+// TLS_CERT_FILE and TLS_KEY_FILE are set, until ctx is done. It then stops
+// accepting connections, ends the live event streams and spin sessions, and
+// waits up to SHUTDOWN_TIMEOUT for the requests in flight.
+func (s *Server) Start(ctx context.Context, address string, port string) error {
+	if (s.config.TLSCertFile == "") != (s.config.TLSKeyFile == "") {
+		return errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	server := &http.Server{
+		Addr:              address + ":" + port,
+		Handler:           s.router,
+		ReadHeaderTimeout: durationOr(s.config.HTTPReadHeaderTimeout, defaultReadHeaderTimeout),
+		ReadTimeout:       durationOr(s.config.HTTPReadTimeout, defaultReadTimeout),
+		// No default, it would cut the live event streams.
+		WriteTimeout:   s.config.HTTPWriteTimeout,
+		IdleTimeout:    durationOr(s.config.HTTPIdleTimeout, defaultIdleTimeout),
+		MaxHeaderBytes: s.config.HTTPMaxHeaderBytes,
+	}
+	// The streams would keep their connections busy until the timeout.
+	server.RegisterOnShutdown(s.hub.Close)
+
+	served := make(chan error, 1)
+	go func() {
+		if s.config.TLSCertFile != "" {
+			served <- server.ListenAndServeTLS(s.config.TLSCertFile, s.config.TLSKeyFile)
+			return
+		}
+		served <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), durationOr(s.config.ShutdownTimeout, defaultShutdownTimeout))
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+
+	// Shutdown doesn't track the hijacked connections of the sessions.
+	s.sessions.Close()
+	if err != nil {
+		return errors.Wrap(err, "cannot drain connections")
+	}
+	return nil
+}
+
+// durationOr returns d, or def when d isn't positive.
+func durationOr(d time.Duration, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// Publish streams e to the clients following its team and counts it in
+// the metrics.
+func (s *Server) Publish(ctx context.Context, e event.Event) {
+	s.hub.Publish(ctx, e)
+	s.metrics.Handle(ctx, e)
+}
+
+// ServeHTTP makes the server usable as an http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
 }
 
 // setupRouter defines a router and add routes to it.
@@ -48,18 +168,41 @@ func (s *Server) setupRouter() {
 	}
 	gin.SetMode(ginMode)
 
-	r := gin.Default()
+	r := gin.New()
+	// the probes would drown the access log
+	r.Use(middleware.Tracing(), middleware.Logger(s.logger, "/healthz", "/readyz", "/version"), gin.Recovery(), s.metrics.Middleware(), middleware.Errors(), middleware.BodyLimit(s.config))
+	r.NoRoute(func(c *gin.Context) {
+		middleware.Abort(c, service.NotFound(service.CodeRouteNotFound, "Route not found."))
+	})
+
+	// probes and build info
+	r.GET("/healthz", s.HandleHealthz)
+	r.GET("/readyz", s.HandleReadyz)
+	r.GET("/version", s.HandleVersion)
+
+	// prometheus metrics
+	r.GET("/metrics", gin.WrapH(s.metrics.Handler()))
+
+	// slack, authenticated by the request signatures
+	r.POST("/slack/commands", s.HandleSlackCommand)
+	r.POST("/slack/actions", s.HandleSlackAction)
+
+	// calendar feeds, authenticated by the token in the URL
+	feeds := r.Group("/api")
+	feeds.GET("/teams/:team-id/turns.ics", s.HandleTeamFeed)
+	feeds.GET("/teams/:team-id/people/:person-id/turns.ics", s.HandlePersonFeed)
 
 	// TODO: authenticate requests
 	api := r.
 		Group("/api").
-		Use(middleware.Cors())
+		Use(middleware.Cors(s.config), s.rateLimiter.Middleware())
 
 	// teams
 	api.GET("/teams", s.HandleListTeams)
 	api.GET("/teams/:team-id", s.HandleShowTeam)
 	api.POST("/teams", s.HandleAddTeam)
 	api.PUT("/teams/:team-id", s.HandleUpdateTeam)
+	api.PATCH("/teams/:team-id", s.HandlePatchTeam)
 	api.DELETE("/teams/:team-id", s.HandleDeleteTeam)
 
 	// team people
@@ -67,23 +210,81 @@ func (s *Server) setupRouter() {
 	api.GET("/teams/:team-id/people/:person-id", s.HandleShowPerson)
 	api.POST("/teams/:team-id/people", s.HandleAddPerson)
 	api.PUT("/teams/:team-id/people/:person-id", s.HandleUpdatePerson)
+	api.PATCH("/teams/:team-id/people/:person-id", s.HandlePatchPerson)
 	api.DELETE("/teams/:team-id/people/:person-id", s.HandleDeletePerson)
 
 	// team turns
 	api.GET("/teams/:team-id/turns", s.HandleListTurns)
 	api.POST("/teams/:team-id/turns", s.HandleUpsertTurn)
+	api.POST("/teams/:team-id/turns/pick", s.HandlePickTurn)
+
+	// team calendar feeds
+	api.GET("/teams/:team-id/calendar", s.HandleShowTeamCalendar)
+	api.GET("/teams/:team-id/people/:person-id/calendar", s.HandleShowPersonCalendar)
+
+	// team live events
+	api.GET("/teams/:team-id/events", s.HandleStreamEvents)
+
+	// team spin sessions
+	api.GET("/teams/:team-id/session", s.HandleSpinSession)
+
+	// team webhooks
+	api.GET("/teams/:team-id/webhooks", s.HandleListWebhooks)
+	api.GET("/teams/:team-id/webhooks/:webhook-id", s.HandleShowWebhook)
+	api.POST("/teams/:team-id/webhooks", s.HandleAddWebhook)
+	api.PUT("/teams/:team-id/webhooks/:webhook-id", s.HandleUpdateWebhook)
+	api.DELETE("/teams/:team-id/webhooks/:webhook-id", s.HandleDeleteWebhook)
+	api.GET("/teams/:team-id/webhooks/:webhook-id/deliveries", s.HandleListWebhookDeliveries)
+
+	// team slack notifications
+	api.GET("/teams/:team-id/slack", s.HandleShowSlackSettings)
+	api.PUT("/teams/:team-id/slack", s.HandleUpdateSlackSettings)
+	api.DELETE("/teams/:team-id/slack", s.HandleDeleteSlackSettings)
+	api.GET("/teams/:team-id/slack/channels", s.HandleListSlackChannels)
+	api.PUT("/teams/:team-id/slack/channels/:channel-id", s.HandleLinkSlackChannel)
+	api.DELETE("/teams/:team-id/slack/channels/:channel-id", s.HandleUnlinkSlackChannel)
+
+	// team scheduled jobs
+	api.GET("/teams/:team-id/jobs", s.HandleListJobs)
+	api.GET("/teams/:team-id/jobs/:kind", s.HandleShowJob)
+	api.PUT("/teams/:team-id/jobs/:kind", s.HandleUpdateJob)
+	api.DELETE("/teams/:team-id/jobs/:kind", s.HandleDeleteJob)
+
+	// docs
+	api.GET("/openapi.json", s.HandleOpenAPI)
+	api.GET("/docs", s.HandleDocs)
+	api.GET("/docs/:asset", s.HandleDocsAsset)
 
 	s.router = r
 }
 
-// teamExists checks if the specified teamID exists in the DB.
-func (s *Server) teamExists(ctx context.Context, teamID int64) (bool, error) {
+// checkTeam returns a NotFound error if the specified teamID doesn't exist in the DB.
+func (s *Server) checkTeam(ctx context.Context, teamID int64) error {
 	_, err := s.teamsService.GetTeam(ctx, teamID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return false, nil
+	return err
+}
+
+// paramID parses the named path parameter as an entity ID.
+func paramID(c *gin.Context, name string) (int64, error) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, service.Validation(
+			service.CodeInvalidParameter,
+			fmt.Sprintf("%s must be a positive integer.", name),
+		)
+	}
+	return id, nil
+}
+
+// bindJSON decodes the request body into obj.
+func bindJSON(c *gin.Context, obj interface{}) error {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		if service.KindOf(err) == service.KindTooLarge {
+			return err
 		}
-		return false, nil
+		e := service.Validation(service.CodeInvalidBody, "Request body is malformed or missing required fields.")
+		e.Err = err
+		return e
 	}
-	return true, nil
+	return nil
 }
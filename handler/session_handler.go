@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/middleware"
+	"github.com/ezerw/wheel/service"
+	"github.com/ezerw/wheel/session"
+	"github.com/ezerw/wheel/util"
+)
+
+// Limits of the spin session connections.
+const (
+	sessionWriteWait      = 10 * time.Second
+	sessionPongWait       = 60 * time.Second
+	sessionPingPeriod     = sessionPongWait * 9 / 10
+	sessionMaxMessageSize = 1024
+	maxWatcherNameLength  = 50
+)
+
+// newUpgrader returns the upgrader of the spin session requests to
+// WebSocket connections, browsers can only open them from the API origin or
+// the allowed web apps. Browsers send their cookies with them, so a
+// wildcard origin doesn't open them to every site.
+func newUpgrader(origins middleware.Origins) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" || origins.Allowed(origin) {
+				return true
+			}
+			parsed, err := url.Parse(origin)
+			return err == nil && strings.EqualFold(parsed.Host, r.Host)
+		},
+	}
+}
+
+// HandleSpinSession handles GET requests to /api/teams/:team-id/session
+// upgraded to a WebSocket joining the spin session of the team as the
+// watcher named by the name query param. Clients send {"type": "spin"} to
+// spin the wheel and receive the session.Message of everything happening.
+func (s *Server) HandleSpinSession(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	name := normalizeWatcherName(c.Query("name"))
+
+	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has replied with the error.
+		return
+	}
+	defer conn.Close()
+
+	client := s.sessions.Join(teamID, name)
+	defer client.Leave()
+
+	go writeSession(conn, client)
+
+	conn.SetReadLimit(sessionMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(sessionPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(sessionPongWait))
+	})
+	for {
+		request := struct {
+			Type string `json:"type"`
+		}{}
+		if err = conn.ReadJSON(&request); err != nil {
+			return
+		}
+		// Other requests are ignored.
+		if request.Type == "spin" {
+			client.Spin(c.Request.Context())
+		}
+	}
+}
+
+// writeSession writes the messages of client to conn, and pings it so dead
+// connections are noticed. It closes conn when the client is closed.
+func writeSession(conn *websocket.Conn, client *session.Client) {
+	ticker := time.NewTicker(sessionPingPeriod)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case message, open := <-client.Messages():
+			_ = conn.SetWriteDeadline(time.Now().Add(sessionWriteWait))
+			if !open {
+				_ = conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := conn.WriteJSON(message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(sessionWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// normalizeWatcherName returns the name shown to the other watchers.
+func normalizeWatcherName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "Anonymous"
+	}
+	if utf8.RuneCountInString(name) > maxWatcherNameLength {
+		name = string([]rune(name)[:maxWatcherNameLength])
+	}
+	return name
+}
+
+// sessionWheel spins the wheels of the spin sessions with the services of
+// the server.
+type sessionWheel struct {
+	server *Server
+}
+
+// Candidates returns the people of the team.
+func (w sessionWheel) Candidates(ctx context.Context, teamID int64) ([]db.ListPeopleRow, error) {
+	return w.server.peopleService.ListPeople(ctx, teamID)
+}
+
+// Pick picks a random member of the team, other than whoever had the
+// latest turn.
+func (w sessionWheel) Pick(ctx context.Context, teamID int64) (*db.ListPeopleRow, error) {
+	return w.server.turnsService.PickPerson(ctx, teamID)
+}
+
+// Assign assigns the next working day's turn to the person.
+func (w sessionWheel) Assign(ctx context.Context, teamID int64, personID int64) (*service.TurnAPI, error) {
+	date, err := util.GetNextWorkingDay(w.server.config.AppTimezone)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting next working day")
+	}
+	return w.server.turnsService.AssignTurn(ctx, teamID, personID, *date)
+}
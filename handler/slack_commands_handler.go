@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/middleware"
+	"github.com/ezerw/wheel/service"
+	"github.com/ezerw/wheel/slack"
+	"github.com/ezerw/wheel/util"
+)
+
+// slackSignatureTolerance is how old a signed Slack request can be.
+const slackSignatureTolerance = 5 * time.Minute
+
+// repickAction is the action ID of the button picking someone else.
+const repickAction = "repick"
+
+// slackHelp is the reply to unknown slash commands.
+const slackHelp = "Usage:\n• `/wheel spin` picks who hosts the next meeting\n• `/wheel who` tells who is hosting today"
+
+// HandleSlackCommand handles POST request to /slack/commands
+// it answers the slash commands of the channels linked to a team:
+// - spin: assigns the next working day's turn to a random member
+// - who: tells who has today's turn
+func (s *Server) HandleSlackCommand(c *gin.Context) {
+	form, err := s.slackForm(c)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	command := slack.ParseCommand(form)
+	ctx := c.Request.Context()
+
+	channel, err := s.slackService.TeamOfChannel(ctx, command.ChannelID)
+	if err != nil {
+		slackError(c, err)
+		return
+	}
+
+	var message slack.Message
+	switch strings.ToLower(strings.TrimSpace(command.Text)) {
+	case "spin":
+		message, err = s.spin(ctx, channel.TeamID, time.Time{})
+	case "who":
+		message, err = s.whoIsHosting(ctx, channel.TeamID)
+	default:
+		message = slack.Message{ResponseType: slack.Ephemeral, Text: slackHelp}
+	}
+	if err != nil {
+		slackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+// HandleSlackAction handles POST request to /slack/actions
+// sent when a button of a message is clicked. The message is updated
+// through the response URL of the interaction.
+func (s *Server) HandleSlackAction(c *gin.Context) {
+	form, err := s.slackForm(c)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	interaction, err := slack.ParseInteraction(form)
+	if err != nil {
+		e := service.Validation(service.CodeInvalidBody, "Interaction payload is malformed.")
+		e.Err = err
+		middleware.Abort(c, e)
+		return
+	}
+	// A leaked signing secret would otherwise let anyone have the API post
+	// wherever they want.
+	if !strings.HasPrefix(interaction.ResponseURL, s.slackReplyURLs) {
+		middleware.Abort(c, service.Validation(service.CodeInvalidBody, "Interaction response_url is not a Slack URL."))
+		return
+	}
+
+	ctx := c.Request.Context()
+	for _, action := range interaction.Actions {
+		if action.ActionID != repickAction {
+			continue
+		}
+
+		message, err := s.repick(ctx, interaction.Channel.ID, action.Value)
+		if err != nil {
+			if service.KindOf(err) == service.KindInternal {
+				middleware.Abort(c, err)
+				return
+			}
+			message = slack.Message{ResponseType: slack.Ephemeral, Text: errorText(err)}
+		}
+
+		err = slack.Post(ctx, s.slackClient, interaction.ResponseURL, message)
+		if err != nil {
+			middleware.Abort(c, errors.Wrap(err, "error replying to slack"))
+			return
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// spin assigns the turn on date, or the next working day when zero, to a
+// random member of the team other than the excluded people. Spins take from
+// the write budget of the team, as the requests all come from Slack.
+func (s *Server) spin(ctx context.Context, teamID int64, date time.Time, exclude ...int64) (slack.Message, error) {
+	if wait := s.rateLimiter.Take("slack:" + strconv.FormatInt(teamID, 10)); wait > 0 {
+		return slack.Message{}, service.RateLimited(service.CodeRateLimited, fmt.Sprintf(
+			"The wheel needs a rest, try again in %d seconds.", int(math.Ceil(wait.Seconds())),
+		))
+	}
+
+	if date.IsZero() {
+		next, err := util.GetNextWorkingDay(s.config.AppTimezone)
+		if err != nil {
+			return slack.Message{}, errors.Wrap(err, "error getting next working day")
+		}
+		date = *next
+	}
+
+	person, err := s.turnsService.PickPerson(ctx, teamID, exclude...)
+	if err != nil {
+		return slack.Message{}, err
+	}
+
+	turn, err := s.turnsService.AssignTurn(ctx, teamID, person.ID, date)
+	if err != nil {
+		return slack.Message{}, err
+	}
+
+	text := fmt.Sprintf(
+		":dart: The wheel picked *%s %s* to host on %s.",
+		person.FirstName,
+		person.LastName,
+		turn.Date.Format("Monday 2 January"),
+	)
+	value := strconv.FormatInt(person.ID, 10) + ":" + turn.Date.Format("2006-01-02")
+
+	message := slack.HostMessage(text, turn.Date).WithButton("Re-pick", repickAction, value)
+	message.ResponseType = slack.InChannel
+	return message, nil
+}
+
+// repick spins again for the turn of a spin message, value holds the
+// person picked and the date as "<person-id>:<YYYY-MM-DD>".
+func (s *Server) repick(ctx context.Context, channelID string, value string) (slack.Message, error) {
+	channel, err := s.slackService.TeamOfChannel(ctx, channelID)
+	if err != nil {
+		return slack.Message{}, err
+	}
+
+	parts := strings.SplitN(value, ":", 2)
+	personID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || len(parts) != 2 {
+		return slack.Message{}, service.Validation(service.CodeInvalidBody, "Action value is malformed.")
+	}
+
+	location, err := time.LoadLocation(s.config.AppTimezone)
+	if err != nil {
+		return slack.Message{}, err
+	}
+	date, err := time.ParseInLocation("2006-01-02", parts[1], location)
+	if err != nil {
+		return slack.Message{}, service.Validation(service.CodeInvalidBody, "Action value is malformed.")
+	}
+
+	message, err := s.spin(ctx, channel.TeamID, date, personID)
+	if err != nil {
+		return slack.Message{}, err
+	}
+
+	message.ReplaceOriginal = true
+	return message, nil
+}
+
+// whoIsHosting tells who has today's turn of the team.
+func (s *Server) whoIsHosting(ctx context.Context, teamID int64) (slack.Message, error) {
+	location, err := time.LoadLocation(s.config.AppTimezone)
+	if err != nil {
+		return slack.Message{}, err
+	}
+	year, month, day := time.Now().In(location).Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, location)
+
+	turn, err := s.turnsService.GetTurnByDate(ctx, db.GetTurnByDateAndTeamParams{
+		Date:   today,
+		TeamID: teamID,
+	})
+	if service.KindOf(err) == service.KindNotFound {
+		return slack.Message{ResponseType: slack.Ephemeral, Text: "Nobody is hosting today."}, nil
+	}
+	if err != nil {
+		return slack.Message{}, err
+	}
+
+	person, err := s.peopleService.GetPerson(ctx, db.GetPersonParams{
+		ID:     turn.PersonID,
+		TeamID: teamID,
+	})
+	if err != nil {
+		return slack.Message{}, err
+	}
+
+	text := fmt.Sprintf("*%s %s* is hosting today.", person.FirstName, person.LastName)
+	message := slack.HostMessage(text, today)
+	message.ResponseType = slack.Ephemeral
+	return message, nil
+}
+
+// slackForm verifies the request was signed by Slack and parses its form
+// encoded body.
+func (s *Server) slackForm(c *gin.Context) (url.Values, error) {
+	body, err := c.GetRawData()
+	if err != nil {
+		return nil, err
+	}
+
+	err = slack.Verify(s.config.SlackSigningSecret, c.Request.Header, body, slackSignatureTolerance)
+	if err != nil {
+		e := service.Unauthorized(service.CodeUnauthorized, "The request is not signed by Slack.")
+		e.Err = err
+		return nil, e
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		e := service.Validation(service.CodeInvalidBody, "Request body is malformed.")
+		e.Err = err
+		return nil, e
+	}
+	return form, nil
+}
+
+// slackError replies with the message of a domain error so Slack shows it
+// to the user, unexpected errors go to the error middleware.
+func slackError(c *gin.Context, err error) {
+	if service.KindOf(err) == service.KindInternal {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, slack.Message{ResponseType: slack.Ephemeral, Text: errorText(err)})
+}
+
+// errorText is the client facing message of a domain error.
+func errorText(err error) string {
+	var e *service.Error
+	if errors.As(err, &e) {
+		return e.Message
+	}
+	return err.Error()
+}
@@ -0,0 +1,325 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/slack"
+	"github.com/ezerw/wheel/util"
+)
+
+const slackSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+func TestSlackCommands(t *testing.T) {
+	store := newSlackStore()
+	server, err := NewServer(util.Config{AppTimezone: "UTC", SlackSigningSecret: slackSecret}, store, util.NewLogger())
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+
+	command := func(text string) slack.Message {
+		form := url.Values{"command": {"/wheel"}, "text": {text}, "channel_id": {"C1"}}
+		w := postSlack(server, "/slack/commands", form, slackSecret)
+		if w.Code != http.StatusOK {
+			t.Fatalf("/wheel %s = %d %s, want 200", text, w.Code, w.Body)
+		}
+		var message slack.Message
+		if err := json.Unmarshal(w.Body.Bytes(), &message); err != nil {
+			t.Fatalf("cannot decode the reply to /wheel %s: %v", text, err)
+		}
+		return message
+	}
+
+	if message := command("who"); message.Text != "Nobody is hosting today." {
+		t.Errorf("/wheel who = %q, want nobody hosting", message.Text)
+	}
+	year, month, day := time.Now().UTC().Date()
+	store.turns[100] = db.Turn{ID: 100, PersonID: 1, Date: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Version: 1}
+	if message := command("who"); message.Text != "*Bruce Wayne* is hosting today." {
+		t.Errorf("/wheel who = %q, want Bruce hosting", message.Text)
+	}
+
+	message := command("spin")
+	if message.ResponseType != slack.InChannel || !strings.Contains(message.Text, "The wheel picked") {
+		t.Fatalf("/wheel spin = %+v, want the pick posted in the channel", message)
+	}
+	value := repickValue(t, message)
+	picked, _ := strconv.ParseInt(value[:strings.Index(value, ":")], 10, 64)
+	if turn := store.turnOn(value[strings.Index(value, ":")+1:]); turn.PersonID != picked {
+		t.Errorf("/wheel spin assigned %d, want %d", turn.PersonID, picked)
+	}
+
+	// Re-pick replies through the response URL of the interaction.
+	var (
+		mu      sync.Mutex
+		replies []slack.Message
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var reply slack.Message
+		if err := json.Unmarshal(body, &reply); err != nil {
+			t.Errorf("cannot decode the reply: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		replies = append(replies, reply)
+	}))
+	defer receiver.Close()
+	server.slackReplyURLs = receiver.URL + "/"
+	sent := func() []slack.Message {
+		mu.Lock()
+		defer mu.Unlock()
+		return replies
+	}
+
+	action := func(responseURL string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(map[string]interface{}{
+			"type":         "block_actions",
+			"channel":      map[string]string{"id": "C1"},
+			"response_url": responseURL,
+			"actions":      []map[string]string{{"action_id": repickAction, "value": value}},
+		})
+		return postSlack(server, "/slack/actions", url.Values{"payload": {string(payload)}}, slackSecret)
+	}
+
+	if w := action(receiver.URL + "/actions/1"); w.Code != http.StatusOK {
+		t.Fatalf("re-pick = %d %s, want 200", w.Code, w.Body)
+	}
+	if got := sent(); len(got) != 1 || !got[0].ReplaceOriginal {
+		t.Fatalf("re-pick replied %+v, want the spin message replaced", got)
+	}
+	if turn := store.turnOn(value[strings.Index(value, ":")+1:]); turn.PersonID == picked {
+		t.Errorf("re-pick kept %d, want the other member", turn.PersonID)
+	}
+
+	if w := action("https://example.com/collect"); w.Code != http.StatusBadRequest {
+		t.Errorf("re-pick with a foreign response URL = %d, want 400", w.Code)
+	}
+	if got := sent(); len(got) != 1 {
+		t.Errorf("got %d replies, want the foreign response URL left alone", len(got))
+	}
+}
+
+func TestSlackLimitsSpins(t *testing.T) {
+	store := newSlackStore()
+	config := util.Config{AppTimezone: "UTC", SlackSigningSecret: slackSecret, RateLimitWrites: 1, RateLimitWriteBurst: 1}
+	server, err := NewServer(config, store, util.NewLogger())
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+
+	form := url.Values{"command": {"/wheel"}, "text": {"spin"}, "channel_id": {"C1"}}
+	postSlack(server, "/slack/commands", form, slackSecret)
+	w := postSlack(server, "/slack/commands", form, slackSecret)
+	var message slack.Message
+	if err := json.Unmarshal(w.Body.Bytes(), &message); err != nil {
+		t.Fatalf("cannot decode the reply: %v", err)
+	}
+	if message.ResponseType != slack.Ephemeral || !strings.Contains(message.Text, "try again in 60 seconds") {
+		t.Errorf("second spin = %+v, want to be told to wait a minute", message)
+	}
+	if len(store.turns) != 1 {
+		t.Errorf("assigned %d turns, want the second spin refused", len(store.turns))
+	}
+}
+
+func TestSlackRejectsBadSignatures(t *testing.T) {
+	store := newSlackStore()
+	server, err := NewServer(util.Config{AppTimezone: "UTC", SlackSigningSecret: slackSecret}, store, util.NewLogger())
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+
+	form := url.Values{"command": {"/wheel"}, "text": {"spin"}, "channel_id": {"C1"}}
+	for _, path := range []string{"/slack/commands", "/slack/actions"} {
+		if w := postSlack(server, path, form, "not the secret"); w.Code != http.StatusUnauthorized {
+			t.Errorf("POST %s signed with another secret = %d, want 401", path, w.Code)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("POST %s unsigned = %d, want 401", path, w.Code)
+		}
+	}
+	if len(store.turns) != 0 {
+		t.Errorf("unsigned spin assigned %d turns, want none", len(store.turns))
+	}
+}
+
+// postSlack posts form to path as Slack would, signed with secret.
+func postSlack(server *Server, path string, form url.Values, secret string) *httptest.ResponseRecorder {
+	body := form.Encode()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set(slack.TimestampHeader, timestamp)
+	req.Header.Set(slack.SignatureHeader, slack.Sign(secret, timestamp, []byte(body)))
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	return w
+}
+
+// repickValue is the value of the Re-pick button of message.
+func repickValue(t *testing.T, message slack.Message) string {
+	t.Helper()
+	for _, block := range message.Blocks {
+		for _, element := range block.Elements {
+			button, _ := element.(map[string]interface{})
+			if button["action_id"] == repickAction {
+				return button["value"].(string)
+			}
+		}
+	}
+	t.Fatalf("message %+v has no Re-pick button", message)
+	return ""
+}
+
+// slackStore is an in-memory db.Store implementing the queries used by the
+// slash commands of a team of two linked to channel C1.
+type slackStore struct {
+	db.Store
+
+	mu     sync.Mutex
+	nextID int64
+	people map[int64]db.Person
+	turns  map[int64]db.Turn
+}
+
+func newSlackStore() *slackStore {
+	return &slackStore{
+		nextID: 100,
+		people: map[int64]db.Person{
+			1: {ID: 1, FirstName: "Bruce", LastName: "Wayne", TeamID: 1, Version: 1},
+			2: {ID: 2, FirstName: "Diana", LastName: "Prince", TeamID: 1, Version: 1},
+		},
+		turns: map[int64]db.Turn{},
+	}
+}
+
+// turnOn is the turn on date, formatted as YYYY-MM-DD.
+func (s *slackStore) turnOn(date string) db.Turn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, turn := range s.turns {
+		if turn.Date.Format("2006-01-02") == date {
+			return turn
+		}
+	}
+	return db.Turn{}
+}
+
+// slackResult is the sql.Result of an insert or update.
+type slackResult int64
+
+func (r slackResult) LastInsertId() (int64, error) { return int64(r), nil }
+func (r slackResult) RowsAffected() (int64, error) { return 1, nil }
+
+// ExecTx runs fn straight on the store, which is enough for the tests.
+func (s *slackStore) ExecTx(_ context.Context, fn func(db.Querier) error) error {
+	return fn(s)
+}
+
+func (s *slackStore) CreateOutboxEvent(_ context.Context, _ db.CreateOutboxEventParams) (sql.Result, error) {
+	return slackResult(0), nil
+}
+
+func (s *slackStore) GetSlackChannel(_ context.Context, channelID string) (db.SlackChannel, error) {
+	if channelID != "C1" {
+		return db.SlackChannel{}, sql.ErrNoRows
+	}
+	return db.SlackChannel{ChannelID: channelID, TeamID: 1}, nil
+}
+
+func (s *slackStore) GetPerson(_ context.Context, arg db.GetPersonParams) (db.GetPersonRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	person, ok := s.people[arg.ID]
+	if !ok || person.TeamID != arg.TeamID {
+		return db.GetPersonRow{}, sql.ErrNoRows
+	}
+	return db.GetPersonRow{ID: person.ID, FirstName: person.FirstName, LastName: person.LastName, TeamID: person.TeamID, Version: person.Version}, nil
+}
+
+func (s *slackStore) ListPeople(_ context.Context, teamID int64) ([]db.ListPeopleRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	people := []db.ListPeopleRow{}
+	for id := int64(1); id <= 2; id++ {
+		person := s.people[id]
+		people = append(people, db.ListPeopleRow{ID: person.ID, FirstName: person.FirstName, LastName: person.LastName, TeamID: person.TeamID, Version: person.Version})
+	}
+	return people, nil
+}
+
+func (s *slackStore) CreateTurn(_ context.Context, arg db.CreateTurnParams) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.turns[s.nextID] = db.Turn{ID: s.nextID, PersonID: arg.PersonID, Date: arg.Date, Version: 1}
+	return slackResult(s.nextID), nil
+}
+
+func (s *slackStore) GetTurn(_ context.Context, arg db.GetTurnParams) (db.GetTurnRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	turn, ok := s.turns[arg.ID]
+	if !ok {
+		return db.GetTurnRow{}, sql.ErrNoRows
+	}
+	return db.GetTurnRow{ID: turn.ID, PersonID: turn.PersonID, Date: turn.Date, Version: turn.Version}, nil
+}
+
+func (s *slackStore) GetTurnByDateAndTeam(_ context.Context, arg db.GetTurnByDateAndTeamParams) (db.GetTurnByDateAndTeamRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, turn := range s.turns {
+		if turn.Date.Equal(arg.Date) {
+			return db.GetTurnByDateAndTeamRow{ID: turn.ID, PersonID: turn.PersonID, Date: turn.Date, Version: turn.Version}, nil
+		}
+	}
+	return db.GetTurnByDateAndTeamRow{}, sql.ErrNoRows
+}
+
+func (s *slackStore) ListTurns(_ context.Context, _ db.ListTurnsParams) ([]db.ListTurnsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	turns := []db.ListTurnsRow{}
+	var latest *db.Turn
+	for _, turn := range s.turns {
+		turn := turn
+		if latest == nil || turn.Date.After(latest.Date) {
+			latest = &turn
+		}
+	}
+	if latest != nil {
+		turns = append(turns, db.ListTurnsRow{ID: latest.ID, PersonID: latest.PersonID, Date: latest.Date, Version: latest.Version})
+	}
+	return turns, nil
+}
+
+func (s *slackStore) UpdateTurn(_ context.Context, arg db.UpdateTurnParams) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	turn := s.turns[arg.ID]
+	turn.PersonID = arg.PersonID
+	turn.Date = arg.Date
+	turn.Version++
+	s.turns[arg.ID] = turn
+	return slackResult(arg.ID), nil
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/middleware"
+	"github.com/ezerw/wheel/service"
+)
+
+// HandleShowSlackSettings handles GET request to /api/teams/:team-id/slack
+func (s *Server) HandleShowSlackSettings(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	settings, err := s.slackService.GetSlackSettings(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": settings})
+}
+
+// HandleUpdateSlackSettings handles PUT request to /api/teams/:team-id/slack
+// it creates the settings or replaces them entirely.
+func (s *Server) HandleUpdateSlackSettings(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	binding := struct {
+		WebhookURL       string `json:"webhook_url"`
+		NotifyAssigned   *bool  `json:"notify_assigned"`
+		ReminderEnabled  bool   `json:"reminder_enabled"`
+		ReminderTime     string `json:"reminder_time"`
+		AssignedTemplate string `json:"assigned_template"`
+		ReminderTemplate string `json:"reminder_template"`
+	}{}
+	err = bindJSON(c, &binding)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	settings, err := s.slackService.PutSlackSettings(c.Request.Context(), service.SlackSettingsAPI{
+		TeamID:           teamID,
+		WebhookURL:       binding.WebhookURL,
+		NotifyAssigned:   optIn(binding.NotifyAssigned),
+		ReminderEnabled:  binding.ReminderEnabled,
+		ReminderTime:     binding.ReminderTime,
+		AssignedTemplate: binding.AssignedTemplate,
+		ReminderTemplate: binding.ReminderTemplate,
+	})
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": settings})
+}
+
+// HandleDeleteSlackSettings handles DELETE request to /api/teams/:team-id/slack
+func (s *Server) HandleDeleteSlackSettings(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.slackService.DeleteSlackSettings(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// HandleListSlackChannels handles GET request to /api/teams/:team-id/slack/channels
+func (s *Server) HandleListSlackChannels(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	channels, err := s.slackService.ListChannels(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": channels})
+}
+
+// HandleLinkSlackChannel handles PUT request to /api/teams/:team-id/slack/channels/:channel-id
+// the slash commands of the channel act on the team from then on.
+func (s *Server) HandleLinkSlackChannel(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	channel, err := s.slackService.LinkChannel(c.Request.Context(), teamID, c.Param("channel-id"))
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": channel})
+}
+
+// HandleUnlinkSlackChannel handles DELETE request to /api/teams/:team-id/slack/channels/:channel-id
+func (s *Server) HandleUnlinkSlackChannel(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	args := db.DeleteSlackChannelParams{
+		ChannelID: c.Param("channel-id"),
+		TeamID:    teamID,
+	}
+	err = s.slackService.UnlinkChannel(c.Request.Context(), args)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
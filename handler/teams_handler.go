@@ -1,78 +1,90 @@
 package handler
 
 import (
-	"database/sql"
+	"context"
 	"net/http"
-	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/pkg/errors"
 
 	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/middleware"
 )
 
 // HandleListTeams handles GET request to /api/teams
 func (s *Server) HandleListTeams(c *gin.Context) {
 	teams, err := s.teamsService.ListTeams(c.Request.Context())
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": teams})
+	respondWithETag(c, teams, teamStamps(teams))
 }
 
 // HandleShowTeam handles GET request to /api/teams/:team-id
 func (s *Server) HandleShowTeam(c *gin.Context) {
-	queryTeamID := c.Param("team-id")
-	teamID, err := strconv.ParseInt(queryTeamID, 10, 64)
+	teamID, err := paramID(c, "team-id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	team, err := s.teamsService.GetTeam(c.Request.Context(), teamID)
+	team, err := s.showTeam(c.Request.Context(), teamID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
-				"error": "Team not found.",
-			})
-			return
-		}
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	// Add people to the team response
-	composite := struct {
-		TeamID   int64              `json:"id"`
-		TeamName string             `json:"name"`
-		People   []db.ListPeopleRow `json:"people"`
-	}{}
+	respondWithETag(c, team, team.stamps())
+}
+
+// teamWithPeople is the representation of a team along with its people.
+type teamWithPeople struct {
+	TeamID        int64              `json:"id"`
+	TeamName      string             `json:"name"`
+	TeamVersion   int32              `json:"version"`
+	TeamUpdatedAt time.Time          `json:"updated_at"`
+	People        []db.ListPeopleRow `json:"people"`
+}
 
-	composite.TeamName = team.Name
-	composite.TeamID = team.ID
+// showTeam returns the representation of the team served by
+// GET /api/teams/:team-id, whose ETag the writes are checked against.
+func (s *Server) showTeam(ctx context.Context, teamID int64) (*teamWithPeople, error) {
+	team, err := s.teamsService.GetTeam(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
 
-	people, _ := s.peopleService.ListPeople(c.Request.Context(), teamID)
-	composite.People = people
+	// Add people to the team response
+	people, err := s.peopleService.ListPeople(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
 
-	c.JSON(http.StatusOK, gin.H{"data": composite})
+	return &teamWithPeople{
+		TeamID:        team.ID,
+		TeamName:      team.Name,
+		TeamVersion:   team.Version,
+		TeamUpdatedAt: team.UpdatedAt,
+		People:        people,
+	}, nil
 }
 
 // HandleAddTeam handles POST request to /api/teams
 func (s *Server) HandleAddTeam(c *gin.Context) {
 	binding := struct {
-		Name string `json:"name" binding:"required"`
+		Name string `json:"name"`
 	}{}
-	err := c.BindJSON(&binding)
+	err := bindJSON(c, &binding)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
 	team, err := s.teamsService.AddTeam(c.Request.Context(), binding.Name)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
@@ -81,68 +93,119 @@ func (s *Server) HandleAddTeam(c *gin.Context) {
 
 // HandleUpdateTeam handles PUT request to /api/teams/:team-id
 func (s *Server) HandleUpdateTeam(c *gin.Context) {
-	queryTeamID := c.Param("team-id")
-	teamID, err := strconv.ParseInt(queryTeamID, 10, 64)
+	teamID, err := paramID(c, "team-id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	binding := struct {
-		Name string `json:"name,omitempty"`
-	}{}
-	err = c.BindJSON(&binding)
+	binding := teamBinding{}
+	err = bindJSON(c, &binding)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	current, err := s.showTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = checkIfMatch(c, current.stamps(), true)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	exists, err := s.teamExists(c.Request.Context(), teamID)
+	s.updateTeam(c, teamID, binding)
+}
+
+// HandlePatchTeam handles PATCH request to /api/teams/:team-id
+// the body is a JSON merge patch (RFC 7396) applied on top of the current team.
+func (s *Server) HandlePatchTeam(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	team, err := s.showTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	// Patches are only conditional when asked to be.
+	err = checkIfMatch(c, team.stamps(), false)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
-	if !exists {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Team not found."})
+
+	binding := teamBinding{}
+	err = bindVersionedPatch(c, team, &binding)
+	if err != nil {
+		middleware.Abort(c, err)
 		return
 	}
 
+	s.updateTeam(c, teamID, binding)
+}
+
+// teamBinding is the writable representation of a team, along with the
+// version the changes are based on.
+type teamBinding struct {
+	Name    string `json:"name"`
+	Version int32  `json:"version"`
+}
+
+// updateTeam stores binding as the new state of the team and responds with
+// the updated team.
+func (s *Server) updateTeam(c *gin.Context, teamID int64, binding teamBinding) {
 	updateTeamArgs := db.UpdateTeamParams{
-		Name: binding.Name,
-		ID:   teamID,
+		Name:    binding.Name,
+		ID:      teamID,
+		Version: binding.Version,
 	}
 
-	err = s.teamsService.UpdateTeam(c.Request.Context(), updateTeamArgs)
+	team, err := s.teamsService.UpdateTeam(c.Request.Context(), updateTeamArgs)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{})
+	// The tag of a team covers its people, as the writes check it against
+	// the team served by GET.
+	if current, err := s.showTeam(c.Request.Context(), teamID); err == nil {
+		setETag(c, current.stamps())
+	}
+	c.JSON(http.StatusOK, gin.H{"data": team})
 }
 
 // HandleDeleteTeam handles DELETE request to /api/teams/:team-id
 func (s *Server) HandleDeleteTeam(c *gin.Context) {
-	queryTeamID := c.Param("team-id")
-	teamID, err := strconv.ParseInt(queryTeamID, 10, 64)
+	teamID, err := paramID(c, "team-id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	exists, err := s.teamExists(c.Request.Context(), teamID)
+	current, err := s.showTeam(c.Request.Context(), teamID)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
-	if !exists {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Team not found."})
+
+	err = checkIfMatch(c, current.stamps(), true)
+	if err != nil {
+		middleware.Abort(c, err)
 		return
 	}
 
 	err = s.teamsService.DeleteTeam(c.Request.Context(), teamID)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
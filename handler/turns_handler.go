@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"database/sql"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,6 +10,8 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/middleware"
+	"github.com/ezerw/wheel/service"
 	"github.com/ezerw/wheel/util"
 )
 
@@ -24,31 +25,26 @@ func (s *Server) HandleListTurns(c *gin.Context) {
 	queryLimit := c.DefaultQuery("limit", "10")
 	limit, err := strconv.ParseInt(queryLimit, 10, 64)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "limit invalid format."})
+		middleware.Abort(c, service.Validation(service.CodeInvalidParameter, "limit invalid format."))
 		return
 	}
 
 	queryOffset := c.DefaultQuery("offset", "0")
 	offset, err := strconv.ParseInt(queryOffset, 10, 64)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "offset invalid format."})
+		middleware.Abort(c, service.Validation(service.CodeInvalidParameter, "offset invalid format."))
 		return
 	}
 
-	queryTeamID := c.Param("team-id")
-	teamID, err := strconv.ParseInt(queryTeamID, 10, 64)
+	teamID, err := paramID(c, "team-id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "team_id invalid format"})
+		middleware.Abort(c, err)
 		return
 	}
 
-	exists, err := s.teamExists(c.Request.Context(), teamID)
+	err = s.checkTeam(c.Request.Context(), teamID)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	if !exists {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Team not found."})
+		middleware.Abort(c, err)
 		return
 	}
 
@@ -59,7 +55,7 @@ func (s *Server) HandleListTurns(c *gin.Context) {
 
 	loc, err := time.LoadLocation(s.config.AppTimezone)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to load location: " + err.Error()})
+		middleware.Abort(c, errors.Wrap(err, "failed to load location"))
 		return
 	}
 
@@ -67,7 +63,7 @@ func (s *Server) HandleListTurns(c *gin.Context) {
 	if queryDateFrom != "" {
 		dateFrom, err = time.ParseInLocation("2006-01-02", queryDateFrom, loc)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "date_from invalid format."})
+			middleware.Abort(c, service.Validation(service.CodeInvalidParameter, "date_from invalid format."))
 			return
 		}
 	}
@@ -76,18 +72,18 @@ func (s *Server) HandleListTurns(c *gin.Context) {
 	if queryDateTo != "" {
 		dateTo, err = time.ParseInLocation("2006-01-02", queryDateTo, loc)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "date_to invalid format."})
+			middleware.Abort(c, service.Validation(service.CodeInvalidParameter, "date_to invalid format."))
 			return
 		}
 	}
 
 	turns, err := s.turnsService.ListTurns(c.Request.Context(), teamID, dateFrom, dateTo, limit, offset)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": turns})
+	respondWithETag(c, turns, turnStamps(turns))
 }
 
 // HandleUpsertTurn handles POST request to /api/teams/:team-id/turns
@@ -95,84 +91,108 @@ func (s *Server) HandleListTurns(c *gin.Context) {
 // if it doesn't exist will create the turn.
 // DB unique: (team_id, date) - A team can't have multiple people assigned for the same date.
 func (s *Server) HandleUpsertTurn(c *gin.Context) {
-	queryTeamID := c.Param("team-id")
-	teamID, err := strconv.ParseInt(queryTeamID, 10, 64)
+	teamID, err := paramID(c, "team-id")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "team_id invalid format"})
+		middleware.Abort(c, err)
 		return
 	}
-	teamExists, err := s.teamExists(c.Request.Context(), teamID)
+	err = s.checkTeam(c.Request.Context(), teamID)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	if !teamExists {
-		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Team not found."})
+		middleware.Abort(c, err)
 		return
 	}
 
 	// Only required person as date will be calculated.
 	binding := struct {
-		PersonID int64 `json:"person_id" binding:"required"`
+		PersonID int64 `json:"person_id"`
+		Version  int32 `json:"version"`
 	}{}
-	err = c.BindJSON(&binding)
+	err = bindJSON(c, &binding)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
-
-	getPersonArgs := db.GetPersonParams{
-		ID:     binding.PersonID,
-		TeamID: teamID,
+	if binding.PersonID <= 0 {
+		middleware.Abort(c, service.InvalidFields(service.FieldError{
+			Field:   "person_id",
+			Code:    service.FieldRequired,
+			Message: "person_id must be a positive integer.",
+		}))
+		return
 	}
-	person, err := s.peopleService.GetPerson(c.Request.Context(), getPersonArgs)
+
+	date, err := util.GetNextWorkingDay(s.config.AppTimezone)
 	if err != nil {
-		if errors.Is(sql.ErrNoRows, err) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Person not found in the specified team"})
-			return
-		}
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, errors.Wrap(err, "error getting next working day"))
 		return
 	}
 
-	date, err := util.GetNextWorkingDay(s.config.AppTimezone)
+	// Reassigning the turn already booked for that day requires its
+	// version, so two people can't silently overwrite each other's change.
+	turn, err := s.turnsService.AssignTurnAtVersion(c.Request.Context(), teamID, binding.PersonID, *date, binding.Version)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error getting next working day"})
+		middleware.Abort(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"data": turn})
+}
+
+// pickResult is the person the wheel picked, along with the turn they were
+// assigned unless it was a dry run.
+type pickResult struct {
+	Person db.ListPeopleRow `json:"person"`
+	Turn   *service.TurnAPI `json:"turn,omitempty"`
+}
 
-	// Lookup the specified turn by date and team
-	getTurnArgs := db.GetTurnByDateAndTeamParams{
-		Date:     *date,
-		TeamID: person.TeamID,
+// HandlePickTurn handles POST request to /api/teams/:team-id/turns/pick
+// it assigns the next working day's turn to a random member of the team other
+// than whoever had the latest turn. The body is optional: dry_run picks
+// without assigning and version is the one of the turn already booked that
+// day, to reassign it.
+func (s *Server) HandlePickTurn(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
 	}
-	turn, err := s.turnsService.GetTurnByDate(c.Request.Context(), getTurnArgs)
+	err = s.checkTeam(c.Request.Context(), teamID)
 	if err != nil {
-		if errors.Is(sql.ErrNoRows, err) {
-			// if it doesn't exist create it and return
-			turn, err = s.turnsService.AddTurn(c.Request.Context(), teamID, person.ID, *date)
-			if err != nil {
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-			c.JSON(http.StatusOK, gin.H{"data": turn})
+		middleware.Abort(c, err)
+		return
+	}
+
+	binding := struct {
+		DryRun  bool  `json:"dry_run"`
+		Version int32 `json:"version"`
+	}{}
+	if c.Request.ContentLength != 0 {
+		err = bindJSON(c, &binding)
+		if err != nil {
+			middleware.Abort(c, err)
 			return
 		}
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+
+	person, err := s.turnsService.PickPerson(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+	if binding.DryRun {
+		c.JSON(http.StatusOK, gin.H{"data": pickResult{Person: *person}})
 		return
 	}
 
-	// if it does exist, update it
-	updateTurnArgs := db.UpdateTurnParams{
-		PersonID: person.ID,
-		Date:     turn.Date,
-		ID:       turn.ID,
+	date, err := util.GetNextWorkingDay(s.config.AppTimezone)
+	if err != nil {
+		middleware.Abort(c, errors.Wrap(err, "error getting next working day"))
+		return
 	}
 
-	turn, err = s.turnsService.UpdateTurn(c.Request.Context(), teamID, updateTurnArgs)
+	turn, err := s.turnsService.AssignTurnAtVersion(c.Request.Context(), teamID, person.ID, *date, binding.Version)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.Abort(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": turn})
+	c.JSON(http.StatusOK, gin.H{"data": pickResult{Person: *person, Turn: turn}})
 }
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
+	"github.com/ezerw/wheel/middleware"
+	"github.com/ezerw/wheel/service"
+)
+
+// HandleListWebhooks handles GET request to /api/teams/:team-id/webhooks
+func (s *Server) HandleListWebhooks(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	webhooks, err := s.webhooksService.ListWebhooks(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": webhooks})
+}
+
+// HandleShowWebhook handles GET request to /api/teams/:team-id/webhooks/:webhook-id
+func (s *Server) HandleShowWebhook(c *gin.Context) {
+	teamID, webhookID, err := webhookParams(c)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	args := db.GetWebhookParams{
+		ID:     webhookID,
+		TeamID: teamID,
+	}
+	webhook, err := s.webhooksService.GetWebhook(c.Request.Context(), args)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": webhook})
+}
+
+// HandleAddWebhook handles POST request to /api/teams/:team-id/webhooks
+// the secret is generated when omitted and only returned in this response.
+func (s *Server) HandleAddWebhook(c *gin.Context) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	err = s.checkTeam(c.Request.Context(), teamID)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	binding := struct {
+		URL    string       `json:"url"`
+		Events []event.Type `json:"events"`
+		Secret string       `json:"secret"`
+	}{}
+	err = bindJSON(c, &binding)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	webhook, err := s.webhooksService.AddWebhook(
+		c.Request.Context(),
+		teamID,
+		binding.URL,
+		binding.Events,
+		binding.Secret,
+	)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": webhook})
+}
+
+// HandleUpdateWebhook handles PUT request to /api/teams/:team-id/webhooks/:webhook-id
+func (s *Server) HandleUpdateWebhook(c *gin.Context) {
+	teamID, webhookID, err := webhookParams(c)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	binding := struct {
+		URL    string       `json:"url"`
+		Events []event.Type `json:"events"`
+	}{}
+	err = bindJSON(c, &binding)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	webhook, err := s.webhooksService.UpdateWebhook(
+		c.Request.Context(),
+		teamID,
+		webhookID,
+		binding.URL,
+		binding.Events,
+	)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": webhook})
+}
+
+// HandleDeleteWebhook handles DELETE request to /api/teams/:team-id/webhooks/:webhook-id
+func (s *Server) HandleDeleteWebhook(c *gin.Context) {
+	teamID, webhookID, err := webhookParams(c)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	args := db.DeleteWebhookParams{
+		ID:     webhookID,
+		TeamID: teamID,
+	}
+	err = s.webhooksService.DeleteWebhook(c.Request.Context(), args)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// HandleListWebhookDeliveries handles GET request to /api/teams/:team-id/webhooks/:webhook-id/deliveries
+// it accepts the following query params:
+// - limit [Default to 20]
+// - offset [Default to 0]
+func (s *Server) HandleListWebhookDeliveries(c *gin.Context) {
+	teamID, webhookID, err := webhookParams(c)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 32)
+	if err != nil {
+		middleware.Abort(c, service.Validation(service.CodeInvalidParameter, "limit invalid format."))
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
+	if err != nil {
+		middleware.Abort(c, service.Validation(service.CodeInvalidParameter, "offset invalid format."))
+		return
+	}
+
+	getWebhookArgs := db.GetWebhookParams{
+		ID:     webhookID,
+		TeamID: teamID,
+	}
+	_, err = s.webhooksService.GetWebhook(c.Request.Context(), getWebhookArgs)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	args := db.ListWebhookDeliveriesParams{
+		WebhookID: webhookID,
+		Limit:     int32(limit),
+		Offset:    int32(offset),
+	}
+	deliveries, err := s.webhooksService.ListDeliveries(c.Request.Context(), args)
+	if err != nil {
+		middleware.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": deliveries})
+}
+
+// webhookParams parses the team and webhook IDs of the path.
+func webhookParams(c *gin.Context) (int64, int64, error) {
+	teamID, err := paramID(c, "team-id")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	webhookID, err := paramID(c, "webhook-id")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return teamID, webhookID, nil
+}
@@ -0,0 +1,115 @@
+// Package health checks whether the API is ready to serve requests.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/util"
+)
+
+// Check reports why a dependency of the API isn't ready, nil when it is.
+type Check func(ctx context.Context) error
+
+// Database checks that the database answers pings.
+func Database(conn *sql.DB) Check {
+	return func(ctx context.Context) error {
+		return errors.Wrap(conn.PingContext(ctx), "cannot ping the database")
+	}
+}
+
+// Migrations checks that the database is at the latest migration embedded
+// in the binary, and that none failed halfway.
+func Migrations(config util.Config, logger *logrus.Logger) (Check, error) {
+	latest, err := db.LatestMigration()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read the migrations")
+	}
+
+	checker := &migrationsChecker{config: config, logger: logger, latest: latest}
+	return checker.check, nil
+}
+
+// migrationsChecker reads the version of the database through the
+// golang-migrate driver, on a connection of its own since the driver holds
+// it until closed.
+type migrationsChecker struct {
+	config util.Config
+	logger *logrus.Logger
+	latest uint
+
+	mu     sync.Mutex
+	driver database.Driver
+}
+
+func (c *migrationsChecker) check(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.driver == nil {
+		driver, err := c.connect()
+		if err != nil {
+			return err
+		}
+		c.driver = driver
+	}
+
+	// The driver doesn't take a context.
+	type result struct {
+		version int
+		dirty   bool
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		version, dirty, err := c.driver.Version()
+		done <- result{version, dirty, err}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-ctx.Done():
+		// The driver's connection may be stuck, use a new one next time.
+		go c.driver.Close()
+		c.driver = nil
+		return errors.Wrap(ctx.Err(), "cannot read the migration version")
+	}
+
+	switch {
+	case r.err != nil:
+		c.driver.Close()
+		c.driver = nil
+		return errors.Wrap(r.err, "cannot read the migration version")
+	case r.dirty:
+		return errors.Errorf("migration %d failed halfway", r.version)
+	case r.version == database.NilVersion:
+		return errors.Errorf("no migrations applied, want %d", c.latest)
+	case uint(r.version) != c.latest:
+		return errors.Errorf("database at migration %d, want %d", r.version, c.latest)
+	}
+	return nil
+}
+
+// connect opens the connection the driver holds, closed along with it.
+func (c *migrationsChecker) connect() (database.Driver, error) {
+	conn, err := db.Connect(c.config, c.logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot connect to the database")
+	}
+	conn.SetMaxOpenConns(1)
+
+	driver, err := mysql.WithInstance(conn, &mysql.Config{})
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "cannot connect to the database")
+	}
+	return driver, nil
+}
@@ -0,0 +1,152 @@
+// Package ical writes iCalendar (RFC 5545) feeds calendar clients can
+// subscribe to.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultProdID identifies the program that created the calendars.
+const DefaultProdID = "-//ezerw//wheel//EN"
+
+// ContentType is the media type of iCalendar documents.
+const ContentType = "text/calendar; charset=utf-8"
+
+// maxLineLength is the length lines are folded at, in octets, without the
+// CRLF.
+const maxLineLength = 75
+
+// Calendar is a VCALENDAR of all-day events.
+type Calendar struct {
+	ProdID   string
+	Name     string
+	Timezone string
+	// RefreshInterval suggests how often clients should poll the feed.
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Event is an all-day VEVENT.
+type Event struct {
+	// UID must stay the same across versions of the feed for clients to
+	// update the event instead of duplicating it.
+	UID string
+	// Date is the day of the event, in the timezone of the calendar.
+	Date        time.Time
+	Summary     string
+	Description string
+	// Stamp is when the event was last modified.
+	Stamp time.Time
+}
+
+// Encode writes the calendar to w.
+func (c Calendar) Encode(w io.Writer) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+
+	prodID := c.ProdID
+	if prodID == "" {
+		prodID = DefaultProdID
+	}
+
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", prodID)
+	e.line("CALSCALE", "GREGORIAN")
+	e.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		e.line("X-WR-CALNAME", escape(c.Name))
+		e.line("NAME", escape(c.Name))
+	}
+	if c.Timezone != "" {
+		e.line("X-WR-TIMEZONE", c.Timezone)
+	}
+	if c.RefreshInterval > 0 {
+		e.line("REFRESH-INTERVAL;VALUE=DURATION", duration(c.RefreshInterval))
+		e.line("X-PUBLISHED-TTL", duration(c.RefreshInterval))
+	}
+
+	for _, event := range c.Events {
+		year, month, day := event.Date.Date()
+		start := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		stamp := event.Stamp.UTC().Format("20060102T150405Z")
+
+		e.line("BEGIN", "VEVENT")
+		e.line("UID", event.UID)
+		e.line("DTSTAMP", stamp)
+		e.line("LAST-MODIFIED", stamp)
+		e.line("DTSTART;VALUE=DATE", start.Format("20060102"))
+		e.line("DTEND;VALUE=DATE", start.AddDate(0, 0, 1).Format("20060102"))
+		e.line("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			e.line("DESCRIPTION", escape(event.Description))
+		}
+		e.line("TRANSP", "TRANSPARENT")
+		e.line("END", "VEVENT")
+	}
+
+	e.line("END", "VCALENDAR")
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// encoder writes content lines, remembering the first error.
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes a content line folded at 75 octets, never splitting a UTF-8
+// sequence.
+func (e *encoder) line(name string, value string) {
+	if e.err != nil {
+		return
+	}
+
+	line := name + ":" + value
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		e.write(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Continuation lines start with the space.
+		limit = maxLineLength - 1
+	}
+	e.write(line + "\r\n")
+}
+
+func (e *encoder) write(s string) {
+	if e.err == nil {
+		_, e.err = e.w.WriteString(s)
+	}
+}
+
+// escape escapes a TEXT value.
+var escape = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+).Replace
+
+// duration formats d as a DURATION value, in whole minutes.
+func duration(d time.Duration) string {
+	minutes := int(d / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	if minutes%60 == 0 {
+		return "PT" + strconv.Itoa(minutes/60) + "H"
+	}
+	return "PT" + strconv.Itoa(minutes) + "M"
+}
@@ -0,0 +1,60 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	auckland, err := time.LoadLocation("Pacific/Auckland")
+	if err != nil {
+		t.Fatalf("cannot load location: %v", err)
+	}
+
+	calendar := Calendar{
+		Name:            "Trading, the rota",
+		Timezone:        "Pacific/Auckland",
+		RefreshInterval: time.Hour,
+		Events: []Event{{
+			UID:         "turn-1@wheel",
+			Date:        time.Date(2021, time.July, 13, 0, 0, 0, 0, auckland),
+			Summary:     "Bruce Wayne hosts Trading; again",
+			Description: strings.Repeat("é", 40),
+			Stamp:       time.Date(2021, time.July, 12, 9, 30, 0, 0, auckland),
+		}},
+	}
+
+	var b strings.Builder
+	if err := calendar.Encode(&b); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:" + DefaultProdID + "\r\n",
+		"X-WR-CALNAME:Trading\\, the rota\r\n",
+		"X-WR-TIMEZONE:Pacific/Auckland\r\n",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H\r\n",
+		"UID:turn-1@wheel\r\n",
+		// The stamp is in UTC, the date stays the local one.
+		"DTSTAMP:20210711T213000Z\r\n",
+		"DTSTART;VALUE=DATE:20210713\r\nDTEND;VALUE=DATE:20210714\r\n",
+		"SUMMARY:Bruce Wayne hosts Trading\\; again\r\n",
+		"END:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("calendar doesn't contain %q:\n%s", want, out)
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("line %q is %d octets long, want at most %d", line, len(line), maxLineLength)
+		}
+	}
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	if !strings.Contains(unfolded, "DESCRIPTION:"+strings.Repeat("é", 40)+"\r\n") {
+		t.Errorf("folded description doesn't unfold to the original:\n%s", out)
+	}
+}
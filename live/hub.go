@@ -0,0 +1,152 @@
+// Package live fans the events of the teams out to the clients following
+// them as they happen.
+package live
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ezerw/wheel/event"
+)
+
+// Events kept per team unless the hub is given a history, and events a
+// subscriber can fall behind by.
+const (
+	defaultHistory = 100
+	defaultBuffer  = 16
+)
+
+// Hub is an in-process pub/sub of the events of each team. It keeps the
+// latest events of every team so clients can resume after reconnecting.
+type Hub struct {
+	history int
+	buffer  int
+
+	mu            sync.Mutex
+	recent        map[int64][]event.Event
+	subscriptions map[int64]map[*Subscription]struct{}
+	closed        bool
+}
+
+// Subscription receives the events of a team.
+type Subscription struct {
+	hub    *Hub
+	teamID int64
+	events chan event.Event
+	closed bool
+}
+
+// NewHub creates a Hub keeping the latest history events of every team, 100
+// when history isn't positive.
+func NewHub(history int) *Hub {
+	if history <= 0 {
+		history = defaultHistory
+	}
+	return &Hub{
+		history:       history,
+		buffer:        defaultBuffer,
+		recent:        map[int64][]event.Event{},
+		subscriptions: map[int64]map[*Subscription]struct{}{},
+	}
+}
+
+// Publish sends e to the subscribers of its team. Subscribers too slow to
+// keep up are dropped rather than blocking the publisher, they resume from
+// the history when they reconnect.
+func (h *Hub) Publish(_ context.Context, e event.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	recent := append(h.recent[e.TeamID], e)
+	if len(recent) > h.history {
+		recent = recent[len(recent)-h.history:]
+	}
+	h.recent[e.TeamID] = recent
+
+	for sub := range h.subscriptions[e.TeamID] {
+		select {
+		case sub.events <- e:
+		default:
+			h.remove(sub)
+		}
+	}
+
+	// Nothing else happens to deleted teams.
+	if e.Type == event.TeamDeleted {
+		delete(h.recent, e.TeamID)
+	}
+}
+
+// Subscribe follows the events of a team. With a lastEventID it also returns
+// the events published after it, ok is false when that event is no longer
+// in the history and some events may have been missed.
+func (h *Hub) Subscribe(teamID int64, lastEventID string) (sub *Subscription, missed []event.Event, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub = &Subscription{
+		hub:    h,
+		teamID: teamID,
+		events: make(chan event.Event, h.buffer),
+	}
+	if h.subscriptions[teamID] == nil {
+		h.subscriptions[teamID] = map[*Subscription]struct{}{}
+	}
+	h.subscriptions[teamID][sub] = struct{}{}
+	if h.closed {
+		h.remove(sub)
+		return sub, nil, true
+	}
+
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+	recent := h.recent[teamID]
+	for i := len(recent) - 1; i >= 0; i-- {
+		if recent[i].ID == lastEventID {
+			return sub, append([]event.Event(nil), recent[i+1:]...), true
+		}
+	}
+	return sub, nil, false
+}
+
+// Close ends every subscription, and those made afterwards right away, so
+// the streams following them end.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, subs := range h.subscriptions {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+}
+
+// Events returns the channel the events are sent to, it's closed when the
+// subscription is.
+func (s *Subscription) Events() <-chan event.Event {
+	return s.events
+}
+
+// Close stops receiving events.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// remove unsubscribes sub, h.mu must be held.
+func (h *Hub) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.events)
+
+	delete(h.subscriptions[sub.teamID], sub)
+	if len(h.subscriptions[sub.teamID]) == 0 {
+		delete(h.subscriptions, sub.teamID)
+	}
+}
@@ -0,0 +1,60 @@
+package live
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ezerw/wheel/event"
+)
+
+func TestHub(t *testing.T) {
+	hub := NewHub(2)
+	publish := func(teamID int64) event.Event {
+		e, err := event.New(event.TurnAssigned, teamID, nil)
+		if err != nil {
+			t.Fatalf("cannot create event: %v", err)
+		}
+		hub.Publish(context.Background(), e)
+		return e
+	}
+
+	sub, _, _ := hub.Subscribe(1, "")
+	first := publish(1)
+	publish(2)
+	if e := <-sub.Events(); e.ID != first.ID {
+		t.Errorf("received %s, want the event of the team", e.ID)
+	}
+
+	second, third := publish(1), publish(1)
+	<-sub.Events()
+	<-sub.Events()
+	sub.Close()
+	if _, open := <-sub.Events(); open {
+		t.Errorf("events channel is open after Close")
+	}
+
+	// Resuming returns what was published after the last event received.
+	sub, missed, ok := hub.Subscribe(1, second.ID)
+	if !ok || len(missed) != 1 || missed[0].ID != third.ID {
+		t.Errorf("resuming after %s missed %v (ok %v), want %s", second.ID, missed, ok, third.ID)
+	}
+	sub.Close()
+
+	// The first event is no longer in the history of 2.
+	sub, missed, ok = hub.Subscribe(1, first.ID)
+	if ok || len(missed) != 0 {
+		t.Errorf("resuming after an evicted event = %v, %v, want not ok", missed, ok)
+	}
+
+	// Subscribers which don't keep up are dropped instead of blocking.
+	for i := 0; i <= defaultBuffer; i++ {
+		publish(1)
+	}
+	received := 0
+	for range sub.Events() {
+		received++
+	}
+	if received != defaultBuffer {
+		t.Errorf("slow subscriber received %d events, want %d before being dropped", received, defaultBuffer)
+	}
+}
@@ -0,0 +1,121 @@
+// Package metrics exposes what the API does to Prometheus.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/ezerw/wheel/event"
+)
+
+// namespace prefixes the names of the metrics.
+const namespace = "wheel"
+
+// unmatchedRoute labels the requests no route matched, so unknown paths
+// don't create new series.
+const unmatchedRoute = "unmatched"
+
+// Metrics holds the metrics of the API in its own registry.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	turnsAssigned   *prometheus.CounterVec
+	turnsReassigned *prometheus.CounterVec
+}
+
+// New creates the metrics, along with the Go runtime and process ones.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests handled, by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests, by method and route template.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		turnsAssigned: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "turns_assigned_total",
+			Help:      "Turns assigned, by team.",
+		}, []string{"team_id"}),
+		turnsReassigned: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "turns_reassigned_total",
+			Help:      "Turns reassigned to someone else, by team.",
+		}, []string{"team_id"}),
+	}
+
+	m.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.turnsAssigned,
+		m.turnsReassigned,
+	)
+	return m
+}
+
+// RegisterDB adds the connection pool stats of db to the metrics.
+func (m *Metrics) RegisterDB(db StatsGetter) {
+	m.registry.MustRegister(newDBCollector(db))
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware counts and times the requests by the template of the route
+// they matched, e.g. /api/teams/:team-id, rather than by path.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		m.requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.requestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handle counts the turns assigned and reassigned, it's meant to be
+// subscribed to the event bus.
+func (m *Metrics) Handle(_ context.Context, e event.Event) {
+	teamID := strconv.FormatInt(e.TeamID, 10)
+	switch e.Type {
+	case event.TurnAssigned:
+		m.turnsAssigned.WithLabelValues(teamID).Inc()
+	case event.TurnReassigned:
+		m.turnsReassigned.WithLabelValues(teamID).Inc()
+	case event.TeamDeleted:
+		m.turnsAssigned.DeleteLabelValues(teamID)
+		m.turnsReassigned.DeleteLabelValues(teamID)
+	}
+}
+
+// StatsGetter is anything with database connection pool stats, such as
+// *sql.DB.
+type StatsGetter interface {
+	Stats() sql.DBStats
+}
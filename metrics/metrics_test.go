@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ezerw/wheel/event"
+)
+
+type fakeDB struct{}
+
+func (fakeDB) Stats() sql.DBStats {
+	return sql.DBStats{MaxOpenConnections: 10, OpenConnections: 3, InUse: 1, Idle: 2}
+}
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+	m.RegisterDB(fakeDB{})
+
+	r := gin.New()
+	r.Use(m.Middleware())
+	r.GET("/api/teams/:team-id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/metrics", gin.WrapH(m.Handler()))
+
+	for _, path := range []string{"/api/teams/1", "/api/teams/2", "/unknown"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	for _, eventType := range []event.Type{event.TurnAssigned, event.TurnAssigned, event.TurnReassigned, event.PersonAdded} {
+		e, err := event.New(eventType, 1, nil)
+		if err != nil {
+			t.Fatalf("cannot create event: %v", err)
+		}
+		m.Handle(context.Background(), e)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := ioutil.ReadAll(rec.Body)
+	out := string(body)
+
+	for _, want := range []string{
+		`wheel_http_requests_total{method="GET",route="/api/teams/:team-id",status="200"} 2`,
+		`wheel_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`wheel_http_request_duration_seconds_count{method="GET",route="/api/teams/:team-id"} 2`,
+		`wheel_turns_assigned_total{team_id="1"} 2`,
+		`wheel_turns_reassigned_total{team_id="1"} 1`,
+		`wheel_db_open_connections 3`,
+		`wheel_db_in_use_connections 1`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics don't contain %q", want)
+		}
+	}
+}
@@ -2,25 +2,25 @@ package middleware
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/pkg/errors"
+
+	"github.com/ezerw/wheel/service"
 )
 
 func Authenticated() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("Auth header not present"))
+			Abort(c, service.Unauthorized(service.CodeUnauthorized, "Auth header not present."))
 			return
 		}
 
 		authContent := strings.Split(authHeader, "Bearer ")
 		if len(authContent) != 2 {
-			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("Bearer token not properly formatted"))
+			Abort(c, service.Unauthorized(service.CodeUnauthorized, "Bearer token not properly formatted."))
 			return
 		}
 
@@ -31,11 +31,13 @@ func Authenticated() gin.HandlerFunc {
 
 		res, err := http.Get(url)
 		if err != nil {
-			log.Fatalln(err)
+			Abort(c, err)
+			return
 		}
+		defer res.Body.Close()
 
 		if res.StatusCode != http.StatusOK {
-			_ = c.AbortWithError(http.StatusUnauthorized, errors.New("Invalid token"))
+			Abort(c, service.Unauthorized(service.CodeUnauthorized, "Invalid token."))
 			return
 		}
 
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ezerw/wheel/service"
+	"github.com/ezerw/wheel/util"
+)
+
+// defaultMaxBodyBytes is the size limit of request bodies unless
+// MAX_BODY_BYTES is set.
+const defaultMaxBodyBytes = 1 << 20
+
+// BodyLimit rejects the requests whose body is over MAX_BODY_BYTES with a
+// 413, up front when they declare their length and otherwise once the
+// handler reads past the limit, getting a KindTooLarge error to return.
+func BodyLimit(config util.Config) gin.HandlerFunc {
+	limit := config.MaxBodyBytes
+	if limit <= 0 {
+		limit = defaultMaxBodyBytes
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			Abort(c, bodyTooLarge(limit))
+			return
+		}
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			c.Request.Body = &limitedBody{ReadCloser: c.Request.Body, remaining: limit, err: bodyTooLarge(limit)}
+		}
+		c.Next()
+	}
+}
+
+// bodyTooLarge returns the error of a request body over limit bytes.
+func bodyTooLarge(limit int64) *service.Error {
+	return service.TooLarge(
+		service.CodeBodyTooLarge,
+		fmt.Sprintf("Request body must be at most %d bytes.", limit),
+	)
+}
+
+// limitedBody fails with err once read past its limit.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	err       error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, b.err
+	}
+	// Read one byte more than allowed to tell a body at the limit from one
+	// over it.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), b.err
+	}
+	return n, err
+}
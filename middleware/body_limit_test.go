@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ezerw/wheel/util"
+)
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Errors(), BodyLimit(util.Config{MaxBodyBytes: 8}))
+	r.POST("/teams", func(c *gin.Context) {
+		if _, err := ioutil.ReadAll(c.Request.Body); err != nil {
+			Abort(c, err)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	for _, tc := range []struct {
+		body    string
+		chunked bool
+		want    int
+	}{
+		{body: "12345678", want: http.StatusCreated},
+		{body: "123456789", want: http.StatusRequestEntityTooLarge},
+		{body: "12345678", chunked: true, want: http.StatusCreated},
+		{body: "123456789", chunked: true, want: http.StatusRequestEntityTooLarge},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/teams", strings.NewReader(tc.body))
+		if tc.chunked {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != tc.want {
+			t.Errorf("got status %d for %d bytes (chunked %v), want %d", rec.Code, len(tc.body), tc.chunked, tc.want)
+		}
+	}
+}
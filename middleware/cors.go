@@ -1,19 +1,127 @@
 package middleware
 
 import (
+	"net/http"
+	"strings"
 	"time"
-	
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+
+	"github.com/ezerw/wheel/util"
 )
 
-func Cors() gin.HandlerFunc {
-	return cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000", "https://wheel.ezerw.com"},
-		AllowMethods:     []string{"*"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	})
+// Defaults of the CORS policy unless set in the config.
+var (
+	defaultCorsOrigins = []string{"http://localhost:3000", "https://wheel.ezerw.com"}
+	defaultCorsMethods = []string{
+		http.MethodGet,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+	}
+	defaultCorsHeaders = []string{
+		"Origin",
+		"Content-Type",
+		"Authorization",
+		"If-Match",
+		"If-None-Match",
+		RequestIDHeader,
+	}
+)
+
+const defaultCorsMaxAge = 12 * time.Hour
+
+// Cors lets the web apps allowed by CORS_ALLOWED_ORIGINS call the API from
+// a browser, with the methods and headers of CORS_ALLOWED_METHODS and
+// CORS_ALLOWED_HEADERS. Browsers cache the preflight responses for
+// CORS_MAX_AGE. A wildcard origin lets any site call the API, so it's
+// without credentials.
+func Cors(config util.Config) gin.HandlerFunc {
+	maxAge := config.CorsMaxAge
+	if maxAge <= 0 {
+		maxAge = defaultCorsMaxAge
+	}
+
+	policy := cors.Config{
+		AllowMethods:  listOr(config.CorsAllowedMethods, defaultCorsMethods),
+		AllowHeaders:  listOr(config.CorsAllowedHeaders, defaultCorsHeaders),
+		ExposeHeaders: []string{"Content-Length", "ETag", "Retry-After", RequestIDHeader},
+		MaxAge:        maxAge,
+	}
+	origins := CorsOrigins(config)
+	if origins.Any() {
+		policy.AllowAllOrigins = true
+	} else {
+		policy.AllowOriginFunc = origins.Allowed
+		policy.AllowCredentials = true
+	}
+	return cors.New(policy)
+}
+
+// Origins are the origins of the web apps allowed to call the API from a
+// browser. An origin may start its host with a wildcard, such as
+// https://*.example.com standing for any subdomain of example.com, and *
+// alone stands for any origin, but only for the requests without
+// credentials.
+type Origins []string
+
+// CorsOrigins returns the origins of CORS_ALLOWED_ORIGINS, or the default
+// ones when unset.
+func CorsOrigins(config util.Config) Origins {
+	return listOr(config.CorsAllowedOrigins, defaultCorsOrigins)
+}
+
+// Any reports whether the origins include *, any origin.
+func (o Origins) Any() bool {
+	for _, allowed := range o {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed reports whether origin is one of the allowed origins. * doesn't
+// count, it only lets the requests without credentials through.
+func (o Origins) Allowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range o {
+		if matchOrigin(strings.ToLower(allowed), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin reports whether origin matches pattern, whose wildcard stands
+// for one or more subdomain labels.
+func matchOrigin(pattern string, origin string) bool {
+	prefix, suffix, wildcard := strings.Cut(pattern, "*")
+	if !wildcard {
+		return origin == pattern
+	}
+	if len(origin) <= len(prefix)+len(suffix) ||
+		!strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	subdomain := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(subdomain, "/:@") &&
+		!strings.HasPrefix(subdomain, ".") && !strings.HasSuffix(subdomain, ".")
+}
+
+// listOr returns the non-blank values of list, or def when there are none.
+// Lists from the environment are split on commas only.
+func listOr(list []string, def []string) []string {
+	var values []string
+	for _, value := range list {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return def
+	}
+	return values
 }
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ezerw/wheel/util"
+)
+
+func TestOriginsAllowed(t *testing.T) {
+	origins := Origins{"https://wheel.example.com", "https://*.staging.example.com"}
+
+	for origin, want := range map[string]bool{
+		"https://wheel.example.com":             true,
+		"https://WHEEL.example.com":             true,
+		"http://wheel.example.com":              false,
+		"https://pr-7.staging.example.com":      true,
+		"https://a.b.staging.example.com":       true,
+		"https://staging.example.com":           false,
+		"https://.staging.example.com":          false,
+		"https://evil.com/.staging.example.com": false,
+		"https://evil.com:.staging.example.com": false,
+		"https://evilstaging.example.com":       false,
+	} {
+		if got := origins.Allowed(origin); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", origin, got, want)
+		}
+	}
+	if (Origins{"*"}).Allowed("https://anywhere.example.org") {
+		t.Error("* allows every origin with credentials")
+	}
+}
+
+func TestCors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Cors(util.Config{
+		CorsAllowedOrigins: []string{"https://*.example.com"},
+		CorsAllowedMethods: []string{"GET", " DELETE"},
+	}))
+	r.DELETE("/teams/1", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodOptions, "/teams/1", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "DELETE")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("got allowed origin %q, want the caller's", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET,DELETE" {
+		t.Errorf("got allowed methods %q, want GET,DELETE", got)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "43200" {
+		t.Errorf("got max age %q, want the default 12h", got)
+	}
+
+	req.Header.Set("Origin", "https://app.example.org")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("got status %d for another origin, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestCorsWildcard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Cors(util.Config{CorsAllowedOrigins: []string{"*"}}))
+	r.GET("/teams", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/teams", nil)
+	req.Header.Set("Origin", "https://anywhere.example.org")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("got allowed origin %q, want *", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("got allowed credentials %q, want none for a wildcard", got)
+	}
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+
+	"github.com/ezerw/wheel/service"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body extended with a stable
+// machine-readable code.
+type Problem struct {
+	Type     string               `json:"type"`
+	Title    string               `json:"title"`
+	Status   int                  `json:"status"`
+	Detail   string               `json:"detail,omitempty"`
+	Instance string               `json:"instance,omitempty"`
+	Code     string               `json:"code"`
+	Errors   []service.FieldError `json:"errors,omitempty"`
+	Current  interface{}          `json:"current,omitempty"`
+}
+
+// Errors renders the last error attached to the context with c.Error as a
+// problem+json response, unless a response has already been written.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		problem := NewProblem(c.Errors.Last().Err)
+		problem.Instance = c.Request.URL.Path
+
+		c.Header("Content-Type", ProblemContentType)
+		c.JSON(problem.Status, problem)
+	}
+}
+
+// NewProblem maps an error to its problem details. Errors that are not
+// domain errors are reported as internal errors without exposing details.
+func NewProblem(err error) Problem {
+	var domainErr *service.Error
+	if !errors.As(err, &domainErr) || domainErr.Kind == service.KindInternal {
+		return Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusInternalServerError),
+			Status: http.StatusInternalServerError,
+			Detail: "An unexpected error occurred.",
+			Code:   service.CodeInternal,
+		}
+	}
+
+	status := statusOf(domainErr.Kind)
+	return Problem{
+		Type:    "about:blank",
+		Title:   http.StatusText(status),
+		Status:  status,
+		Detail:  domainErr.Message,
+		Code:    domainErr.Code,
+		Errors:  domainErr.Fields,
+		Current: domainErr.Current,
+	}
+}
+
+// statusOf maps a domain error kind to its HTTP status code.
+func statusOf(kind service.Kind) int {
+	switch kind {
+	case service.KindNotFound:
+		return http.StatusNotFound
+	case service.KindConflict:
+		return http.StatusConflict
+	case service.KindValidation:
+		return http.StatusBadRequest
+	case service.KindUnauthorized:
+		return http.StatusUnauthorized
+	case service.KindForbidden:
+		return http.StatusForbidden
+	case service.KindRateLimited:
+		return http.StatusTooManyRequests
+	case service.KindTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case service.KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case service.KindPreconditionRequired:
+		return http.StatusPreconditionRequired
+	case service.KindUnprocessable:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Abort records err for the Errors middleware and stops the handler chain.
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ezerw/wheel/util"
+)
+
+// RequestIDHeader carries the ID of a request, it's taken from the request
+// when the caller sets it and always sent back.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the request IDs taken from callers.
+const maxRequestIDLength = 128
+
+// Logger logs every request once handled, but those to skipPaths, and
+// attaches a logger with the request ID, and the trace ID when traced, to
+// the request context for the handlers and services, see util.LoggerFrom.
+func Logger(logger *logrus.Logger, skipPaths ...string) gin.HandlerFunc {
+	skip := map[string]bool{}
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		entry := logger.WithField("request_id", requestID)
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+			entry = entry.WithField("trace_id", span.TraceID().String())
+		}
+		c.Request = c.Request.WithContext(util.WithLogger(c.Request.Context(), entry))
+
+		c.Next()
+		if skip[c.Request.URL.Path] {
+			return
+		}
+
+		status := c.Writer.Status()
+		fields := logrus.Fields{
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"route":      c.FullPath(),
+			"status":     status,
+			"latency_ms": time.Since(start).Milliseconds(),
+			"bytes":      c.Writer.Size(),
+			"client_ip":  c.ClientIP(),
+			"user_agent": c.Request.UserAgent(),
+		}
+		if teamID, err := strconv.ParseInt(c.Param("team-id"), 10, 64); err == nil {
+			fields["team_id"] = teamID
+		}
+		entry = entry.WithFields(fields)
+		if len(c.Errors) > 0 {
+			entry = entry.WithError(c.Errors.Last().Err)
+		}
+
+		switch {
+		case status >= 500:
+			entry.Error("request failed")
+		case status >= 400:
+			entry.Warn("request rejected")
+		default:
+			entry.Info("request handled")
+		}
+	}
+}
+
+// validRequestID reports whether id can be used as the ID of a request, it
+// ends up in logs so only short IDs of URL-safe characters are accepted.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random request ID.
+func newRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+
+	"github.com/ezerw/wheel/util"
+)
+
+func TestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, hook := test.NewNullLogger()
+
+	r := gin.New()
+	r.Use(Logger(logger))
+	r.GET("/api/teams/:team-id", func(c *gin.Context) {
+		util.LoggerFrom(c.Request.Context()).Info("handling")
+		c.Status(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/teams/7", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(RequestIDHeader); got != "abc-123" {
+		t.Errorf("got request ID %q, want the caller's", got)
+	}
+	if len(hook.Entries) != 2 {
+		t.Fatalf("got %d log entries, want 2", len(hook.Entries))
+	}
+	if got := hook.Entries[0].Data["request_id"]; got != "abc-123" {
+		t.Errorf("handler logged with request ID %v, want abc-123", got)
+	}
+	access := hook.LastEntry()
+	if access.Level != logrus.WarnLevel {
+		t.Errorf("got level %s for a 404, want warning", access.Level)
+	}
+	for field, want := range map[string]interface{}{
+		"request_id": "abc-123",
+		"route":      "/api/teams/:team-id",
+		"status":     http.StatusNotFound,
+		"team_id":    int64(7),
+	} {
+		if got := access.Data[field]; got != want {
+			t.Errorf("got %s %v, want %v", field, got, want)
+		}
+	}
+
+	// IDs unfit for the logs are replaced.
+	req = httptest.NewRequest(http.MethodGet, "/api/teams/7", nil)
+	req.Header.Set(RequestIDHeader, "bad id\n")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if got := rec.Header().Get(RequestIDHeader); got == "" || got == "bad id\n" {
+		t.Errorf("got request ID %q, want a new one", got)
+	}
+}
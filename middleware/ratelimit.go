@@ -0,0 +1,225 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ezerw/wheel/service"
+	"github.com/ezerw/wheel/util"
+)
+
+// Defaults of the rate limits unless set in the config, in requests per
+// minute and requests in a burst.
+const (
+	defaultReadsPerMinute  = 300
+	defaultReadBurst       = 60
+	defaultWritesPerMinute = 30
+	defaultWriteBurst      = 10
+)
+
+// sweepInterval is how often the buckets of the clients gone quiet are
+// dropped.
+const sweepInterval = time.Minute
+
+// maxBuckets caps the buckets kept in memory. Once reached, the new clients
+// share the overflow bucket until the quiet ones are swept.
+const (
+	maxBuckets     = 100000
+	overflowClient = "overflow"
+)
+
+// Rate is the budget of requests of a client: a bucket of Burst tokens
+// refilled with PerMinute tokens a minute, each request taking one.
+type Rate struct {
+	PerMinute int
+	Burst     int
+}
+
+// newRate returns the rate of the config values, or of the defaults when
+// unset. It's nil when perMinute is negative, for no limit.
+func newRate(perMinute int, burst int, defaultPerMinute int, defaultBurst int) *Rate {
+	if perMinute < 0 {
+		return nil
+	}
+	if perMinute == 0 {
+		perMinute = defaultPerMinute
+	}
+	if burst <= 0 {
+		burst = defaultBurst
+	}
+	return &Rate{PerMinute: perMinute, Burst: burst}
+}
+
+// bucket is the token bucket of a client for a rate.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// bucketKey identifies the bucket of a client for reads or writes.
+type bucketKey struct {
+	client string
+	write  bool
+}
+
+// RateLimiter limits the requests of each client, identified by its IP, with
+// separate budgets for reads and writes.
+type RateLimiter struct {
+	reads      *Rate
+	writes     *Rate
+	proxies    []*net.IPNet
+	maxBuckets int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+	swept   time.Time
+}
+
+// NewRateLimiter creates a limiter with the budgets of RATE_LIMIT_READS and
+// RATE_LIMIT_READ_BURST, and RATE_LIMIT_WRITES and RATE_LIMIT_WRITE_BURST.
+// Clients are identified by the address of their connection, or by
+// X-Forwarded-For when it comes from one of TRUSTED_PROXIES.
+func NewRateLimiter(config util.Config) *RateLimiter {
+	// Validated with the config.
+	proxies, _ := util.ParseNetworks(config.TrustedProxies)
+	return &RateLimiter{
+		reads:      newRate(config.RateLimitReads, config.RateLimitReadBurst, defaultReadsPerMinute, defaultReadBurst),
+		writes:     newRate(config.RateLimitWrites, config.RateLimitWriteBurst, defaultWritesPerMinute, defaultWriteBurst),
+		proxies:    proxies,
+		maxBuckets: maxBuckets,
+		now:        time.Now,
+		buckets:    make(map[bucketKey]*bucket),
+	}
+}
+
+// Middleware rejects the requests over the budget of their client with a
+// 429 telling in Retry-After when to try again.
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		write := c.Request.Method != http.MethodGet &&
+			c.Request.Method != http.MethodHead &&
+			c.Request.Method != http.MethodOptions
+
+		wait := l.take(bucketKey{client: "ip:" + l.clientIP(c.Request), write: write})
+		if wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			Abort(c, service.RateLimited(service.CodeRateLimited, "Too many requests, try again later."))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Take takes a token from the write budget of client, for the requests
+// limited by something else than their IP. It returns how long to wait for
+// one when it's empty.
+func (l *RateLimiter) Take(client string) time.Duration {
+	return l.take(bucketKey{client: client, write: true})
+}
+
+// take takes a token from the bucket of key, returning how long to wait for
+// one when it's empty.
+func (l *RateLimiter) take(key bucketKey) time.Duration {
+	rate := l.reads
+	if key.write {
+		rate = l.writes
+	}
+	if rate == nil {
+		return 0
+	}
+	perSecond := float64(rate.PerMinute) / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.swept) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok && len(l.buckets) >= l.maxBuckets {
+		l.sweep(now)
+		if len(l.buckets) >= l.maxBuckets {
+			key.client = overflowClient
+			b, ok = l.buckets[key]
+		}
+	}
+	if !ok {
+		b = &bucket{tokens: float64(rate.Burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(rate.Burst), b.tokens+now.Sub(b.updated).Seconds()*perSecond)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
+
+// sweep drops the buckets refilled by now, the same as new ones.
+func (l *RateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		rate := l.reads
+		if key.write {
+			rate = l.writes
+		}
+		full := time.Duration(float64(rate.Burst) / (float64(rate.PerMinute) / 60) * float64(time.Second))
+		if now.Sub(b.updated) >= full {
+			delete(l.buckets, key)
+		}
+	}
+	l.swept = now
+}
+
+// clientIP is the IP of the client of r. It's the address of the
+// connection unless that's a trusted proxy, in which case it's the last
+// address of X-Forwarded-For not belonging to one. Bearer tokens aren't
+// verified yet, so keying by them would hand a fresh budget to every made-up
+// token.
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !l.trusted(ip) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !l.trusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// trusted reports whether ip is one of the trusted proxies.
+func (l *RateLimiter) trusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, proxy := range l.proxies {
+		if proxy.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
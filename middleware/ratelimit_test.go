@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ezerw/wheel/util"
+)
+
+func TestRateLimiter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2021, time.July, 12, 9, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(util.Config{RateLimitWrites: 6, RateLimitWriteBurst: 2})
+	limiter.now = func() time.Time { return now }
+
+	r := gin.New()
+	r.Use(Errors(), limiter.Middleware())
+	r.GET("/turns", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/turns", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	send := func(method string, ip string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/turns", nil)
+		req.RemoteAddr = ip + ":40000"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := send(http.MethodPost, "10.0.0.1", "alice"); rec.Code != http.StatusCreated {
+			t.Fatalf("write %d got status %d, want it within the burst", i+1, rec.Code)
+		}
+	}
+	rec := send(http.MethodPost, "10.0.0.1", "alice")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("got status %d over the burst, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	// 6 writes a minute refill a token every 10s.
+	if got := rec.Header().Get("Retry-After"); got != "10" {
+		t.Errorf("got Retry-After %q, want 10", got)
+	}
+
+	if rec := send(http.MethodGet, "10.0.0.1", "alice"); rec.Code != http.StatusOK {
+		t.Errorf("got status %d reading, want reads to have their own budget", rec.Code)
+	}
+	// Tokens aren't verified, so a new one mustn't reset the budget.
+	for _, token := range []string{"bob", ""} {
+		if rec := send(http.MethodPost, "10.0.0.1", token); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("got status %d with token %q, want the budget of the IP", rec.Code, token)
+		}
+	}
+	// Nor may a made-up X-Forwarded-For, the client isn't a trusted proxy.
+	req := httptest.NewRequest(http.MethodPost, "/turns", nil)
+	req.RemoteAddr = "10.0.0.1:40000"
+	req.Header.Set("X-Forwarded-For", "10.0.0.3")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("got status %d forwarded for another IP, want the budget of the connection", rec.Code)
+	}
+	if rec := send(http.MethodPost, "10.0.0.2", "alice"); rec.Code != http.StatusCreated {
+		t.Errorf("got status %d for another IP, want its own budget", rec.Code)
+	}
+
+	now = now.Add(10 * time.Second)
+	if rec := send(http.MethodPost, "10.0.0.1", "alice"); rec.Code != http.StatusCreated {
+		t.Errorf("got status %d once refilled, want %d", rec.Code, http.StatusCreated)
+	}
+}
+
+func TestRateLimiterClientIP(t *testing.T) {
+	limiter := NewRateLimiter(util.Config{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}})
+
+	tests := []struct {
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"203.0.113.7:40000", "198.51.100.1", "203.0.113.7"},
+		{"10.0.0.5:40000", "198.51.100.1", "198.51.100.1"},
+		{"10.0.0.5:40000", "198.51.100.9, 198.51.100.1, 192.168.1.1", "198.51.100.1"},
+		{"10.0.0.5:40000", "10.1.1.1", "10.1.1.1"},
+		{"10.0.0.5:40000", "", "10.0.0.5"},
+		{"10.0.0.5:40000", "unknown", "10.0.0.5"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/turns", nil)
+		req.RemoteAddr = test.remoteAddr
+		if test.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+		if got := limiter.clientIP(req); got != test.want {
+			t.Errorf("clientIP from %s forwarding %q = %s, want %s", test.remoteAddr, test.forwarded, got, test.want)
+		}
+	}
+}
+
+func TestRateLimiterCapsBuckets(t *testing.T) {
+	limiter := NewRateLimiter(util.Config{RateLimitWrites: 6, RateLimitWriteBurst: 1})
+	limiter.maxBuckets = 2
+
+	for _, client := range []string{"a", "b"} {
+		if wait := limiter.Take(client); wait > 0 {
+			t.Fatalf("Take(%s) waits %v, want a fresh budget", client, wait)
+		}
+	}
+	if wait := limiter.Take("c"); wait > 0 {
+		t.Fatalf("Take(c) waits %v, want the overflow budget", wait)
+	}
+	if wait := limiter.Take("d"); wait == 0 {
+		t.Error("Take(d) went through, want it to share the spent overflow budget")
+	}
+	if len(limiter.buckets) != 3 {
+		t.Errorf("got %d buckets, want the 2 clients and the overflow", len(limiter.buckets))
+	}
+}
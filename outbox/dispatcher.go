@@ -0,0 +1,335 @@
+// Package outbox dispatches the events the services store in the outbox table
+// to the sinks interested in them.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
+	"github.com/ezerw/wheel/util"
+)
+
+// Status values of the outbox and outbox_deliveries rows.
+const (
+	StatusPending = "pending"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// Defaults of the outbox polling unless set in the config: the wait between
+// polls, the events claimed by each, the attempts before giving up and the
+// deliveries each sink runs at the same time.
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+	defaultMaxAttempts  = 10
+	defaultConcurrency  = 16
+)
+
+// maxErrorLength is the size of the last_error column of outbox_deliveries.
+const maxErrorLength = 500
+
+// claimLease is how long claimed deliveries are hidden from the other
+// instances while they run. Deliveries still pending after it, e.g. when the
+// instance died, are claimed again.
+const claimLease = 5 * time.Minute
+
+// Sink receives the events claimed from the outbox. It must return once the
+// event is delivered, an error makes the event be retried later to this sink
+// only.
+type Sink interface {
+	Deliver(ctx context.Context, e event.Event) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(ctx context.Context, e event.Event) error
+
+// Deliver calls f(ctx, e).
+func (f SinkFunc) Deliver(ctx context.Context, e event.Event) error {
+	return f(ctx, e)
+}
+
+// PublisherSink delivers events to an event.Publisher such as event.Bus.
+// Publishers don't report failures, so it suits best-effort subscribers.
+func PublisherSink(publisher event.Publisher) Sink {
+	return SinkFunc(func(ctx context.Context, e event.Event) error {
+		publisher.Publish(ctx, e)
+		return nil
+	})
+}
+
+// sink is a registered Sink, its slots holding a token for each of its
+// deliveries in flight.
+type sink struct {
+	Sink
+	name  string
+	slots chan struct{}
+}
+
+// Dispatcher polls the outbox and fans each pending event out to a delivery
+// row per sink, which the sinks then work through independently: a failing
+// sink retries its own deliveries without holding up or repeating the
+// others'. Rows are claimed with SELECT ... FOR UPDATE SKIP LOCKED and
+// deliveries leased so several instances of the API can dispatch at the same
+// time, each delivery running in the background once its claim is committed.
+type Dispatcher struct {
+	store        db.Store
+	logger       *logrus.Logger
+	sinks        []*sink
+	pollInterval time.Duration
+	batchSize    int32
+	maxAttempts  int32
+	concurrency  int
+
+	// running counts the deliveries in flight.
+	running sync.WaitGroup
+}
+
+// NewDispatcher creates a Dispatcher configured by the OUTBOX_* settings.
+func NewDispatcher(store db.Store, config util.Config, logger *logrus.Logger) *Dispatcher {
+	d := &Dispatcher{
+		store:        store,
+		logger:       logger,
+		pollInterval: defaultPollInterval,
+		batchSize:    defaultBatchSize,
+		maxAttempts:  defaultMaxAttempts,
+		concurrency:  defaultConcurrency,
+	}
+	if config.OutboxPollInterval > 0 {
+		d.pollInterval = config.OutboxPollInterval
+	}
+	if config.OutboxBatchSize > 0 {
+		d.batchSize = int32(config.OutboxBatchSize)
+	}
+	if config.OutboxMaxAttempts > 0 {
+		d.maxAttempts = int32(config.OutboxMaxAttempts)
+	}
+	if config.OutboxConcurrency > 0 {
+		d.concurrency = config.OutboxConcurrency
+	}
+	return d
+}
+
+// Register adds a sink receiving every dispatched event under name, which
+// identifies its deliveries in the outbox_deliveries table and so must stay
+// the same across restarts. It must be called before Run.
+func (d *Dispatcher) Register(name string, s Sink) {
+	d.sinks = append(d.sinks, &sink{Sink: s, name: name, slots: make(chan struct{}, d.concurrency)})
+}
+
+// Run dispatches events until ctx is cancelled, then waits for the
+// deliveries in flight. Full batches are followed by the next one straight
+// away, otherwise it waits for the poll interval.
+func (d *Dispatcher) Run(ctx context.Context) {
+	var loops sync.WaitGroup
+	for _, s := range d.sinks {
+		loops.Add(1)
+		go func(s *sink) {
+			defer loops.Done()
+			d.poll(ctx, func() (int, int, error) {
+				return d.dispatchSink(ctx, s)
+			})
+		}(s)
+	}
+
+	d.poll(ctx, func() (int, int, error) {
+		n, err := d.FanOut(ctx)
+		return n, int(d.batchSize), err
+	})
+	loops.Wait()
+	d.Wait()
+}
+
+// poll calls batch until ctx is cancelled, straight away again when it
+// claimed as many rows as it asked for.
+func (d *Dispatcher) poll(ctx context.Context, batch func() (claimed, limit int, err error)) {
+	for {
+		n, limit, err := batch()
+		if err != nil && ctx.Err() == nil {
+			util.LoggerOr(ctx, d.logger).WithError(err).Error("cannot dispatch outbox events")
+		}
+		if err == nil && n > 0 && n == limit {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.pollInterval):
+		}
+	}
+}
+
+// FanOut claims a batch of pending events and creates a delivery of each one
+// for every sink, marking the events done. No sink is called, so the rows
+// are only locked for the transaction. It returns how many events were
+// claimed.
+func (d *Dispatcher) FanOut(ctx context.Context) (int, error) {
+	var ids []int64
+	err := d.store.ExecTx(ctx, func(q db.Querier) error {
+		var err error
+		ids, err = q.ClaimOutboxEvents(ctx, d.batchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			for _, s := range d.sinks {
+				err = q.CreateOutboxDelivery(ctx, db.CreateOutboxDeliveryParams{OutboxID: id, Sink: s.name})
+				if err != nil {
+					return err
+				}
+			}
+			if err = q.MarkOutboxEventDone(ctx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(ids), err
+}
+
+// Dispatch starts the pending deliveries of every sink, as many as each sink
+// has free slots for, without waiting for them. It returns how many
+// deliveries were started.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	started := 0
+	for _, s := range d.sinks {
+		n, _, err := d.dispatchSink(ctx, s)
+		started += n
+		if err != nil {
+			return started, err
+		}
+	}
+	return started, nil
+}
+
+// Wait waits for the deliveries in flight to finish.
+func (d *Dispatcher) Wait() {
+	d.running.Wait()
+}
+
+// dispatchSink claims up to a batch of the pending deliveries of s, bounded
+// by its free slots, and runs each one in the background. It returns how
+// many it started and how many it asked for.
+func (d *Dispatcher) dispatchSink(ctx context.Context, s *sink) (int, int, error) {
+	limit := cap(s.slots) - len(s.slots)
+	if limit > int(d.batchSize) {
+		limit = int(d.batchSize)
+	}
+	if limit == 0 {
+		return 0, 0, nil
+	}
+
+	rows, err := d.claim(ctx, s, int32(limit))
+	if err != nil {
+		return 0, limit, err
+	}
+	for _, row := range rows {
+		s.slots <- struct{}{}
+		d.running.Add(1)
+		go func(row db.ClaimOutboxDeliveriesRow) {
+			defer d.running.Done()
+			defer func() { <-s.slots }()
+			d.deliver(ctx, s, row)
+		}(row)
+	}
+	return len(rows), limit, nil
+}
+
+// claim leases up to limit pending deliveries of s, so no network call is
+// made while their rows are locked.
+func (d *Dispatcher) claim(ctx context.Context, s *sink, limit int32) ([]db.ClaimOutboxDeliveriesRow, error) {
+	var rows []db.ClaimOutboxDeliveriesRow
+	err := d.store.ExecTx(ctx, func(q db.Querier) error {
+		var err error
+		rows, err = q.ClaimOutboxDeliveries(ctx, db.ClaimOutboxDeliveriesParams{Sink: s.name, Limit: limit})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			err = q.LeaseOutboxDelivery(ctx, db.LeaseOutboxDeliveryParams{
+				LeaseSeconds: int32(claimLease.Seconds()),
+				OutboxID:     row.OutboxID,
+				Sink:         s.name,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return rows, err
+}
+
+// deliver decodes the stored event, hands it to s and records the outcome.
+// The outcome is stored even once ctx is cancelled so an interrupted delivery
+// is retried instead of waiting for its lease to run out. The sink gets a
+// logger with the event and its name in ctx, see util.LoggerFrom.
+func (d *Dispatcher) deliver(ctx context.Context, s *sink, row db.ClaimOutboxDeliveriesRow) {
+	logger := util.LoggerOr(ctx, d.logger).WithFields(logrus.Fields{
+		"event_id":   row.EventID,
+		"event_type": row.EventType,
+		"sink":       s.name,
+	})
+	ctx = util.WithLogger(ctx, logger)
+
+	var e event.Event
+	err := json.Unmarshal([]byte(row.Payload), &e)
+	if err != nil {
+		err = fmt.Errorf("cannot decode event: %w", err)
+	} else {
+		err = s.Deliver(ctx, e)
+	}
+
+	if err == nil {
+		err = d.store.MarkOutboxDeliveryDone(context.Background(), db.MarkOutboxDeliveryDoneParams{
+			OutboxID: row.OutboxID,
+			Sink:     s.name,
+		})
+	} else {
+		err = d.store.MarkOutboxDeliveryFailed(context.Background(), d.failure(ctx, s, row, err))
+	}
+	if err != nil {
+		logger.WithError(err).Error("cannot record outbox delivery")
+	}
+}
+
+// failure records why the delivery failed. It's retried after a delay
+// doubling on every attempt until attempts run out.
+func (d *Dispatcher) failure(ctx context.Context, s *sink, row db.ClaimOutboxDeliveriesRow, err error) db.MarkOutboxDeliveryFailedParams {
+	attempts := row.Attempts + 1
+	status := StatusPending
+	if attempts >= d.maxAttempts {
+		status = StatusFailed
+	}
+
+	util.LoggerOr(ctx, d.logger).WithError(err).WithFields(logrus.Fields{
+		"attempt": attempts,
+		"status":  status,
+	}).Warn("outbox event delivery failed")
+
+	message := err.Error()
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+
+	delay := d.pollInterval.Seconds() * math.Pow(2, float64(row.Attempts))
+	return db.MarkOutboxDeliveryFailedParams{
+		Status:       status,
+		LastError:    sql.NullString{String: message, Valid: true},
+		DelaySeconds: int32(math.Min(math.Ceil(delay), 3600)),
+		OutboxID:     row.OutboxID,
+		Sink:         s.name,
+	}
+}
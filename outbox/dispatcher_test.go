@@ -0,0 +1,238 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
+	"github.com/ezerw/wheel/util"
+)
+
+func TestFanOut(t *testing.T) {
+	store := newOutboxStore()
+	store.events = []int64{1, 2}
+
+	dispatcher := NewDispatcher(store, util.Config{}, util.NewLogger())
+	dispatcher.Register("live", SinkFunc(func(context.Context, event.Event) error { return nil }))
+	dispatcher.Register("webhooks", SinkFunc(func(context.Context, event.Event) error { return nil }))
+
+	n, err := dispatcher.FanOut(context.Background())
+	if err != nil {
+		t.Fatalf("FanOut: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("FanOut claimed %d events, want 2", n)
+	}
+	want := []db.CreateOutboxDeliveryParams{
+		{OutboxID: 1, Sink: "live"}, {OutboxID: 1, Sink: "webhooks"},
+		{OutboxID: 2, Sink: "live"}, {OutboxID: 2, Sink: "webhooks"},
+	}
+	if len(store.created) != len(want) {
+		t.Fatalf("created %v, want %v", store.created, want)
+	}
+	for i := range want {
+		if store.created[i] != want[i] {
+			t.Errorf("created %v, want %v", store.created, want)
+		}
+	}
+	if len(store.eventsDone) != 2 {
+		t.Errorf("events done = %v, want [1 2]", store.eventsDone)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	store := newOutboxStore()
+	for i, eventType := range []event.Type{event.TurnAssigned, event.PersonAdded} {
+		e, err := event.New(eventType, 7, map[string]int{"n": i})
+		if err != nil {
+			t.Fatalf("cannot create event: %v", err)
+		}
+		payload, _ := json.Marshal(e)
+		for _, sink := range []string{"live", "webhooks"} {
+			store.deliveries[sink] = append(store.deliveries[sink], db.ClaimOutboxDeliveriesRow{
+				OutboxID:  int64(i + 1),
+				Attempts:  int32(i),
+				EventID:   e.ID,
+				EventType: string(e.Type),
+				Payload:   string(payload),
+			})
+		}
+	}
+
+	config := util.Config{OutboxMaxAttempts: 2}
+	dispatcher := NewDispatcher(store, config, util.NewLogger())
+
+	var (
+		mu        sync.Mutex
+		delivered = map[string][]event.Type{}
+	)
+	sink := func(name string, fail event.Type) Sink {
+		return SinkFunc(func(ctx context.Context, e event.Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if store.inTx() {
+				t.Errorf("%s delivered inside the claim transaction", name)
+			}
+			if fields := util.LoggerFrom(ctx).Data; fields["event_id"] != e.ID || fields["sink"] != name {
+				t.Errorf("%s got a logger with %v, want the event ID and sink", name, fields)
+			}
+			delivered[name] = append(delivered[name], e.Type)
+			if e.Type == fail {
+				return errors.New("sink unavailable")
+			}
+			return nil
+		})
+	}
+	dispatcher.Register("live", sink("live", ""))
+	dispatcher.Register("webhooks", sink("webhooks", event.PersonAdded))
+
+	n, err := dispatcher.Dispatch(context.Background())
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	dispatcher.Wait()
+	if n != 4 {
+		t.Errorf("Dispatch started %d deliveries, want 4", n)
+	}
+	if len(delivered["live"]) != 2 || len(delivered["webhooks"]) != 2 {
+		t.Errorf("delivered %v, want both events to both sinks", delivered)
+	}
+
+	if len(store.leased) != 4 || store.leased[0].LeaseSeconds != 300 {
+		t.Errorf("leased = %+v, want every delivery leased for 5 minutes", store.leased)
+	}
+	if len(store.done) != 3 {
+		t.Errorf("done = %+v, want all but the failed delivery", store.done)
+	}
+	if len(store.failed) != 1 {
+		t.Fatalf("failed %d deliveries, want 1", len(store.failed))
+	}
+	failed := store.failed[0]
+	if failed.OutboxID != 2 || failed.Sink != "webhooks" || failed.Status != StatusFailed || failed.LastError.String != "sink unavailable" {
+		t.Errorf("failed = %+v, want the webhooks delivery of event 2 failed for good on its last attempt", failed)
+	}
+	if failed.DelaySeconds != 2 {
+		t.Errorf("retry delay = %ds, want 2s after the second attempt", failed.DelaySeconds)
+	}
+}
+
+func TestDispatchBoundsDeliveries(t *testing.T) {
+	store := newOutboxStore()
+	for i := 1; i <= 3; i++ {
+		store.deliveries["slow"] = append(store.deliveries["slow"], db.ClaimOutboxDeliveriesRow{OutboxID: int64(i), Payload: "{}"})
+	}
+
+	dispatcher := NewDispatcher(store, util.Config{OutboxConcurrency: 2}, util.NewLogger())
+	release := make(chan struct{})
+	dispatcher.Register("slow", SinkFunc(func(context.Context, event.Event) error {
+		<-release
+		return nil
+	}))
+
+	n, err := dispatcher.Dispatch(context.Background())
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Dispatch started %d deliveries, want 2", n)
+	}
+	if n, _ := dispatcher.Dispatch(context.Background()); n != 0 {
+		t.Errorf("Dispatch with every slot taken started %d deliveries, want 0", n)
+	}
+
+	close(release)
+	dispatcher.Wait()
+	if n, _ := dispatcher.Dispatch(context.Background()); n != 1 {
+		t.Errorf("Dispatch once the slots freed up started %d deliveries, want 1", n)
+	}
+	dispatcher.Wait()
+}
+
+// outboxStore is an in-memory db.Store implementing the queries used by the Dispatcher.
+type outboxStore struct {
+	db.Store
+
+	mu         sync.Mutex
+	events     []int64
+	eventsDone []int64
+	created    []db.CreateOutboxDeliveryParams
+	deliveries map[string][]db.ClaimOutboxDeliveriesRow
+	leased     []db.LeaseOutboxDeliveryParams
+	done       []db.MarkOutboxDeliveryDoneParams
+	failed     []db.MarkOutboxDeliveryFailedParams
+
+	// tx is set while a transaction runs.
+	tx bool
+}
+
+func newOutboxStore() *outboxStore {
+	return &outboxStore{deliveries: map[string][]db.ClaimOutboxDeliveriesRow{}}
+}
+
+func (s *outboxStore) inTx() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tx
+}
+
+func (s *outboxStore) ExecTx(_ context.Context, fn func(db.Querier) error) error {
+	s.mu.Lock()
+	s.tx = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.tx = false
+		s.mu.Unlock()
+	}()
+	return fn(s)
+}
+
+func (s *outboxStore) ClaimOutboxEvents(_ context.Context, limit int32) ([]int64, error) {
+	if int(limit) < len(s.events) {
+		return s.events[:limit], nil
+	}
+	return s.events, nil
+}
+
+func (s *outboxStore) CreateOutboxDelivery(_ context.Context, arg db.CreateOutboxDeliveryParams) error {
+	s.created = append(s.created, arg)
+	return nil
+}
+
+func (s *outboxStore) MarkOutboxEventDone(_ context.Context, id int64) error {
+	s.eventsDone = append(s.eventsDone, id)
+	return nil
+}
+
+// ClaimOutboxDeliveries hands out the deliveries of the sink once.
+func (s *outboxStore) ClaimOutboxDeliveries(_ context.Context, arg db.ClaimOutboxDeliveriesParams) ([]db.ClaimOutboxDeliveriesRow, error) {
+	rows := s.deliveries[arg.Sink]
+	if int(arg.Limit) < len(rows) {
+		rows = rows[:arg.Limit]
+	}
+	s.deliveries[arg.Sink] = s.deliveries[arg.Sink][len(rows):]
+	return rows, nil
+}
+
+func (s *outboxStore) LeaseOutboxDelivery(_ context.Context, arg db.LeaseOutboxDeliveryParams) error {
+	s.leased = append(s.leased, arg)
+	return nil
+}
+
+func (s *outboxStore) MarkOutboxDeliveryDone(_ context.Context, arg db.MarkOutboxDeliveryDoneParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done = append(s.done, arg)
+	return nil
+}
+
+func (s *outboxStore) MarkOutboxDeliveryFailed(_ context.Context, arg db.MarkOutboxDeliveryFailedParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = append(s.failed, arg)
+	return nil
+}
@@ -0,0 +1,179 @@
+// Package scheduler runs the jobs of the teams on their cron schedules.
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/service"
+	"github.com/ezerw/wheel/util"
+)
+
+// defaultInterval is how often due jobs are looked for unless
+// SCHEDULER_INTERVAL is set.
+const defaultInterval = 30 * time.Second
+
+// RunFunc runs a job of a team and summarises what it did.
+type RunFunc func(ctx context.Context, teamID int64, now time.Time) (string, error)
+
+// Scheduler runs the jobs of the teams when they're due. Every run holds a
+// MySQL named lock so only one instance of the API runs a job at a time.
+type Scheduler struct {
+	store    db.Store
+	logger   *logrus.Logger
+	location *time.Location
+	interval time.Duration
+	runners  map[string]RunFunc
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a Scheduler evaluating the schedules in the APP_TIMEZONE.
+func New(store db.Store, config util.Config, logger *logrus.Logger) (*Scheduler, error) {
+	location, err := time.LoadLocation(config.AppTimezone)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Scheduler{
+		store:    store,
+		logger:   logger,
+		location: location,
+		interval: defaultInterval,
+		runners:  map[string]RunFunc{},
+	}
+	if config.SchedulerInterval > 0 {
+		s.interval = config.SchedulerInterval
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s, nil
+}
+
+// Register makes run the function running the jobs of kind.
+func (s *Scheduler) Register(kind string, run RunFunc) {
+	s.runners[kind] = run
+}
+
+// Start runs the due jobs in the background until the scheduler is closed.
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			s.RunDue(s.ctx, time.Now())
+
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunDue runs the jobs due at now, one after the other.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) {
+	now = now.In(s.location)
+
+	jobs, err := s.store.ListDueJobs(ctx, now)
+	if err != nil {
+		util.LoggerOr(ctx, s.logger).WithError(err).Error("cannot list due jobs")
+		return
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err = s.run(ctx, job, now); err != nil {
+			util.LoggerOr(ctx, s.logger).WithError(err).WithFields(logrus.Fields{
+				"team_id": job.TeamID,
+				"kind":    job.Kind,
+			}).Error("cannot run job")
+		}
+	}
+}
+
+// Close stops running jobs and waits for the one running.
+func (s *Scheduler) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// run runs job unless another instance is running it, and records the
+// outcome. The runner gets a logger with the job in ctx, see
+// util.LoggerFrom.
+func (s *Scheduler) run(ctx context.Context, job db.Job, now time.Time) error {
+	logger := util.LoggerOr(ctx, s.logger).WithFields(logrus.Fields{
+		"team_id": job.TeamID,
+		"kind":    job.Kind,
+	})
+	ctx = util.WithLogger(ctx, logger)
+
+	lock := fmt.Sprintf("wheel.job.%d.%s", job.TeamID, job.Kind)
+	_, err := s.store.TryLock(ctx, lock, func() error {
+		// Another instance may have run it since it was listed.
+		job, err := s.store.GetJob(ctx, db.GetJobParams{TeamID: job.TeamID, Kind: job.Kind})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !job.Enabled || job.NextRunAt.After(now) {
+			return nil
+		}
+
+		next, err := service.NextJobRun(job.Schedule, now)
+		if err != nil {
+			return errors.Wrap(err, "invalid schedule")
+		}
+
+		started := time.Now()
+		status, result := service.JobSucceeded, ""
+		if run, ok := s.runners[job.Kind]; !ok {
+			status, result = service.JobFailed, "No runner for this kind of job."
+		} else if result, err = run(ctx, job.TeamID, now); err != nil {
+			status, result = service.JobFailed, err.Error()
+		}
+
+		logger.WithFields(logrus.Fields{
+			"status": status,
+			"result": result,
+		}).Info("job ran")
+
+		// The outcome must be recorded even when the scheduler is closing.
+		return s.store.FinishJobRun(context.Background(), db.FinishJobRunParams{
+			LastRunAt:      sql.NullTime{Time: started, Valid: true},
+			LastStatus:     sql.NullString{String: status, Valid: true},
+			LastResult:     sql.NullString{String: truncate(result, service.MaxJobResultLength), Valid: true},
+			LastDurationMs: sql.NullInt32{Int32: int32(time.Since(started) / time.Millisecond), Valid: true},
+			NextRunAt:      next,
+			TeamID:         job.TeamID,
+			Kind:           job.Kind,
+		})
+	})
+	return err
+}
+
+// truncate shortens s to at most max characters.
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
@@ -0,0 +1,112 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/service"
+	"github.com/ezerw/wheel/util"
+)
+
+func TestRunDue(t *testing.T) {
+	now := time.Date(2021, time.July, 19, 8, 0, 30, 0, time.UTC)
+	store := &schedulerStore{
+		jobs: []db.Job{
+			{TeamID: 1, Kind: service.JobAssign, Schedule: "0 8 * * 1-5", Enabled: true, NextRunAt: now.Add(-30 * time.Second)},
+			{TeamID: 2, Kind: service.JobAssign, Schedule: "0 8 * * 1-5", Enabled: true, NextRunAt: now.Add(-30 * time.Second)},
+			{TeamID: 3, Kind: service.JobPurge, Schedule: "0 3 * * 0", Enabled: true, NextRunAt: now.Add(-time.Hour)},
+			{TeamID: 4, Kind: service.JobAssign, Schedule: "0 8 * * 1-5", Enabled: true, NextRunAt: now.Add(time.Hour)},
+		},
+		// Another instance is running the job of team 2.
+		locked: map[string]bool{"wheel.job.2.assign": true},
+	}
+	s, err := New(store, util.Config{AppTimezone: "UTC"}, util.NewLogger())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+
+	var ran []int64
+	s.Register(service.JobAssign, func(_ context.Context, teamID int64, _ time.Time) (string, error) {
+		ran = append(ran, teamID)
+		return "Assigned.", nil
+	})
+	s.Register(service.JobPurge, func(_ context.Context, teamID int64, _ time.Time) (string, error) {
+		ran = append(ran, teamID)
+		return "", errors.New("purge failed")
+	})
+
+	// Running again at the same time must not run the jobs twice.
+	s.RunDue(context.Background(), now)
+	s.RunDue(context.Background(), now)
+
+	if len(ran) != 2 || ran[0] != 1 || ran[1] != 3 {
+		t.Fatalf("ran the jobs of teams %v, want [1 3]", ran)
+	}
+
+	assign := store.jobs[0]
+	if assign.LastStatus.String != service.JobSucceeded || assign.LastResult.String != "Assigned." {
+		t.Errorf("assign job outcome = %q %q, want succeeded", assign.LastStatus.String, assign.LastResult.String)
+	}
+	if want := time.Date(2021, time.July, 20, 8, 0, 0, 0, time.UTC); !assign.NextRunAt.Equal(want) {
+		t.Errorf("assign job next run = %v, want %v", assign.NextRunAt, want)
+	}
+	purge := store.jobs[2]
+	if purge.LastStatus.String != service.JobFailed || purge.LastResult.String != "purge failed" {
+		t.Errorf("purge job outcome = %q %q, want the error", purge.LastStatus.String, purge.LastResult.String)
+	}
+	if store.jobs[1].LastRunAt.Valid || store.jobs[3].LastRunAt.Valid {
+		t.Errorf("jobs locked elsewhere or not due have run")
+	}
+}
+
+// schedulerStore is an in-memory db.Store implementing the queries used by the Scheduler.
+type schedulerStore struct {
+	db.Store
+
+	jobs   []db.Job
+	locked map[string]bool
+}
+
+func (s *schedulerStore) ListDueJobs(_ context.Context, now time.Time) ([]db.Job, error) {
+	var due []db.Job
+	for _, job := range s.jobs {
+		if job.Enabled && !job.NextRunAt.After(now) {
+			due = append(due, job)
+		}
+	}
+	return due, nil
+}
+
+func (s *schedulerStore) GetJob(_ context.Context, arg db.GetJobParams) (db.Job, error) {
+	for _, job := range s.jobs {
+		if job.TeamID == arg.TeamID && job.Kind == arg.Kind {
+			return job, nil
+		}
+	}
+	return db.Job{}, sql.ErrNoRows
+}
+
+func (s *schedulerStore) FinishJobRun(_ context.Context, arg db.FinishJobRunParams) error {
+	for i, job := range s.jobs {
+		if job.TeamID == arg.TeamID && job.Kind == arg.Kind {
+			s.jobs[i].LastRunAt = arg.LastRunAt
+			s.jobs[i].LastStatus = arg.LastStatus
+			s.jobs[i].LastResult = arg.LastResult
+			s.jobs[i].LastDurationMs = arg.LastDurationMs
+			s.jobs[i].NextRunAt = arg.NextRunAt
+		}
+	}
+	return nil
+}
+
+func (s *schedulerStore) TryLock(_ context.Context, name string, fn func() error) (bool, error) {
+	if s.locked[name] {
+		return false, nil
+	}
+	return true, fn()
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/ezerw/wheel/db"
+)
+
+// Calendar is the service in charge of the iCalendar feeds of the turns.
+// Feeds are authenticated by a token derived from CALENDAR_SECRET, so
+// rotating the secret revokes every subscription.
+type Calendar struct {
+	store  db.Store
+	secret []byte
+}
+
+// CalendarFeedAPI is the representation returned to the client.
+type CalendarFeedAPI struct {
+	URL string `json:"url"`
+}
+
+// NewCalendar creates a new CalendarService instance, feeds are disabled
+// when secret is empty.
+func NewCalendar(store db.Store, secret string) *Calendar {
+	return &Calendar{store: store, secret: []byte(secret)}
+}
+
+// FeedToken returns the token of the feed of the team, or of one of its
+// people when personID isn't 0.
+func (s *Calendar) FeedToken(teamID int64, personID int64) (string, error) {
+	if len(s.secret) == 0 {
+		return "", NotFound(CodeCalendarDisabled, "Calendar feeds are disabled.")
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	if personID == 0 {
+		fmt.Fprintf(mac, "teams/%d/turns.ics", teamID)
+	} else {
+		fmt.Fprintf(mac, "teams/%d/people/%d/turns.ics", teamID, personID)
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// CheckFeedToken returns an Unauthorized error unless token is the one of
+// the feed.
+func (s *Calendar) CheckFeedToken(teamID int64, personID int64, token string) error {
+	expected, err := s.FeedToken(teamID, personID)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(token), []byte(expected)) {
+		return Unauthorized(CodeUnauthorized, "Invalid calendar token.")
+	}
+	return nil
+}
+
+// ListTurns gets the turns of a team from since onwards, with the people
+// hosting them.
+func (s *Calendar) ListTurns(ctx context.Context, teamID int64, since time.Time) ([]db.ListCalendarTurnsRow, error) {
+	ctx, span := tracer.Start(ctx, "Calendar.ListTurns")
+	defer span.End()
+
+	return s.store.ListCalendarTurns(ctx, db.ListCalendarTurnsParams{
+		TeamID: teamID,
+		Date:   since,
+	})
+}
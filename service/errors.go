@@ -0,0 +1,216 @@
+package service
+
+import (
+	"database/sql"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+// Kind classifies a domain error so the transport layer can decide how to
+// present it to the client.
+type Kind int
+
+const (
+	// KindInternal is an unexpected failure, its details are never exposed.
+	KindInternal Kind = iota
+	// KindNotFound means the requested entity does not exist.
+	KindNotFound
+	// KindConflict means the request clashes with the current state.
+	KindConflict
+	// KindValidation means the request input is not acceptable.
+	KindValidation
+	// KindUnauthorized means the request is not authenticated.
+	KindUnauthorized
+	// KindForbidden means the caller is not allowed to perform the action.
+	KindForbidden
+	// KindRateLimited means the caller sent too many requests.
+	KindRateLimited
+	// KindTooLarge means the request body is over the size limit.
+	KindTooLarge
+	// KindPreconditionFailed means the resource changed since the caller
+	// read it.
+	KindPreconditionFailed
+	// KindPreconditionRequired means the request must be conditional.
+	KindPreconditionRequired
+	// KindUnprocessable means the request lacks what the change depends on.
+	KindUnprocessable
+)
+
+// Stable machine-readable error codes exposed to clients.
+const (
+	CodeInternal         = "internal_error"
+	CodeRouteNotFound    = "route_not_found"
+	CodeTeamNotFound     = "team_not_found"
+	CodePersonNotFound   = "person_not_found"
+	CodeTurnNotFound     = "turn_not_found"
+	CodeWebhookNotFound  = "webhook_not_found"
+	CodeSlackNotFound    = "slack_not_configured"
+	CodeJobNotFound      = "job_not_found"
+	CodeChannelNotLinked = "channel_not_linked"
+	CodeTeamEmpty        = "team_empty"
+	CodeCalendarDisabled = "calendar_disabled"
+	CodeTeamNameTaken    = "team_name_taken"
+	CodeEmailTaken       = "email_taken"
+	CodeTurnTaken        = "turn_taken"
+	CodeVersionConflict  = "version_conflict"
+	CodeVersionRequired  = "version_required"
+	CodeDuplicateEntry   = "duplicate_entry"
+	CodeInvalidReference = "invalid_reference"
+	CodeInvalidParameter = "invalid_parameter"
+	CodeInvalidBody      = "invalid_body"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeRateLimited      = "rate_limited"
+	CodeBodyTooLarge     = "body_too_large"
+
+	CodePreconditionFailed   = "precondition_failed"
+	CodePreconditionRequired = "precondition_required"
+)
+
+// MySQL server error numbers the services translate into domain errors.
+const (
+	mysqlErrDupEntry         = 1062
+	mysqlErrNoReferencedRow  = 1452
+	mysqlErrNoReferencedRow1 = 1216
+)
+
+// Error is a domain error returned by the services.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	// Current is the current state of the resource a stale write conflicts
+	// with.
+	Current interface{}
+	Err     error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NotFound creates a KindNotFound error.
+func NotFound(code string, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+// Conflict creates a KindConflict error.
+func Conflict(code string, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+// Validation creates a KindValidation error.
+func Validation(code string, message string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
+
+// Unauthorized creates a KindUnauthorized error.
+func Unauthorized(code string, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+// Forbidden creates a KindForbidden error.
+func Forbidden(code string, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+// RateLimited creates a KindRateLimited error.
+func RateLimited(code string, message string) *Error {
+	return &Error{Kind: KindRateLimited, Code: code, Message: message}
+}
+
+// TooLarge creates a KindTooLarge error.
+func TooLarge(code string, message string) *Error {
+	return &Error{Kind: KindTooLarge, Code: code, Message: message}
+}
+
+// PreconditionFailed creates a KindPreconditionFailed error.
+func PreconditionFailed(code string, message string) *Error {
+	return &Error{Kind: KindPreconditionFailed, Code: code, Message: message}
+}
+
+// PreconditionRequired creates a KindPreconditionRequired error.
+func PreconditionRequired(code string, message string) *Error {
+	return &Error{Kind: KindPreconditionRequired, Code: code, Message: message}
+}
+
+// Unprocessable creates a KindUnprocessable error.
+func Unprocessable(code string, message string) *Error {
+	return &Error{Kind: KindUnprocessable, Code: code, Message: message}
+}
+
+// VersionRequired creates the KindUnprocessable error of a change missing
+// the version it's based on, along with the current state when known.
+func VersionRequired(current interface{}) *Error {
+	e := Unprocessable(CodeVersionRequired, "version must be set to the version of the resource the change is based on.")
+	e.Current = current
+	return e
+}
+
+// versionConflict creates the KindConflict error of a write based on a
+// stale version of current.
+func versionConflict(current interface{}) *Error {
+	e := Conflict(CodeVersionConflict, "The resource has changed since it was read, retry on top of its current state.")
+	e.Current = current
+	return e
+}
+
+// updated reports whether the update behind result matched a row, which
+// it doesn't when the version it's conditional on is stale.
+func updated(result sql.Result) (bool, error) {
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// KindOf returns the Kind of err, KindInternal if it isn't a domain error.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// notFound translates sql.ErrNoRows into the given domain error.
+func notFound(err error, domainErr *Error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		domainErr.Err = err
+		return domainErr
+	}
+	return err
+}
+
+// dbError translates MySQL constraint violations into domain errors. A
+// duplicate key becomes the given conflict, or a generic one when nil.
+func dbError(err error, conflict *Error) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return err
+	}
+
+	switch mysqlErr.Number {
+	case mysqlErrDupEntry:
+		if conflict == nil {
+			conflict = Conflict(CodeDuplicateEntry, "The resource already exists.")
+		}
+		conflict.Err = err
+		return conflict
+	case mysqlErrNoReferencedRow, mysqlErrNoReferencedRow1:
+		e := Validation(CodeInvalidReference, "A referenced resource does not exist.")
+		e.Err = err
+		return e
+	}
+
+	return err
+}
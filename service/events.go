@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
+	"github.com/ezerw/wheel/util"
+)
+
+// TeamEventData is the data of team events.
+type TeamEventData struct {
+	Team db.GetTeamRow `json:"team"`
+}
+
+// PersonEventData is the data of person events.
+type PersonEventData struct {
+	Person db.GetPersonRow `json:"person"`
+}
+
+// TurnEventData is the data of turn events, PreviousPersonID is only set
+// when the turn is reassigned.
+type TurnEventData struct {
+	Turn             TurnAPI         `json:"turn"`
+	Person           db.GetPersonRow `json:"person"`
+	PreviousPersonID int64           `json:"previous_person_id,omitempty"`
+}
+
+// recordEvent writes an event to the outbox using q, which must be bound to
+// the transaction of the change the event describes so both are stored or
+// neither is.
+func recordEvent(ctx context.Context, q db.Querier, eventType event.Type, teamID int64, data interface{}) error {
+	e, err := event.New(eventType, teamID, data)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateOutboxEvent(ctx, db.CreateOutboxEventParams{
+		EventID:   e.ID,
+		EventType: string(e.Type),
+		TeamID:    e.TeamID,
+		Payload:   string(payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Logged with the request ID, which the deliveries of the event can be
+	// traced back to by its ID.
+	util.LoggerFrom(ctx).WithFields(logrus.Fields{
+		"event_id":   e.ID,
+		"event_type": e.Type,
+		"team_id":    e.TeamID,
+	}).Info("event written to the outbox")
+	return nil
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ezerw/wheel/cron"
+	"github.com/ezerw/wheel/db"
+)
+
+// Kinds of the jobs the scheduler can run for a team.
+const (
+	// JobAssign assigns the next working day's turn to a random member,
+	// unless someone already has it.
+	JobAssign = "assign"
+	// JobRemind posts today's Slack reminder.
+	JobRemind = "remind"
+	// JobPurge deletes the turns and webhook deliveries older than
+	// PURGE_AFTER_DAYS.
+	JobPurge = "purge"
+)
+
+// Outcomes of a job run.
+const (
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// MaxJobScheduleLength and MaxJobResultLength are the limits of the jobs columns.
+const (
+	MaxJobScheduleLength = 100
+	MaxJobResultLength   = 500
+)
+
+// DefaultJobSchedules are the schedules of the jobs which don't set theirs.
+var DefaultJobSchedules = map[string]string{
+	JobAssign: "0 8 * * 1-5",
+	JobRemind: "0 9 * * 1-5",
+	JobPurge:  "0 3 * * 0",
+}
+
+// Jobs is the service in charge of interact with the jobs table in the database.
+type Jobs struct {
+	store db.Store
+}
+
+// JobAPI is the representation returned to the client.
+type JobAPI struct {
+	TeamID    int64      `json:"team_id"`
+	Kind      string     `json:"kind"`
+	Schedule  string     `json:"schedule"`
+	Enabled   bool       `json:"enabled"`
+	NextRunAt *time.Time `json:"next_run_at"`
+	LastRun   *JobRunAPI `json:"last_run"`
+}
+
+// JobRunAPI is the outcome of the last run of a job.
+type JobRunAPI struct {
+	StartedAt  time.Time `json:"started_at"`
+	Status     string    `json:"status"`
+	Result     string    `json:"result"`
+	DurationMs int32     `json:"duration_ms"`
+}
+
+// NewJobs creates a new JobsService instance.
+func NewJobs(store db.Store) *Jobs {
+	return &Jobs{store: store}
+}
+
+// ListJobs gets the jobs of a team from the DB.
+func (s *Jobs) ListJobs(ctx context.Context, teamID int64) ([]JobAPI, error) {
+	ctx, span := tracer.Start(ctx, "Jobs.ListJobs")
+	defer span.End()
+
+	dbJobs, err := s.store.ListJobs(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []JobAPI{}
+	for _, job := range dbJobs {
+		jobs = append(jobs, jobAPI(job))
+	}
+	return jobs, nil
+}
+
+// GetJob gets a job of a team from the DB.
+func (s *Jobs) GetJob(ctx context.Context, teamID int64, kind string) (*JobAPI, error) {
+	ctx, span := tracer.Start(ctx, "Jobs.GetJob")
+	defer span.End()
+
+	job, err := s.store.GetJob(ctx, db.GetJobParams{TeamID: teamID, Kind: kind})
+	if err != nil {
+		return nil, notFound(err, NotFound(CodeJobNotFound, "The team doesn't have this job."))
+	}
+
+	api := jobAPI(job)
+	return &api, nil
+}
+
+// PutJob creates or updates a job of a team, scheduling its next run after
+// now. An empty schedule uses the default one of the kind.
+func (s *Jobs) PutJob(ctx context.Context, job JobAPI, now time.Time) (*JobAPI, error) {
+	ctx, span := tracer.Start(ctx, "Jobs.PutJob")
+	defer span.End()
+
+	if err := checkJobKind(job.Kind); err != nil {
+		return nil, err
+	}
+
+	job.Schedule = normalizeText(job.Schedule)
+	if job.Schedule == "" {
+		job.Schedule = DefaultJobSchedules[job.Kind]
+	}
+
+	v := validator{}
+	v.text("schedule", job.Schedule, MaxJobScheduleLength)
+	next, err := NextJobRun(job.Schedule, now)
+	if err != nil {
+		v.add("schedule", FieldInvalid, fmt.Sprintf("schedule must be a cron expression: %v.", err))
+	}
+	if err = v.err(); err != nil {
+		return nil, err
+	}
+
+	err = s.store.UpsertJob(ctx, db.UpsertJobParams{
+		TeamID:    job.TeamID,
+		Kind:      job.Kind,
+		Schedule:  job.Schedule,
+		Enabled:   job.Enabled,
+		NextRunAt: next,
+	})
+	if err != nil {
+		return nil, dbError(err, nil)
+	}
+
+	return s.GetJob(ctx, job.TeamID, job.Kind)
+}
+
+// DeleteJob stops running a job for a team.
+func (s *Jobs) DeleteJob(ctx context.Context, teamID int64, kind string) error {
+	ctx, span := tracer.Start(ctx, "Jobs.DeleteJob")
+	defer span.End()
+
+	if err := checkJobKind(kind); err != nil {
+		return err
+	}
+	return s.store.DeleteJob(ctx, db.DeleteJobParams{TeamID: teamID, Kind: kind})
+}
+
+// Purge deletes the turns of a team before date and its webhook deliveries
+// created before it, returning how many of each were deleted.
+func (s *Jobs) Purge(ctx context.Context, teamID int64, date time.Time) (int64, int64, error) {
+	ctx, span := tracer.Start(ctx, "Jobs.Purge")
+	defer span.End()
+
+	var turns, deliveries int64
+	err := s.store.ExecTx(ctx, func(q db.Querier) error {
+		result, err := q.DeleteTurnsBefore(ctx, db.DeleteTurnsBeforeParams{TeamID: teamID, Date: date})
+		if err != nil {
+			return err
+		}
+		if turns, err = result.RowsAffected(); err != nil {
+			return err
+		}
+
+		result, err = q.DeleteWebhookDeliveriesBefore(ctx, db.DeleteWebhookDeliveriesBeforeParams{
+			TeamID:    teamID,
+			CreatedAt: sql.NullTime{Time: date, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		deliveries, err = result.RowsAffected()
+		return err
+	})
+	return turns, deliveries, err
+}
+
+// NextJobRun returns when a job on schedule runs next after now, in the
+// location of now.
+func NextJobRun(schedule string, now time.Time) (time.Time, error) {
+	parsed, err := cron.Parse(schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next := parsed.Next(now)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("%q never runs", schedule)
+	}
+	return next, nil
+}
+
+// checkJobKind returns a validation error unless kind is a kind of job.
+func checkJobKind(kind string) error {
+	if _, ok := DefaultJobSchedules[kind]; !ok {
+		return Validation(CodeInvalidParameter, "kind must be one of assign, remind, purge.")
+	}
+	return nil
+}
+
+// jobAPI converts a job row to its representation.
+func jobAPI(job db.Job) JobAPI {
+	api := JobAPI{
+		TeamID:   job.TeamID,
+		Kind:     job.Kind,
+		Schedule: job.Schedule,
+		Enabled:  job.Enabled,
+	}
+	if job.Enabled {
+		next := job.NextRunAt
+		api.NextRunAt = &next
+	}
+	if job.LastRunAt.Valid {
+		api.LastRun = &JobRunAPI{
+			StartedAt:  job.LastRunAt.Time,
+			Status:     job.LastStatus.String,
+			Result:     job.LastResult.String,
+			DurationMs: job.LastDurationMs.Int32,
+		}
+	}
+	return api
+}
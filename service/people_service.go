@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+
 	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
 )
 
 // People is the service in charge of interact with the people table in the database.
@@ -17,48 +19,161 @@ func NewPeople(store db.Store) *People {
 
 // ListPeople gets people of a team from the DB.
 func (s *People) ListPeople(ctx context.Context, teamID int64) ([]db.ListPeopleRow, error) {
+	ctx, span := tracer.Start(ctx, "People.ListPeople")
+	defer span.End()
+
 	return s.store.ListPeople(ctx, teamID)
 }
 
 // GetPerson gets one person of the team from the DB.
 func (s *People) GetPerson(ctx context.Context, args db.GetPersonParams) (*db.GetPersonRow, error) {
-	person, err := s.store.GetPerson(ctx, args)
-	if err != nil {
-		return nil, err
-	}
+	ctx, span := tracer.Start(ctx, "People.GetPerson")
+	defer span.End()
 
-	return &person, nil
+	return getPerson(ctx, s.store, args)
 }
 
 // AddPerson add one person to the team in the DB.
 func (s *People) AddPerson(ctx context.Context, args db.CreatePersonParams) (*db.GetPersonRow, error) {
-	result, err := s.store.CreatePerson(ctx, args)
-	if err != nil {
+	ctx, span := tracer.Start(ctx, "People.AddPerson")
+	defer span.End()
+
+	args.FirstName = normalizeText(args.FirstName)
+	args.LastName = normalizeText(args.LastName)
+	args.Email = normalizeEmail(args.Email)
+	if err := validatePerson(args.FirstName, args.LastName, args.Email); err != nil {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
+	var person *db.GetPersonRow
+	err := s.store.ExecTx(ctx, func(q db.Querier) error {
+		result, err := q.CreatePerson(ctx, args)
+		if err != nil {
+			return dbError(err, errEmailTaken())
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		person, err = getPerson(ctx, q, db.GetPersonParams{
+			ID:     id,
+			TeamID: args.TeamID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, event.PersonAdded, person.TeamID, PersonEventData{Person: *person})
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return s.GetPerson(ctx, db.GetPersonParams{
-		ID:     id,
-		TeamID: args.TeamID,
-	})
+	return person, nil
 }
 
-// UpdatePerson updates a person from the team in the DB.
-func (s *People) UpdatePerson(ctx context.Context, args db.UpdatePersonParams) error {
-	_, err := s.store.UpdatePerson(ctx, args)
+// UpdatePerson updates a person in the DB and returns the updated person.
+func (s *People) UpdatePerson(ctx context.Context, args db.UpdatePersonParams) (*db.GetPersonRow, error) {
+	ctx, span := tracer.Start(ctx, "People.UpdatePerson")
+	defer span.End()
+
+	args.FirstName = normalizeText(args.FirstName)
+	args.LastName = normalizeText(args.LastName)
+	args.Email = normalizeEmail(args.Email)
+	if err := validatePerson(args.FirstName, args.LastName, args.Email); err != nil {
+		return nil, err
+	}
+	if args.Version <= 0 {
+		return nil, VersionRequired(nil)
+	}
+
+	var person *db.GetPersonRow
+	err := s.store.ExecTx(ctx, func(q db.Querier) error {
+		previous, err := q.GetPersonByID(ctx, args.ID)
+		if err != nil {
+			return notFound(err, NotFound(CodePersonNotFound, "Person not found."))
+		}
+
+		result, err := q.UpdatePerson(ctx, args)
+		if err != nil {
+			return dbError(err, errEmailTaken())
+		}
+		ok, err := updated(result)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			current, err := q.GetPersonByID(ctx, args.ID)
+			if err != nil {
+				return notFound(err, NotFound(CodePersonNotFound, "Person not found."))
+			}
+			return versionConflict(current)
+		}
+
+		person, err = getPerson(ctx, q, db.GetPersonParams{
+			ID:     args.ID,
+			TeamID: args.TeamID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// A person moving between teams is gone from the team they leave,
+		// whose subscribers are told too.
+		if previous.TeamID != person.TeamID {
+			err = recordEvent(ctx, q, event.PersonUpdated, previous.TeamID, PersonEventData{Person: *person})
+			if err != nil {
+				return err
+			}
+		}
+
+		return recordEvent(ctx, q, event.PersonUpdated, person.TeamID, PersonEventData{Person: *person})
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return person, nil
 }
 
 // DeletePerson deletes a person from the team from the DB.
 func (s *People) DeletePerson(ctx context.Context, args db.DeletePersonParams) error {
-	return s.store.DeletePerson(ctx, args)
+	ctx, span := tracer.Start(ctx, "People.DeletePerson")
+	defer span.End()
+
+	return s.store.ExecTx(ctx, func(q db.Querier) error {
+		err := q.DeletePerson(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		person := db.GetPersonRow{ID: args.ID, TeamID: args.TeamID}
+		return recordEvent(ctx, q, event.PersonRemoved, args.TeamID, PersonEventData{Person: person})
+	})
+}
+
+// getPerson gets a person of a team using q, so it can run inside a transaction.
+func getPerson(ctx context.Context, q db.Querier, args db.GetPersonParams) (*db.GetPersonRow, error) {
+	person, err := q.GetPerson(ctx, args)
+	if err != nil {
+		return nil, notFound(err, NotFound(CodePersonNotFound, "Person not found in the specified team."))
+	}
+
+	return &person, nil
+}
+
+// validatePerson checks normalised person fields fit the schema.
+func validatePerson(firstName string, lastName string, email string) error {
+	v := validator{}
+	v.text("first_name", firstName, MaxPersonNameLength)
+	v.text("last_name", lastName, MaxPersonNameLength)
+	v.email("email", email)
+	return v.err()
+}
+
+// errEmailTaken is returned when the email belongs to another person.
+func errEmailTaken() *Error {
+	return Conflict(CodeEmailTaken, "A person with that email already exists.")
 }
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ezerw/wheel/db"
+)
+
+// Limits of the slack_settings columns.
+const (
+	MaxSlackWebhookURLLength = 2048
+	MaxSlackTemplateLength   = 2000
+	MaxSlackChannelIDLength  = 50
+)
+
+// SlackWebhookURLPrefix starts the URLs of Slack incoming webhooks, the
+// only ones the notifications may be posted to.
+const SlackWebhookURLPrefix = "https://hooks.slack.com/"
+
+// DefaultSlackReminderTime is when reminders are sent unless a team sets
+// its own time.
+const DefaultSlackReminderTime = "09:00"
+
+// Slack is the service in charge of interact with the slack_settings table in the database.
+type Slack struct {
+	store db.Store
+}
+
+// SlackSettingsAPI is the representation returned to the client. Empty
+// templates use the default messages.
+type SlackSettingsAPI struct {
+	TeamID           int64     `json:"team_id"`
+	WebhookURL       string    `json:"webhook_url"`
+	NotifyAssigned   bool      `json:"notify_assigned"`
+	ReminderEnabled  bool      `json:"reminder_enabled"`
+	ReminderTime     string    `json:"reminder_time"`
+	AssignedTemplate string    `json:"assigned_template"`
+	ReminderTemplate string    `json:"reminder_template"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// SlackChannelAPI is a Slack channel linked to a team, where its slash
+// commands act on the team.
+type SlackChannelAPI struct {
+	ChannelID string    `json:"channel_id"`
+	TeamID    int64     `json:"team_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SlackTemplateData is what Slack message templates are rendered with.
+type SlackTemplateData struct {
+	Team   db.GetTeamRow
+	Person db.GetPersonRow
+	Date   time.Time
+}
+
+// NewSlack creates a new SlackService instance.
+func NewSlack(store db.Store) *Slack {
+	return &Slack{store: store}
+}
+
+// GetSlackSettings gets the Slack settings of a team from the DB.
+func (s *Slack) GetSlackSettings(ctx context.Context, teamID int64) (*SlackSettingsAPI, error) {
+	ctx, span := tracer.Start(ctx, "Slack.GetSlackSettings")
+	defer span.End()
+
+	settings, err := s.store.GetSlackSettings(ctx, teamID)
+	if err != nil {
+		return nil, notFound(err, NotFound(CodeSlackNotFound, "Slack notifications are not configured for the team."))
+	}
+
+	return &SlackSettingsAPI{
+		TeamID:           settings.TeamID,
+		WebhookURL:       settings.WebhookUrl,
+		NotifyAssigned:   settings.NotifyAssigned,
+		ReminderEnabled:  settings.ReminderEnabled,
+		ReminderTime:     settings.ReminderTime,
+		AssignedTemplate: settings.AssignedTemplate.String,
+		ReminderTemplate: settings.ReminderTemplate.String,
+		UpdatedAt:        settings.UpdatedAt.Time,
+	}, nil
+}
+
+// PutSlackSettings creates or replaces the Slack settings of a team.
+func (s *Slack) PutSlackSettings(ctx context.Context, settings SlackSettingsAPI) (*SlackSettingsAPI, error) {
+	ctx, span := tracer.Start(ctx, "Slack.PutSlackSettings")
+	defer span.End()
+
+	settings.WebhookURL = strings.TrimSpace(settings.WebhookURL)
+	if settings.ReminderTime == "" {
+		settings.ReminderTime = DefaultSlackReminderTime
+	}
+
+	v := validator{}
+	v.url("webhook_url", settings.WebhookURL, MaxSlackWebhookURLLength)
+	if !strings.HasPrefix(settings.WebhookURL, SlackWebhookURLPrefix) {
+		v.add("webhook_url", FieldInvalid, "webhook_url must be a Slack incoming webhook starting with "+SlackWebhookURLPrefix+".")
+	}
+	if _, err := time.Parse("15:04", settings.ReminderTime); err != nil || len(settings.ReminderTime) != 5 {
+		v.add("reminder_time", FieldInvalid, "reminder_time must be a 24-hour HH:MM time.")
+	}
+	validateSlackTemplate(&v, "assigned_template", settings.AssignedTemplate)
+	validateSlackTemplate(&v, "reminder_template", settings.ReminderTemplate)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	err := s.store.UpsertSlackSettings(ctx, db.UpsertSlackSettingsParams{
+		TeamID:           settings.TeamID,
+		WebhookUrl:       settings.WebhookURL,
+		NotifyAssigned:   settings.NotifyAssigned,
+		ReminderEnabled:  settings.ReminderEnabled,
+		ReminderTime:     settings.ReminderTime,
+		AssignedTemplate: sql.NullString{String: settings.AssignedTemplate, Valid: settings.AssignedTemplate != ""},
+		ReminderTemplate: sql.NullString{String: settings.ReminderTemplate, Valid: settings.ReminderTemplate != ""},
+	})
+	if err != nil {
+		return nil, dbError(err, nil)
+	}
+
+	return s.GetSlackSettings(ctx, settings.TeamID)
+}
+
+// DeleteSlackSettings turns off the Slack notifications of a team.
+func (s *Slack) DeleteSlackSettings(ctx context.Context, teamID int64) error {
+	ctx, span := tracer.Start(ctx, "Slack.DeleteSlackSettings")
+	defer span.End()
+
+	return s.store.DeleteSlackSettings(ctx, teamID)
+}
+
+// ListChannels gets the Slack channels linked to a team.
+func (s *Slack) ListChannels(ctx context.Context, teamID int64) ([]SlackChannelAPI, error) {
+	ctx, span := tracer.Start(ctx, "Slack.ListChannels")
+	defer span.End()
+
+	dbChannels, err := s.store.ListSlackChannels(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := []SlackChannelAPI{}
+	for _, channel := range dbChannels {
+		channels = append(channels, SlackChannelAPI{
+			ChannelID: channel.ChannelID,
+			TeamID:    channel.TeamID,
+			CreatedAt: channel.CreatedAt.Time,
+		})
+	}
+	return channels, nil
+}
+
+// LinkChannel links a Slack channel to a team, moving it from any other team.
+func (s *Slack) LinkChannel(ctx context.Context, teamID int64, channelID string) (*SlackChannelAPI, error) {
+	ctx, span := tracer.Start(ctx, "Slack.LinkChannel")
+	defer span.End()
+
+	channelID = strings.TrimSpace(channelID)
+	v := validator{}
+	v.text("channel_id", channelID, MaxSlackChannelIDLength)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	err := s.store.UpsertSlackChannel(ctx, db.UpsertSlackChannelParams{
+		ChannelID: channelID,
+		TeamID:    teamID,
+	})
+	if err != nil {
+		return nil, dbError(err, nil)
+	}
+
+	return s.TeamOfChannel(ctx, channelID)
+}
+
+// UnlinkChannel unlinks a Slack channel from a team.
+func (s *Slack) UnlinkChannel(ctx context.Context, args db.DeleteSlackChannelParams) error {
+	ctx, span := tracer.Start(ctx, "Slack.UnlinkChannel")
+	defer span.End()
+
+	return s.store.DeleteSlackChannel(ctx, args)
+}
+
+// TeamOfChannel gets the team a Slack channel is linked to.
+func (s *Slack) TeamOfChannel(ctx context.Context, channelID string) (*SlackChannelAPI, error) {
+	ctx, span := tracer.Start(ctx, "Slack.TeamOfChannel")
+	defer span.End()
+
+	channel, err := s.store.GetSlackChannel(ctx, channelID)
+	if err != nil {
+		return nil, notFound(err, NotFound(CodeChannelNotLinked, "The Slack channel is not linked to a team."))
+	}
+
+	return &SlackChannelAPI{
+		ChannelID: channel.ChannelID,
+		TeamID:    channel.TeamID,
+		CreatedAt: channel.CreatedAt.Time,
+	}, nil
+}
+
+// validateSlackTemplate checks an optional template parses and renders.
+func validateSlackTemplate(v *validator, field string, source string) {
+	if source == "" {
+		return
+	}
+	if len(source) > MaxSlackTemplateLength {
+		v.add(field, FieldTooLong, field+" must be at most 2000 characters.")
+		return
+	}
+
+	tmpl, err := template.New(field).Parse(source)
+	if err == nil {
+		err = tmpl.Execute(ioutil.Discard, SlackTemplateData{})
+	}
+	if err != nil {
+		v.add(field, FieldInvalid, field+" is not a valid template: "+err.Error())
+	}
+}
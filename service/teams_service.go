@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
 )
 
 // Teams is the service in charge of interact with the teams table in the database.
@@ -18,48 +19,130 @@ func NewTeams(store db.Store) *Teams {
 
 // ListTeams gets a list of teams from the DB.
 func (s *Teams) ListTeams(ctx context.Context) ([]db.ListTeamsRow, error) {
+	ctx, span := tracer.Start(ctx, "Teams.ListTeams")
+	defer span.End()
+
 	return s.store.ListTeams(ctx)
 }
 
 // GetTeam gets a team from the DB.
 func (s *Teams) GetTeam(ctx context.Context, teamID int64) (*db.GetTeamRow, error) {
-	team, err := s.store.GetTeam(ctx, teamID)
-	if err != nil {
-		return nil, err
-	}
+	ctx, span := tracer.Start(ctx, "Teams.GetTeam")
+	defer span.End()
 
-	return &team, nil
+	return getTeam(ctx, s.store, teamID)
 }
 
 // AddTeam adds a team to the DB.
 func (s *Teams) AddTeam(ctx context.Context, teamName string) (*db.GetTeamRow, error) {
-	result, err := s.store.CreateTeam(ctx, teamName)
-	if err != nil {
+	ctx, span := tracer.Start(ctx, "Teams.AddTeam")
+	defer span.End()
+
+	teamName = normalizeText(teamName)
+	if err := validateTeamName(teamName); err != nil {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
+	var team *db.GetTeamRow
+	err := s.store.ExecTx(ctx, func(q db.Querier) error {
+		result, err := q.CreateTeam(ctx, teamName)
+		if err != nil {
+			return dbError(err, errTeamNameTaken())
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		team, err = getTeam(ctx, q, id)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, event.TeamCreated, team.ID, TeamEventData{Team: *team})
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &db.GetTeamRow{
-		ID:   id,
-		Name: teamName,
-	}, nil
+	return team, nil
 }
 
-// UpdateTeam updates a team name in the DB.
-func (s *Teams) UpdateTeam(ctx context.Context, args db.UpdateTeamParams) error {
-	_, err := s.store.UpdateTeam(ctx, args)
+// UpdateTeam updates a team name in the DB and returns the updated team.
+func (s *Teams) UpdateTeam(ctx context.Context, args db.UpdateTeamParams) (*db.GetTeamRow, error) {
+	ctx, span := tracer.Start(ctx, "Teams.UpdateTeam")
+	defer span.End()
+
+	args.Name = normalizeText(args.Name)
+	if err := validateTeamName(args.Name); err != nil {
+		return nil, err
+	}
+	if args.Version <= 0 {
+		return nil, VersionRequired(nil)
+	}
+
+	var team *db.GetTeamRow
+	err := s.store.ExecTx(ctx, func(q db.Querier) error {
+		result, err := q.UpdateTeam(ctx, args)
+		if err != nil {
+			return dbError(err, errTeamNameTaken())
+		}
+		ok, err := updated(result)
+		if err != nil {
+			return err
+		}
+
+		team, err = getTeam(ctx, q, args.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return versionConflict(team)
+		}
+
+		return recordEvent(ctx, q, event.TeamUpdated, team.ID, TeamEventData{Team: *team})
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return team, nil
 }
 
 // DeleteTeam deletes a team from the DB
 func (s *Teams) DeleteTeam(ctx context.Context, teamID int64) error {
-	return s.store.DeleteTeam(ctx, teamID)
+	ctx, span := tracer.Start(ctx, "Teams.DeleteTeam")
+	defer span.End()
+
+	return s.store.ExecTx(ctx, func(q db.Querier) error {
+		err := q.DeleteTeam(ctx, teamID)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, event.TeamDeleted, teamID, TeamEventData{Team: db.GetTeamRow{ID: teamID}})
+	})
+}
+
+// getTeam gets a team using q, so it can run inside a transaction.
+func getTeam(ctx context.Context, q db.Querier, teamID int64) (*db.GetTeamRow, error) {
+	team, err := q.GetTeam(ctx, teamID)
+	if err != nil {
+		return nil, notFound(err, NotFound(CodeTeamNotFound, "Team not found."))
+	}
+
+	return &team, nil
+}
+
+// validateTeamName checks a normalised team name fits the schema.
+func validateTeamName(name string) error {
+	v := validator{}
+	v.text("name", name, MaxTeamNameLength)
+	return v.err()
+}
+
+// errTeamNameTaken is returned when the team name is already in use.
+func errTeamNameTaken() *Error {
+	return Conflict(CodeTeamNameTaken, "A team with that name already exists.")
 }
@@ -0,0 +1,6 @@
+package service
+
+import "go.opentelemetry.io/otel"
+
+// tracer traces the service methods, each in a span named after it.
+var tracer = otel.Tracer("github.com/ezerw/wheel/service")
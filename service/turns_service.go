@@ -2,9 +2,12 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"math/big"
 	"time"
 
 	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
 )
 
 // Turns is the service in charge of interact with the turns table in the database.
@@ -18,6 +21,8 @@ type TurnAPI struct {
 	PersonID  int64     `json:"person_id"`
 	Date      time.Time `json:"date"`
 	CreatedAt time.Time `json:"created_at"`
+	Version   int32     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // NewTurns creates a new TeamsService instance.
@@ -34,6 +39,9 @@ func (s *Turns) ListTurns(
 	limit int64,
 	offset int64,
 ) ([]TurnAPI, error) {
+	ctx, span := tracer.Start(ctx, "Turns.ListTurns")
+	defer span.End()
+
 	if !dateFrom.IsZero() && !dateTo.IsZero() {
 		args := db.ListTurnsWithBothDatesParams{
 			TeamID: teamID,
@@ -55,6 +63,8 @@ func (s *Turns) ListTurns(
 				PersonID:  turn.PersonID,
 				Date:      turn.Date,
 				CreatedAt: turn.CreatedAt.Time,
+				Version:   turn.Version,
+				UpdatedAt: turn.UpdatedAt,
 			})
 		}
 
@@ -79,6 +89,8 @@ func (s *Turns) ListTurns(
 				PersonID:  turn.PersonID,
 				Date:      turn.Date,
 				CreatedAt: turn.CreatedAt.Time,
+				Version:   turn.Version,
+				UpdatedAt: turn.UpdatedAt,
 			})
 		}
 		return turns, nil
@@ -103,6 +115,8 @@ func (s *Turns) ListTurns(
 				PersonID:  turn.PersonID,
 				Date:      turn.Date,
 				CreatedAt: turn.CreatedAt.Time,
+				Version:   turn.Version,
+				UpdatedAt: turn.UpdatedAt,
 			})
 		}
 
@@ -126,6 +140,8 @@ func (s *Turns) ListTurns(
 			PersonID:  turn.PersonID,
 			Date:      turn.Date,
 			CreatedAt: turn.CreatedAt.Time,
+			Version:   turn.Version,
+			UpdatedAt: turn.UpdatedAt,
 		})
 	}
 
@@ -134,26 +150,175 @@ func (s *Turns) ListTurns(
 
 // GetTurn gets one turn from the DB using id and teamID as params.
 func (s *Turns) GetTurn(ctx context.Context, args db.GetTurnParams) (*TurnAPI, error) {
-	turn, err := s.store.GetTurn(ctx, args)
+	ctx, span := tracer.Start(ctx, "Turns.GetTurn")
+	defer span.End()
+
+	return getTurn(ctx, s.store, args)
+}
+
+// GetTurnByDate gets one turn from the DB using date and teamID as params.
+func (s *Turns) GetTurnByDate(ctx context.Context, args db.GetTurnByDateAndTeamParams) (*TurnAPI, error) {
+	ctx, span := tracer.Start(ctx, "Turns.GetTurnByDate")
+	defer span.End()
+
+	return getTurnByDate(ctx, s.store, args)
+}
+
+// AssignTurn assigns the turn of the team on date to the person, creating the
+// turn or reassigning it if the team already has one on that date.
+// DB unique: (team_id, date) - A team can't have multiple people assigned for the same date.
+func (s *Turns) AssignTurn(ctx context.Context, teamID int64, personID int64, date time.Time) (*TurnAPI, error) {
+	ctx, span := tracer.Start(ctx, "Turns.AssignTurn")
+	defer span.End()
+
+	return s.assignTurn(ctx, teamID, personID, date, nil)
+}
+
+// AssignTurnAtVersion is AssignTurn for a caller which read the turn of the
+// team on date at version: it fails with a version conflict carrying the
+// current turn when it has changed since. Version 0 is for a caller which
+// found no turn, reassigning one requires its version.
+func (s *Turns) AssignTurnAtVersion(
+	ctx context.Context,
+	teamID int64,
+	personID int64,
+	date time.Time,
+	version int32,
+) (*TurnAPI, error) {
+	ctx, span := tracer.Start(ctx, "Turns.AssignTurnAtVersion")
+	defer span.End()
+
+	return s.assignTurn(ctx, teamID, personID, date, &version)
+}
+
+// assignTurn assigns the turn, checking its version unless it's nil.
+func (s *Turns) assignTurn(ctx context.Context, teamID int64, personID int64, date time.Time, version *int32) (*TurnAPI, error) {
+	var turn *TurnAPI
+	err := s.store.ExecTx(ctx, func(q db.Querier) error {
+		person, err := getPerson(ctx, q, db.GetPersonParams{
+			ID:     personID,
+			TeamID: teamID,
+		})
+		if err != nil {
+			return err
+		}
+
+		getTurnArgs := db.GetTurnByDateAndTeamParams{
+			Date:   date,
+			TeamID: teamID,
+		}
+		turn, err = getTurnByDate(ctx, q, getTurnArgs)
+		if err != nil {
+			if KindOf(err) != KindNotFound {
+				return err
+			}
+
+			turn, err = addTurn(ctx, q, teamID, person.ID, date)
+			if err != nil {
+				return err
+			}
+
+			return recordEvent(ctx, q, event.TurnAssigned, teamID, TurnEventData{Turn: *turn, Person: *person})
+		}
+
+		if version != nil && *version <= 0 {
+			return VersionRequired(turn)
+		}
+		if version != nil && turn.Version != *version {
+			return versionConflict(turn)
+		}
+		if turn.PersonID == person.ID {
+			return nil
+		}
+
+		previousPersonID := turn.PersonID
+		updateTurnArgs := db.UpdateTurnParams{
+			PersonID: person.ID,
+			Date:     turn.Date,
+			ID:       turn.ID,
+			Version:  turn.Version,
+		}
+		turn, err = updateTurn(ctx, q, teamID, updateTurnArgs)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, event.TurnReassigned, teamID, TurnEventData{
+			Turn:             *turn,
+			Person:           *person,
+			PreviousPersonID: previousPersonID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return turn, nil
+}
+
+// PickPerson picks a random member of the team other than whoever had the
+// latest turn and the excluded people, unless nobody else is left.
+func (s *Turns) PickPerson(ctx context.Context, teamID int64, exclude ...int64) (*db.ListPeopleRow, error) {
+	ctx, span := tracer.Start(ctx, "Turns.PickPerson")
+	defer span.End()
+
+	people, err := s.store.ListPeople(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if len(people) == 0 {
+		return nil, Conflict(CodeTeamEmpty, "The team has no people to pick from.")
+	}
+
+	latest, err := s.store.ListTurns(ctx, db.ListTurnsParams{TeamID: teamID, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(latest) == 1 {
+		exclude = append(exclude, latest[0].PersonID)
+	}
+
+	candidates := []db.ListPeopleRow{}
+	for _, person := range people {
+		if !containsID(exclude, person.ID) {
+			candidates = append(candidates, person)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = people
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(candidates))))
 	if err != nil {
 		return nil, err
 	}
+	return &candidates[n.Int64()], nil
+}
+
+// getTurn gets one turn of a team using q, so it can run inside a transaction.
+func getTurn(ctx context.Context, q db.Querier, args db.GetTurnParams) (*TurnAPI, error) {
+	turn, err := q.GetTurn(ctx, args)
+	if err != nil {
+		return nil, notFound(err, errTurnNotFound())
+	}
 
 	apiTurn := &TurnAPI{
 		ID:        turn.ID,
 		PersonID:  turn.PersonID,
 		Date:      turn.Date,
 		CreatedAt: turn.CreatedAt.Time,
+		Version:   turn.Version,
+		UpdatedAt: turn.UpdatedAt,
 	}
 
 	return apiTurn, nil
 }
 
-// GetTurnByDate gets one turn from the DB using date and teamID as params.
-func (s *Turns) GetTurnByDate(ctx context.Context, args db.GetTurnByDateAndTeamParams) (*TurnAPI, error) {
-	turn, err := s.store.GetTurnByDateAndTeam(ctx, args)
+// getTurnByDate gets the turn of a team on a date using q.
+func getTurnByDate(ctx context.Context, q db.Querier, args db.GetTurnByDateAndTeamParams) (*TurnAPI, error) {
+	turn, err := q.GetTurnByDateAndTeam(ctx, args)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, errTurnNotFound())
 	}
 
 	apiTurn := &TurnAPI{
@@ -161,21 +326,23 @@ func (s *Turns) GetTurnByDate(ctx context.Context, args db.GetTurnByDateAndTeamP
 		PersonID:  turn.PersonID,
 		Date:      turn.Date,
 		CreatedAt: turn.CreatedAt.Time,
+		Version:   turn.Version,
+		UpdatedAt: turn.UpdatedAt,
 	}
 
 	return apiTurn, nil
 }
 
-// AddTurn adds a turn to the DB for the specified team.
-func (s *Turns) AddTurn(ctx context.Context, teamID int64, personID int64, date time.Time) (*TurnAPI, error) {
+// addTurn adds a turn for the specified team using q.
+func addTurn(ctx context.Context, q db.Querier, teamID int64, personID int64, date time.Time) (*TurnAPI, error) {
 	args := db.CreateTurnParams{
 		PersonID: personID,
 		Date:     date,
 	}
 
-	result, err := s.store.CreateTurn(ctx, args)
+	result, err := q.CreateTurn(ctx, args)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, errTurnTaken())
 	}
 
 	id, err := result.LastInsertId()
@@ -183,29 +350,53 @@ func (s *Turns) AddTurn(ctx context.Context, teamID int64, personID int64, date
 		return nil, err
 	}
 
-	getTurnArgs := db.GetTurnParams{
+	return getTurn(ctx, q, db.GetTurnParams{
 		ID:     id,
 		TeamID: teamID,
-	}
+	})
+}
 
-	turn, err := s.GetTurn(ctx, getTurnArgs)
+// updateTurn updates a turn of the specified team using q, unless it's no
+// longer at the version of args.
+func updateTurn(ctx context.Context, q db.Querier, teamID int64, args db.UpdateTurnParams) (*TurnAPI, error) {
+	result, err := q.UpdateTurn(ctx, args)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, errTurnTaken())
 	}
-
-	return turn, nil
-}
-
-// UpdateTurn updates a turn in the DB.
-func (s *Turns) UpdateTurn(ctx context.Context, teamID int64, args db.UpdateTurnParams) (*TurnAPI, error) {
-	_, err := s.store.UpdateTurn(ctx, args)
+	ok, err := updated(result)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.GetTurn(ctx, db.GetTurnParams{
+	turn, err := getTurn(ctx, q, db.GetTurnParams{
 		ID:     args.ID,
 		TeamID: teamID,
 	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, versionConflict(turn)
+	}
+	return turn, nil
+}
+
+// containsID reports whether id is one of ids.
+func containsID(ids []int64, id int64) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// errTurnNotFound is returned when the turn does not exist in the team.
+func errTurnNotFound() *Error {
+	return NotFound(CodeTurnNotFound, "Turn not found.")
+}
 
+// errTurnTaken is returned when the person already has a turn on that date.
+func errTurnTaken() *Error {
+	return Conflict(CodeTurnTaken, "The person already has a turn on that date.")
 }
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Length limits derived from the varchar columns of the schema.
+const (
+	MaxTeamNameLength   = 100
+	MaxPersonNameLength = 100
+	MaxEmailLength      = 80
+)
+
+// CodeValidationFailed is the code of errors carrying field-level details.
+const CodeValidationFailed = "validation_failed"
+
+// Field-level error codes.
+const (
+	FieldRequired     = "required"
+	FieldTooLong      = "too_long"
+	FieldInvalidEmail = "invalid_email"
+	FieldInvalid      = "invalid"
+)
+
+// FieldError describes why a single input field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// InvalidFields creates a KindValidation error carrying field-level details.
+func InvalidFields(fields ...FieldError) *Error {
+	e := Validation(CodeValidationFailed, "One or more fields are invalid.")
+	e.Fields = fields
+	return e
+}
+
+// validator accumulates field errors so every problem is reported at once.
+type validator struct {
+	fields []FieldError
+}
+
+// add records a field error.
+func (v *validator) add(field string, code string, message string) {
+	v.fields = append(v.fields, FieldError{Field: field, Code: code, Message: message})
+}
+
+// text checks a required free-text field against its column length.
+func (v *validator) text(field string, value string, max int) {
+	if value == "" {
+		v.add(field, FieldRequired, fmt.Sprintf("%s is required.", field))
+		return
+	}
+	if utf8.RuneCountInString(value) > max {
+		v.add(field, FieldTooLong, fmt.Sprintf("%s must be at most %d characters.", field, max))
+	}
+}
+
+// email checks a required field holds a bare email address.
+func (v *validator) email(field string, value string) {
+	v.text(field, value, MaxEmailLength)
+	if value == "" {
+		return
+	}
+
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Name != "" || addr.Address != value || !strings.Contains(value, "@") {
+		v.add(field, FieldInvalidEmail, fmt.Sprintf("%s must be a valid email address.", field))
+	}
+}
+
+// url checks a required field holds an absolute http or https URL.
+func (v *validator) url(field string, value string, max int) {
+	v.text(field, value, max)
+	if value == "" {
+		return
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		v.add(field, FieldInvalid, fmt.Sprintf("%s must be an absolute http or https URL.", field))
+	}
+}
+
+// err returns the accumulated errors as a validation error, or nil.
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return InvalidFields(v.fields...)
+}
+
+// normalizeText trims surrounding whitespace and collapses inner runs of it.
+func normalizeText(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// normalizeEmail trims and lower-cases an email address.
+func normalizeEmail(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
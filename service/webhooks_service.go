@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
+	"github.com/ezerw/wheel/util"
+)
+
+// Length limits of the webhooks columns.
+const (
+	MaxWebhookURLLength    = 2048
+	MaxWebhookSecretLength = 100
+	MinWebhookSecretLength = 16
+	MaxWebhookEventsLength = 500
+)
+
+// AllEvents subscribes a webhook to every event type.
+const AllEvents = "*"
+
+// Webhooks is the service in charge of interact with the webhooks tables in the database.
+type Webhooks struct {
+	store db.Store
+}
+
+// WebhookAPI is the representation returned to the client, the secret is
+// only included when the webhook is created.
+type WebhookAPI struct {
+	ID        int64        `json:"id"`
+	TeamID    int64        `json:"team_id"`
+	URL       string       `json:"url"`
+	Events    []event.Type `json:"events"`
+	Secret    string       `json:"secret,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// WebhookDeliveryAPI is the representation of a delivery attempt returned to the client.
+type WebhookDeliveryAPI struct {
+	ID         int64      `json:"id"`
+	WebhookID  int64      `json:"webhook_id"`
+	EventID    string     `json:"event_id"`
+	EventType  event.Type `json:"event_type"`
+	Attempt    int32      `json:"attempt"`
+	Succeeded  bool       `json:"succeeded"`
+	StatusCode int32      `json:"status_code,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	DurationMs int32      `json:"duration_ms"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewWebhooks creates a new WebhooksService instance.
+func NewWebhooks(store db.Store) *Webhooks {
+	return &Webhooks{store: store}
+}
+
+// ListWebhooks gets the webhooks of a team from the DB.
+func (s *Webhooks) ListWebhooks(ctx context.Context, teamID int64) ([]WebhookAPI, error) {
+	ctx, span := tracer.Start(ctx, "Webhooks.ListWebhooks")
+	defer span.End()
+
+	dbWebhooks, err := s.store.ListWebhooks(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := []WebhookAPI{}
+	for _, webhook := range dbWebhooks {
+		webhooks = append(webhooks, toWebhookAPI(webhook))
+	}
+	return webhooks, nil
+}
+
+// GetWebhook gets one webhook of the team from the DB.
+func (s *Webhooks) GetWebhook(ctx context.Context, args db.GetWebhookParams) (*WebhookAPI, error) {
+	ctx, span := tracer.Start(ctx, "Webhooks.GetWebhook")
+	defer span.End()
+
+	webhook, err := s.store.GetWebhook(ctx, args)
+	if err != nil {
+		return nil, notFound(err, NotFound(CodeWebhookNotFound, "Webhook not found in the specified team."))
+	}
+
+	apiWebhook := toWebhookAPI(webhook)
+	return &apiWebhook, nil
+}
+
+// AddWebhook subscribes url to events of the team. A secret is generated
+// when none is given, it's returned only by this method.
+func (s *Webhooks) AddWebhook(
+	ctx context.Context,
+	teamID int64,
+	webhookURL string,
+	events []event.Type,
+	secret string,
+) (*WebhookAPI, error) {
+	ctx, span := tracer.Start(ctx, "Webhooks.AddWebhook")
+	defer span.End()
+
+	webhookURL = strings.TrimSpace(webhookURL)
+	v := validator{}
+	validateWebhook(&v, webhookURL, events)
+	if secret != "" && (len(secret) < MinWebhookSecretLength || len(secret) > MaxWebhookSecretLength) {
+		v.add("secret", FieldInvalid, "secret must be between 16 and 100 characters.")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if secret == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(key)
+	}
+
+	result, err := s.store.CreateWebhook(ctx, db.CreateWebhookParams{
+		TeamID: teamID,
+		Url:    webhookURL,
+		Secret: secret,
+		Events: joinEvents(events),
+	})
+	if err != nil {
+		return nil, dbError(err, nil)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := s.GetWebhook(ctx, db.GetWebhookParams{
+		ID:     id,
+		TeamID: teamID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.Secret = secret
+	return webhook, nil
+}
+
+// UpdateWebhook changes the url and events of a webhook of the team.
+func (s *Webhooks) UpdateWebhook(
+	ctx context.Context,
+	teamID int64,
+	webhookID int64,
+	webhookURL string,
+	events []event.Type,
+) (*WebhookAPI, error) {
+	ctx, span := tracer.Start(ctx, "Webhooks.UpdateWebhook")
+	defer span.End()
+
+	webhookURL = strings.TrimSpace(webhookURL)
+	v := validator{}
+	validateWebhook(&v, webhookURL, events)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	getWebhookArgs := db.GetWebhookParams{
+		ID:     webhookID,
+		TeamID: teamID,
+	}
+	if _, err := s.GetWebhook(ctx, getWebhookArgs); err != nil {
+		return nil, err
+	}
+
+	_, err := s.store.UpdateWebhook(ctx, db.UpdateWebhookParams{
+		Url:    webhookURL,
+		Events: joinEvents(events),
+		ID:     webhookID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetWebhook(ctx, getWebhookArgs)
+}
+
+// DeleteWebhook deletes a webhook of the team from the DB.
+func (s *Webhooks) DeleteWebhook(ctx context.Context, args db.DeleteWebhookParams) error {
+	ctx, span := tracer.Start(ctx, "Webhooks.DeleteWebhook")
+	defer span.End()
+
+	return s.store.DeleteWebhook(ctx, args)
+}
+
+// ListDeliveries gets the delivery attempts of a webhook, most recent first.
+func (s *Webhooks) ListDeliveries(ctx context.Context, args db.ListWebhookDeliveriesParams) ([]WebhookDeliveryAPI, error) {
+	ctx, span := tracer.Start(ctx, "Webhooks.ListDeliveries")
+	defer span.End()
+
+	dbDeliveries, err := s.store.ListWebhookDeliveries(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []WebhookDeliveryAPI{}
+	for _, delivery := range dbDeliveries {
+		deliveries = append(deliveries, WebhookDeliveryAPI{
+			ID:         delivery.ID,
+			WebhookID:  delivery.WebhookID,
+			EventID:    delivery.EventID,
+			EventType:  event.Type(delivery.EventType),
+			Attempt:    delivery.Attempt,
+			Succeeded:  delivery.Succeeded,
+			StatusCode: delivery.StatusCode.Int32,
+			Error:      delivery.Error.String,
+			DurationMs: delivery.DurationMs,
+			CreatedAt:  delivery.CreatedAt.Time,
+		})
+	}
+	return deliveries, nil
+}
+
+// Subscribed reports whether the webhook wants events of the given type.
+func Subscribed(webhook db.Webhook, eventType event.Type) bool {
+	for _, name := range strings.Split(webhook.Events, ",") {
+		if name == AllEvents || event.Type(name) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookEvent reports whether webhooks can receive events of type t. The
+// webhooks of a team are only created after team.created and are deleted
+// along with the team, so neither event ever reaches one.
+func webhookEvent(t event.Type) bool {
+	return t != event.TeamCreated && t != event.TeamDeleted
+}
+
+// validateWebhook checks the url and events of a webhook.
+func validateWebhook(v *validator, webhookURL string, events []event.Type) {
+	v.url("url", webhookURL, MaxWebhookURLLength)
+	if parsed, err := url.Parse(webhookURL); err == nil && !publicHost(parsed.Hostname()) {
+		v.add("url", FieldInvalid, "url must not point to a loopback, link-local or private address.")
+	}
+
+	if len(events) == 0 {
+		v.add("events", FieldRequired, "events is required.")
+		return
+	}
+	for _, eventType := range events {
+		switch {
+		case eventType == AllEvents:
+		case !eventType.IsValid():
+			v.add("events", FieldInvalid, "events contains an unknown event type: "+string(eventType)+".")
+		case !webhookEvent(eventType):
+			v.add("events", FieldInvalid, "events contains an event type webhooks never receive: "+string(eventType)+".")
+		}
+	}
+	if len(joinEvents(events)) > MaxWebhookEventsLength {
+		v.add("events", FieldTooLong, "events has too many event types.")
+	}
+}
+
+// publicHost reports whether host may be on the internet. Host names are
+// resolved when they're dialled, so only the ones of the machine itself are
+// rejected here and the dispatcher checks the addresses they resolve to.
+func publicHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return util.IsPublicIP(ip)
+	}
+	return !util.IsLocalHostname(host)
+}
+
+// joinEvents stores event types as a comma separated list.
+func joinEvents(events []event.Type) string {
+	names := make([]string, 0, len(events))
+	for _, eventType := range events {
+		names = append(names, string(eventType))
+	}
+	return strings.Join(names, ",")
+}
+
+// toWebhookAPI converts a webhook row, leaving out its secret.
+func toWebhookAPI(webhook db.Webhook) WebhookAPI {
+	events := []event.Type{}
+	for _, name := range strings.Split(webhook.Events, ",") {
+		events = append(events, event.Type(name))
+	}
+
+	return WebhookAPI{
+		ID:        webhook.ID,
+		TeamID:    webhook.TeamID,
+		URL:       webhook.Url,
+		Events:    events,
+		CreatedAt: webhook.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,419 @@
+// Package session runs the shared spin sessions of the teams: everyone
+// watching the wheel of a team sees the same spin at the same time.
+package session
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/service"
+)
+
+// Message types sent to the watchers.
+const (
+	// TypeWelcome is sent on joining, with the watchers and the current or
+	// last spin of the team.
+	TypeWelcome = "welcome"
+	// TypePresence is sent when someone joins or leaves.
+	TypePresence = "presence"
+	// TypeSpinStarted is sent when someone spins the wheel.
+	TypeSpinStarted = "spin.started"
+	// TypeSpinFinished is sent when the wheel stops and the turn is assigned.
+	TypeSpinFinished = "spin.finished"
+	// TypeSpinFailed is sent when the turn couldn't be assigned.
+	TypeSpinFailed = "spin.failed"
+	// TypeError is sent to a watcher whose request was rejected.
+	TypeError = "error"
+)
+
+// Statuses of a spin.
+const (
+	StatusSpinning = "spinning"
+	StatusFinished = "finished"
+	StatusFailed   = "failed"
+)
+
+// Length of a spin unless the hub is given one, messages a watcher can fall
+// behind by, and how long the wheel has to assign the picked turn.
+const (
+	defaultDuration = 5 * time.Second
+	defaultBuffer   = 32
+	assignTimeout   = 10 * time.Second
+)
+
+// Wheel picks and assigns the hosts of the turns.
+type Wheel interface {
+	// Candidates returns the people the wheel shows.
+	Candidates(ctx context.Context, teamID int64) ([]db.ListPeopleRow, error)
+	// Pick picks the host of the next turn.
+	Pick(ctx context.Context, teamID int64) (*db.ListPeopleRow, error)
+	// Assign assigns the next turn to the person picked.
+	Assign(ctx context.Context, teamID int64, personID int64) (*service.TurnAPI, error)
+}
+
+// Watcher is someone following the wheel of a team.
+type Watcher struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Candidate is a person on the wheel.
+type Candidate struct {
+	PersonID  int64  `json:"person_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// Spin is a spin of the wheel. The candidates are in the order they're on
+// the wheel, shuffled with Seed, and the wheel stops on the candidate at
+// Landing after DurationMs.
+type Spin struct {
+	ID         string           `json:"id"`
+	Status     string           `json:"status"`
+	StartedBy  Watcher          `json:"started_by"`
+	StartedAt  time.Time        `json:"started_at"`
+	Candidates []Candidate      `json:"candidates"`
+	Seed       int64            `json:"seed"`
+	Landing    int              `json:"landing"`
+	DurationMs int64            `json:"duration_ms"`
+	ElapsedMs  int64            `json:"elapsed_ms"`
+	Turn       *service.TurnAPI `json:"turn,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// Message is sent to the watchers.
+type Message struct {
+	Type     string    `json:"type"`
+	You      *Watcher  `json:"you,omitempty"`
+	Watchers []Watcher `json:"watchers,omitempty"`
+	Spin     *Spin     `json:"spin,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Hub holds the sessions of the teams being watched.
+type Hub struct {
+	wheel    Wheel
+	duration time.Duration
+
+	mu     sync.Mutex
+	rooms  map[int64]*room
+	closed bool
+	// spins counts the spins whose turn isn't assigned yet.
+	spins sync.WaitGroup
+}
+
+// room is the session of a team.
+type room struct {
+	teamID  int64
+	clients map[*Client]struct{}
+	// spin is the current or last spin, picking is set while the person
+	// of a new one is picked.
+	spin    *Spin
+	picking bool
+}
+
+// Client is the connection of a watcher to the session of a team.
+type Client struct {
+	hub      *Hub
+	teamID   int64
+	watcher  Watcher
+	messages chan Message
+	closed   bool
+}
+
+// NewHub creates a Hub spinning wheel, spins last duration or 5 seconds
+// when it isn't positive.
+func NewHub(wheel Wheel, duration time.Duration) *Hub {
+	if duration <= 0 {
+		duration = defaultDuration
+	}
+	return &Hub{
+		wheel:    wheel,
+		duration: duration,
+		rooms:    map[int64]*room{},
+	}
+}
+
+// Join adds a watcher named name to the session of a team. The client
+// receives a welcome message, late joiners find the spin in progress in it
+// with how long it has been spinning. Once the hub is closed the client is
+// closed right away.
+func (h *Hub) Join(teamID int64, name string) *Client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c := &Client{
+		hub:      h,
+		teamID:   teamID,
+		watcher:  Watcher{ID: newID(), Name: name},
+		messages: make(chan Message, defaultBuffer),
+	}
+	if h.closed {
+		c.close()
+		return c
+	}
+
+	r := h.rooms[teamID]
+	if r == nil {
+		r = &room{teamID: teamID, clients: map[*Client]struct{}{}}
+		h.rooms[teamID] = r
+	}
+	r.clients[c] = struct{}{}
+
+	c.send(Message{Type: TypeWelcome, You: &c.watcher, Watchers: r.watchers(), Spin: r.snapshot()})
+	h.broadcast(r, Message{Type: TypePresence, Watchers: r.watchers()})
+	return c
+}
+
+// Watcher returns who the client is.
+func (c *Client) Watcher() Watcher {
+	return c.watcher
+}
+
+// Messages returns the channel the messages are sent to, it's closed when
+// the client leaves or is too slow to keep up.
+func (c *Client) Messages() <-chan Message {
+	return c.messages
+}
+
+// Spin spins the wheel of the team, unless it's already spinning.
+func (c *Client) Spin(ctx context.Context) {
+	h := c.hub
+	h.mu.Lock()
+	r := h.rooms[c.teamID]
+	if c.closed || r == nil {
+		h.mu.Unlock()
+		return
+	}
+	if r.picking || (r.spin != nil && r.spin.Status == StatusSpinning) {
+		c.send(Message{Type: TypeError, Error: "The wheel is already spinning."})
+		h.mu.Unlock()
+		return
+	}
+	r.picking = true
+	h.mu.Unlock()
+
+	spin, err := h.newSpin(ctx, c)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r.picking = false
+	if err != nil {
+		c.send(Message{Type: TypeError, Error: errorMessage(err)})
+		h.cleanup(r)
+		return
+	}
+	if h.closed {
+		h.cleanup(r)
+		return
+	}
+
+	r.spin = spin
+	h.broadcast(r, Message{Type: TypeSpinStarted, Spin: r.snapshot()})
+	h.spins.Add(1)
+	time.AfterFunc(h.duration, func() {
+		h.finish(r, spin, spin.Candidates[spin.Landing].PersonID)
+	})
+}
+
+// Leave removes the client from the session.
+func (c *Client) Leave() {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r := h.rooms[c.teamID]
+	c.close()
+	if r == nil {
+		return
+	}
+	delete(r.clients, c)
+	h.broadcast(r, Message{Type: TypePresence, Watchers: r.watchers()})
+	h.cleanup(r)
+}
+
+// Close ends every session and rejects new ones, then waits for the wheels
+// spinning to stop and their turns to be assigned.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	for _, r := range h.rooms {
+		for c := range r.clients {
+			c.close()
+			delete(r.clients, c)
+		}
+	}
+	h.mu.Unlock()
+
+	h.spins.Wait()
+}
+
+// newSpin picks the host and lays out the wheel for a spin started by c.
+func (h *Hub) newSpin(ctx context.Context, c *Client) (*Spin, error) {
+	people, err := h.wheel.Candidates(ctx, c.teamID)
+	if err != nil {
+		return nil, err
+	}
+	picked, err := h.wheel.Pick(ctx, c.teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := newSeed()
+	rand.New(rand.NewSource(seed)).Shuffle(len(people), func(i, j int) {
+		people[i], people[j] = people[j], people[i]
+	})
+
+	spin := &Spin{
+		ID:         newID(),
+		Status:     StatusSpinning,
+		StartedBy:  c.watcher,
+		StartedAt:  time.Now().UTC(),
+		Candidates: []Candidate{},
+		Seed:       seed,
+		Landing:    -1,
+		DurationMs: int64(h.duration / time.Millisecond),
+	}
+	for i, person := range people {
+		spin.Candidates = append(spin.Candidates, Candidate{
+			PersonID:  person.ID,
+			FirstName: person.FirstName,
+			LastName:  person.LastName,
+		})
+		if person.ID == picked.ID {
+			spin.Landing = i
+		}
+	}
+	// The person was added after the candidates were listed.
+	if spin.Landing < 0 {
+		spin.Candidates = append(spin.Candidates, Candidate{
+			PersonID:  picked.ID,
+			FirstName: picked.FirstName,
+			LastName:  picked.LastName,
+		})
+		spin.Landing = len(spin.Candidates) - 1
+	}
+	return spin, nil
+}
+
+// finish assigns the turn once the wheel stops, and announces it.
+func (h *Hub) finish(r *room, spin *Spin, personID int64) {
+	defer h.spins.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), assignTimeout)
+	defer cancel()
+	turn, err := h.wheel.Assign(ctx, r.teamID, personID)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	message := Message{Type: TypeSpinFinished}
+	if err != nil {
+		spin.Status = StatusFailed
+		spin.Error = errorMessage(err)
+		message.Type = TypeSpinFailed
+	} else {
+		spin.Status = StatusFinished
+		spin.Turn = turn
+	}
+	message.Spin = r.snapshot()
+	h.broadcast(r, message)
+	h.cleanup(r)
+}
+
+// broadcast sends message to every client of r, h.mu must be held.
+func (h *Hub) broadcast(r *room, message Message) {
+	for c := range r.clients {
+		if !c.send(message) {
+			delete(r.clients, c)
+		}
+	}
+}
+
+// cleanup forgets r once nobody watches it and it isn't spinning, h.mu
+// must be held.
+func (h *Hub) cleanup(r *room) {
+	if len(r.clients) == 0 && !r.picking && (r.spin == nil || r.spin.Status != StatusSpinning) {
+		if h.rooms[r.teamID] == r {
+			delete(h.rooms, r.teamID)
+		}
+	}
+}
+
+// watchers lists who watches r, h.mu must be held.
+func (r *room) watchers() []Watcher {
+	watchers := []Watcher{}
+	for c := range r.clients {
+		watchers = append(watchers, c.watcher)
+	}
+	return watchers
+}
+
+// snapshot copies the spin of r with how long it has been spinning, h.mu
+// must be held.
+func (r *room) snapshot() *Spin {
+	if r.spin == nil {
+		return nil
+	}
+	spin := *r.spin
+	spin.ElapsedMs = int64(time.Since(spin.StartedAt) / time.Millisecond)
+	if spin.ElapsedMs > spin.DurationMs {
+		spin.ElapsedMs = spin.DurationMs
+	}
+	return &spin
+}
+
+// send queues message without blocking, a client too slow to keep up is
+// closed. It reports whether the client is still open, h.mu must be held.
+func (c *Client) send(message Message) bool {
+	if c.closed {
+		return false
+	}
+	select {
+	case c.messages <- message:
+		return true
+	default:
+		c.close()
+		return false
+	}
+}
+
+// close closes the messages channel once, h.mu must be held.
+func (c *Client) close() {
+	if !c.closed {
+		c.closed = true
+		close(c.messages)
+	}
+}
+
+// errorMessage is the message of err shown to the watchers, the details of
+// unexpected errors aren't exposed.
+func errorMessage(err error) string {
+	var e *service.Error
+	if service.KindOf(err) != service.KindInternal && errors.As(err, &e) {
+		return e.Message
+	}
+	return "The wheel couldn't be spun, try again."
+}
+
+// newID returns a random identifier.
+func newID() string {
+	return strconv.FormatInt(newSeed(), 36)
+}
+
+// newSeed returns a random non-negative number.
+func newSeed() int64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return time.Now().UnixNano()
+	}
+	return int64(binary.BigEndian.Uint64(b[:]) >> 1)
+}
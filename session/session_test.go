@@ -0,0 +1,134 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/service"
+)
+
+// fakeWheel always picks the second person.
+type fakeWheel struct {
+	assigned chan int64
+}
+
+func (w *fakeWheel) Candidates(ctx context.Context, teamID int64) ([]db.ListPeopleRow, error) {
+	return []db.ListPeopleRow{
+		{ID: 1, FirstName: "Bruce", LastName: "Wayne", TeamID: teamID},
+		{ID: 2, FirstName: "Diana", LastName: "Prince", TeamID: teamID},
+		{ID: 3, FirstName: "Clark", LastName: "Kent", TeamID: teamID},
+	}, nil
+}
+
+func (w *fakeWheel) Pick(ctx context.Context, teamID int64) (*db.ListPeopleRow, error) {
+	return &db.ListPeopleRow{ID: 2, FirstName: "Diana", LastName: "Prince", TeamID: teamID}, nil
+}
+
+func (w *fakeWheel) Assign(ctx context.Context, teamID int64, personID int64) (*service.TurnAPI, error) {
+	w.assigned <- personID
+	return &service.TurnAPI{ID: 10, PersonID: personID}, nil
+}
+
+func receive(t *testing.T, c *Client, typ string) Message {
+	t.Helper()
+	select {
+	case message, ok := <-c.Messages():
+		if !ok {
+			t.Fatalf("%s: messages closed, want %s", c.Watcher().Name, typ)
+		}
+		if message.Type != typ {
+			t.Fatalf("%s: got %s message, want %s", c.Watcher().Name, message.Type, typ)
+		}
+		return message
+	case <-time.After(time.Second):
+		t.Fatalf("%s: no %s message", c.Watcher().Name, typ)
+	}
+	return Message{}
+}
+
+func TestSpinSession(t *testing.T) {
+	wheel := &fakeWheel{assigned: make(chan int64, 1)}
+	hub := NewHub(wheel, 100*time.Millisecond)
+
+	alice := hub.Join(1, "Alice")
+	welcome := receive(t, alice, TypeWelcome)
+	if welcome.You.Name != "Alice" || len(welcome.Watchers) != 1 || welcome.Spin != nil {
+		t.Fatalf("unexpected welcome %+v", welcome)
+	}
+	receive(t, alice, TypePresence)
+
+	alice.Spin(context.Background())
+	started := receive(t, alice, TypeSpinStarted).Spin
+	if started.Status != StatusSpinning || started.DurationMs != 100 || len(started.Candidates) != 3 {
+		t.Fatalf("unexpected spin %+v", started)
+	}
+	if got := started.Candidates[started.Landing].PersonID; got != 2 {
+		t.Fatalf("spin lands on person %d, want 2", got)
+	}
+
+	// Spinning again is rejected while the wheel spins.
+	alice.Spin(context.Background())
+	receive(t, alice, TypeError)
+
+	// A late joiner sees the spin in progress.
+	bob := hub.Join(1, "Bob")
+	late := receive(t, bob, TypeWelcome)
+	if late.Spin == nil || late.Spin.ID != started.ID || late.Spin.Seed != started.Seed {
+		t.Fatalf("late joiner got spin %+v, want %+v", late.Spin, started)
+	}
+	if presence := receive(t, alice, TypePresence); len(presence.Watchers) != 2 {
+		t.Fatalf("got watchers %+v, want 2", presence.Watchers)
+	}
+	receive(t, bob, TypePresence)
+
+	if personID := <-wheel.assigned; personID != 2 {
+		t.Fatalf("assigned person %d, want 2", personID)
+	}
+	for _, c := range []*Client{alice, bob} {
+		finished := receive(t, c, TypeSpinFinished).Spin
+		if finished.Status != StatusFinished || finished.Turn == nil || finished.Turn.PersonID != 2 {
+			t.Fatalf("unexpected finished spin %+v", finished)
+		}
+	}
+
+	bob.Leave()
+	if _, ok := <-bob.Messages(); ok {
+		t.Fatal("messages of a client who left are open")
+	}
+	if presence := receive(t, alice, TypePresence); len(presence.Watchers) != 1 {
+		t.Fatalf("got watchers %+v, want 1", presence.Watchers)
+	}
+	alice.Leave()
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if len(hub.rooms) != 0 {
+		t.Fatalf("%d rooms left, want none", len(hub.rooms))
+	}
+}
+
+func TestHubCloseWaitsForSpins(t *testing.T) {
+	wheel := &fakeWheel{assigned: make(chan int64, 1)}
+	hub := NewHub(wheel, 50*time.Millisecond)
+
+	alice := hub.Join(1, "Alice")
+	receive(t, alice, TypeWelcome)
+	receive(t, alice, TypePresence)
+	alice.Spin(context.Background())
+	receive(t, alice, TypeSpinStarted)
+
+	hub.Close()
+	select {
+	case <-wheel.assigned:
+	default:
+		t.Fatal("Close returned before the turn was assigned")
+	}
+	if _, ok := <-alice.Messages(); ok {
+		t.Fatal("messages are open after Close")
+	}
+	if _, ok := <-hub.Join(1, "Bob").Messages(); ok {
+		t.Fatal("joined a closed hub")
+	}
+}
@@ -0,0 +1,43 @@
+// Package signing signs payloads with HMAC-SHA256 and verifies them, for the
+// webhook deliveries the API sends and the Slack requests it receives.
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// HMAC returns the hex encoded HMAC-SHA256 of the parts, concatenated, keyed
+// with secret.
+func HMAC(secret string, parts ...[]byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks signature is the one sign returns for timestamp, a Unix
+// time. Payloads signed more than tolerance away from now are rejected so
+// they can't be replayed later.
+func Verify(timestamp string, signature string, tolerance time.Duration, sign func(timestamp string) string) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("missing or malformed timestamp header")
+	}
+
+	age := time.Since(time.Unix(seconds, 0))
+	if age > tolerance || age < -tolerance {
+		return errors.New("timestamp outside of the tolerance")
+	}
+
+	if !hmac.Equal([]byte(sign(timestamp)), []byte(signature)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Package slack posts notifications about the daily host to Slack
+// incoming webhooks.
+package slack
+
+import (
+	"bytes"
+	"text/template"
+	"time"
+
+	"github.com/ezerw/wheel/service"
+)
+
+// Default templates used when a team doesn't set its own.
+const (
+	DefaultAssignedTemplate = `*{{.Person.FirstName}} {{.Person.LastName}}* is hosting on {{.Date.Format "Monday 2 January"}}.`
+	DefaultReminderTemplate = `Good morning {{.Team.Name}}! *{{.Person.FirstName}} {{.Person.LastName}}* is hosting today.`
+)
+
+// Response types of slash command and interaction replies.
+const (
+	Ephemeral = "ephemeral"
+	InChannel = "in_channel"
+)
+
+// Message is the payload of an incoming webhook or of a reply to a slash
+// command or interaction. Text is the fallback shown in notifications,
+// Blocks the Block Kit layout shown in the channel.
+type Message struct {
+	ResponseType    string  `json:"response_type,omitempty"`
+	ReplaceOriginal bool    `json:"replace_original,omitempty"`
+	Text            string  `json:"text"`
+	Blocks          []Block `json:"blocks,omitempty"`
+}
+
+// Block is a Block Kit layout block. Elements are Text objects in context
+// blocks and Buttons in actions blocks.
+type Block struct {
+	Type     string        `json:"type"`
+	Text     *Text         `json:"text,omitempty"`
+	Elements []interface{} `json:"elements,omitempty"`
+}
+
+// Text is a Block Kit text object.
+type Text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Button is a Block Kit button element, Value is sent back when it's clicked.
+type Button struct {
+	Type     string `json:"type"`
+	Text     Text   `json:"text"`
+	ActionID string `json:"action_id"`
+	Value    string `json:"value"`
+}
+
+// Render renders the template source with data, using fallback when the
+// source is empty.
+func Render(source string, fallback string, data service.SlackTemplateData) (string, error) {
+	if source == "" {
+		source = fallback
+	}
+
+	tmpl, err := template.New("message").Parse(source)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// HostMessage lays out text announcing the host of date.
+func HostMessage(text string, date time.Time) Message {
+	return Message{
+		Text: text,
+		Blocks: []Block{
+			{
+				Type: "section",
+				Text: &Text{Type: "mrkdwn", Text: text},
+			},
+			{
+				Type: "context",
+				Elements: []interface{}{
+					Text{Type: "mrkdwn", Text: ":calendar: " + date.Format("Mon 2 Jan 2006")},
+				},
+			},
+		},
+	}
+}
+
+// WithButton adds an actions block with a single button to the message.
+func (m Message) WithButton(label string, actionID string, value string) Message {
+	m.Blocks = append(m.Blocks, Block{
+		Type: "actions",
+		Elements: []interface{}{
+			Button{
+				Type:     "button",
+				Text:     Text{Type: "plain_text", Text: label},
+				ActionID: actionID,
+				Value:    value,
+			},
+		},
+	})
+	return m
+}
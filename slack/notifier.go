@@ -0,0 +1,281 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
+	"github.com/ezerw/wheel/service"
+	"github.com/ezerw/wheel/util"
+)
+
+// defaultTimeout limits the posts to Slack unless SLACK_TIMEOUT is set,
+// defaultCheckInterval is how often the reminders are checked.
+const (
+	defaultTimeout       = 10 * time.Second
+	defaultCheckInterval = time.Minute
+)
+
+// Notifier posts to the Slack incoming webhook of a team when its turn is
+// assigned and, if enabled, every morning to remind who is hosting.
+type Notifier struct {
+	store         db.Store
+	client        *http.Client
+	webhookURLs   string
+	logger        *logrus.Logger
+	location      *time.Location
+	checkInterval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewNotifier creates a Notifier configured by the SLACK_* settings, dates
+// are in the APP_TIMEZONE.
+func NewNotifier(store db.Store, config util.Config, logger *logrus.Logger) (*Notifier, error) {
+	location, err := time.LoadLocation(config.AppTimezone)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Notifier{
+		store:         store,
+		client:        &http.Client{Timeout: defaultTimeout},
+		webhookURLs:   service.SlackWebhookURLPrefix,
+		logger:        logger,
+		location:      location,
+		checkInterval: defaultCheckInterval,
+	}
+	if config.SlackTimeout > 0 {
+		n.client.Timeout = config.SlackTimeout
+	}
+
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+	return n, nil
+}
+
+// Deliver is an outbox.Sink announcing assigned turns, failed messages are
+// retried by the outbox.
+func (n *Notifier) Deliver(ctx context.Context, e event.Event) error {
+	if e.Type != event.TurnAssigned && e.Type != event.TurnReassigned {
+		return nil
+	}
+	return n.notifyAssigned(ctx, e)
+}
+
+// StartReminders sends the morning reminders in the background until the
+// notifier is closed, checking every minute which teams are due.
+func (n *Notifier) StartReminders() {
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+
+		ticker := time.NewTicker(n.checkInterval)
+		defer ticker.Stop()
+		for {
+			n.SendReminders(n.ctx, time.Now())
+
+			select {
+			case <-n.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SendReminders reminds the host of the day to every team whose reminder
+// time has passed at now and hasn't been reminded today. Claiming the
+// reminder in the DB first makes sure only one instance of the API sends it.
+func (n *Notifier) SendReminders(ctx context.Context, now time.Time) {
+	now = now.In(n.location)
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, n.location)
+
+	reminders, err := n.store.ListSlackReminders(ctx)
+	if err != nil {
+		util.LoggerOr(ctx, n.logger).WithError(err).Error("cannot list slack reminders")
+		return
+	}
+
+	for _, settings := range reminders {
+		if now.Format("15:04") < settings.ReminderTime {
+			continue
+		}
+		if _, err = n.remind(ctx, settings, today); err != nil {
+			util.LoggerOr(ctx, n.logger).WithError(err).WithField("team_id", settings.TeamID).Error("cannot send slack reminder")
+		}
+	}
+}
+
+// RemindTeam posts today's reminder of the team without waiting for its
+// reminder time. Reminders are posted at most once a day, it reports
+// whether this one was.
+func (n *Notifier) RemindTeam(ctx context.Context, teamID int64, now time.Time) (bool, error) {
+	settings, err := n.store.GetSlackSettings(ctx, teamID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	year, month, day := now.In(n.location).Date()
+	return n.remind(ctx, settings, time.Date(year, month, day, 0, 0, 0, 0, n.location))
+}
+
+// Close abandons pending reminders and waits for in-flight messages.
+func (n *Notifier) Close() {
+	n.cancel()
+	n.wg.Wait()
+}
+
+// notifyAssigned announces the host of the turn of e.
+func (n *Notifier) notifyAssigned(ctx context.Context, e event.Event) error {
+	var data service.TurnEventData
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		return err
+	}
+
+	settings, err := n.store.GetSlackSettings(ctx, e.TeamID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !settings.NotifyAssigned) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	team, err := n.store.GetTeam(ctx, e.TeamID)
+	if err != nil {
+		return err
+	}
+
+	date := data.Turn.Date.In(n.location)
+	text, err := Render(settings.AssignedTemplate.String, DefaultAssignedTemplate, service.SlackTemplateData{
+		Team:   team,
+		Person: data.Person,
+		Date:   date,
+	})
+	if err != nil {
+		return err
+	}
+
+	return n.post(ctx, settings.WebhookUrl, HostMessage(text, date))
+}
+
+// remind announces today's host of the team, if it has one and it wasn't
+// announced yet. It reports whether the message was posted.
+func (n *Notifier) remind(ctx context.Context, settings db.SlackSetting, today time.Time) (bool, error) {
+	turn, err := n.store.GetTurnByDateAndTeam(ctx, db.GetTurnByDateAndTeamParams{
+		Date:   today,
+		TeamID: settings.TeamID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	result, err := n.store.ClaimSlackReminder(ctx, db.ClaimSlackReminderParams{
+		Date:   today,
+		TeamID: settings.TeamID,
+	})
+	if err != nil {
+		return false, err
+	}
+	if claimed, err := result.RowsAffected(); err != nil || claimed == 0 {
+		return false, err
+	}
+
+	if err = n.postReminder(ctx, settings, turn.PersonID, today); err != nil {
+		// The claim must be released even when the notifier is closing, so
+		// the reminder is sent on the next check.
+		release := db.ReleaseSlackReminderParams{
+			TeamID:         settings.TeamID,
+			LastReminderOn: sql.NullTime{Time: today, Valid: true},
+		}
+		if err := n.store.ReleaseSlackReminder(context.Background(), release); err != nil {
+			util.LoggerOr(ctx, n.logger).WithError(err).WithField("team_id", settings.TeamID).Error("cannot release slack reminder")
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// postReminder announces the person as today's host of the team.
+func (n *Notifier) postReminder(ctx context.Context, settings db.SlackSetting, personID int64, today time.Time) error {
+	team, err := n.store.GetTeam(ctx, settings.TeamID)
+	if err != nil {
+		return err
+	}
+
+	person, err := n.store.GetPerson(ctx, db.GetPersonParams{
+		ID:     personID,
+		TeamID: settings.TeamID,
+	})
+	if err != nil {
+		return err
+	}
+
+	text, err := Render(settings.ReminderTemplate.String, DefaultReminderTemplate, service.SlackTemplateData{
+		Team:   team,
+		Person: person,
+		Date:   today,
+	})
+	if err != nil {
+		return err
+	}
+
+	return n.post(ctx, settings.WebhookUrl, HostMessage(text, today))
+}
+
+// post sends message to a Slack incoming webhook. Settings stored before
+// the URLs were checked may point elsewhere, so they're checked again.
+func (n *Notifier) post(ctx context.Context, webhookURL string, message Message) error {
+	if !strings.HasPrefix(webhookURL, n.webhookURLs) {
+		return errors.Errorf("webhook URL %q isn't a Slack incoming webhook", webhookURL)
+	}
+	return Post(ctx, n.client, webhookURL, message)
+}
+
+// Post sends message to a Slack incoming webhook or response URL, non 2xx
+// responses are errors.
+func Post(ctx context.Context, client *http.Client, webhookURL string, message Message) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package slack
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
+	"github.com/ezerw/wheel/service"
+	"github.com/ezerw/wheel/util"
+)
+
+func TestNotifier(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		messages []Message
+		failNext bool
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message Message
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Errorf("cannot decode message: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if failNext {
+			failNext = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		messages = append(messages, message)
+	}))
+	defer receiver.Close()
+
+	today := time.Date(2021, time.June, 28, 0, 0, 0, 0, time.UTC)
+	person := db.GetPersonRow{ID: 3, FirstName: "Bruce", LastName: "Wayne", TeamID: 7}
+	store := &notifierStore{
+		settings: db.SlackSetting{
+			TeamID:           7,
+			WebhookUrl:       receiver.URL,
+			NotifyAssigned:   true,
+			ReminderEnabled:  true,
+			ReminderTime:     "09:00",
+			AssignedTemplate: sql.NullString{String: "{{.Person.FirstName}} hosts {{.Team.Name}}", Valid: true},
+		},
+		team:   db.GetTeamRow{ID: 7, Name: "Trading"},
+		person: person,
+		turn:   db.GetTurnByDateAndTeamRow{ID: 1, PersonID: 3, Date: today},
+	}
+	notifier, err := NewNotifier(store, util.Config{AppTimezone: "UTC"}, util.NewLogger())
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+	defer notifier.Close()
+	notifier.webhookURLs = receiver.URL
+
+	e, err := event.New(event.TurnAssigned, 7, service.TurnEventData{
+		Turn:   service.TurnAPI{ID: 1, PersonID: 3, Date: today},
+		Person: person,
+	})
+	if err != nil {
+		t.Fatalf("cannot create event: %v", err)
+	}
+	if err = notifier.Deliver(context.Background(), e); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	// Too early, then due three times: the failed reminder is sent again,
+	// and only once.
+	notifier.SendReminders(context.Background(), today.Add(8*time.Hour))
+	failNext = true
+	notifier.SendReminders(context.Background(), today.Add(9*time.Hour))
+	if store.settings.LastReminderOn.Valid {
+		t.Error("the claim of the failed reminder wasn't released")
+	}
+	notifier.SendReminders(context.Background(), today.Add(10*time.Hour))
+	notifier.SendReminders(context.Background(), today.Add(11*time.Hour))
+
+	if len(messages) != 2 {
+		t.Fatalf("posted %d messages, want 2", len(messages))
+	}
+	if messages[0].Text != "Bruce hosts Trading" {
+		t.Errorf("assigned text = %q, want the team template", messages[0].Text)
+	}
+	if len(messages[0].Blocks) != 2 || messages[0].Blocks[0].Text.Text != messages[0].Text {
+		t.Errorf("assigned blocks = %+v, want a section with the text and a context", messages[0].Blocks)
+	}
+	if messages[1].Text != "Good morning Trading! *Bruce Wayne* is hosting today." {
+		t.Errorf("reminder text = %q, want the default template", messages[1].Text)
+	}
+}
+
+func TestNotifierPostsToSlackOnly(t *testing.T) {
+	notifier, err := NewNotifier(&notifierStore{}, util.Config{AppTimezone: "UTC"}, util.NewLogger())
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+	defer notifier.Close()
+
+	err = notifier.post(context.Background(), "http://169.254.169.254/latest/meta-data", Message{Text: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "isn't a Slack incoming webhook") {
+		t.Errorf("post to a metadata service = %v, want it refused", err)
+	}
+}
+
+// notifierStore is an in-memory db.Store implementing the queries used by the Notifier.
+type notifierStore struct {
+	db.Store
+
+	settings db.SlackSetting
+	team     db.GetTeamRow
+	person   db.GetPersonRow
+	turn     db.GetTurnByDateAndTeamRow
+}
+
+func (s *notifierStore) GetSlackSettings(_ context.Context, teamID int64) (db.SlackSetting, error) {
+	if teamID != s.settings.TeamID {
+		return db.SlackSetting{}, sql.ErrNoRows
+	}
+	return s.settings, nil
+}
+
+func (s *notifierStore) ListSlackReminders(_ context.Context) ([]db.SlackSetting, error) {
+	return []db.SlackSetting{s.settings}, nil
+}
+
+func (s *notifierStore) ClaimSlackReminder(_ context.Context, arg db.ClaimSlackReminderParams) (sql.Result, error) {
+	if s.settings.LastReminderOn.Valid && !s.settings.LastReminderOn.Time.Before(arg.Date) {
+		return claimResult(0), nil
+	}
+	s.settings.LastReminderOn = sql.NullTime{Time: arg.Date, Valid: true}
+	return claimResult(1), nil
+}
+
+func (s *notifierStore) ReleaseSlackReminder(_ context.Context, arg db.ReleaseSlackReminderParams) error {
+	if arg.TeamID == s.settings.TeamID && s.settings.LastReminderOn == arg.LastReminderOn {
+		s.settings.LastReminderOn = sql.NullTime{}
+	}
+	return nil
+}
+
+func (s *notifierStore) GetTeam(_ context.Context, _ int64) (db.GetTeamRow, error) {
+	return s.team, nil
+}
+
+func (s *notifierStore) GetPerson(_ context.Context, _ db.GetPersonParams) (db.GetPersonRow, error) {
+	return s.person, nil
+}
+
+func (s *notifierStore) GetTurnByDateAndTeam(_ context.Context, arg db.GetTurnByDateAndTeamParams) (db.GetTurnByDateAndTeamRow, error) {
+	if !arg.Date.Equal(s.turn.Date) {
+		return db.GetTurnByDateAndTeamRow{}, sql.ErrNoRows
+	}
+	return s.turn, nil
+}
+
+// claimResult is the sql.Result of ClaimSlackReminder.
+type claimResult int64
+
+func (r claimResult) LastInsertId() (int64, error) { return 0, nil }
+func (r claimResult) RowsAffected() (int64, error) { return int64(r), nil }
@@ -0,0 +1,57 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+// Command is the payload of a slash command.
+type Command struct {
+	Command     string
+	Text        string
+	ChannelID   string
+	UserID      string
+	ResponseURL string
+}
+
+// ParseCommand reads a slash command from its form encoded body.
+func ParseCommand(form url.Values) Command {
+	return Command{
+		Command:     form.Get("command"),
+		Text:        form.Get("text"),
+		ChannelID:   form.Get("channel_id"),
+		UserID:      form.Get("user_id"),
+		ResponseURL: form.Get("response_url"),
+	}
+}
+
+// ResponseURLPrefix starts the URLs Slack gives to reply to commands and
+// interactions, nothing else may be posted to.
+const ResponseURLPrefix = "https://hooks.slack.com/"
+
+// Interaction is the payload sent when a user clicks a button of a message.
+type Interaction struct {
+	Type string `json:"type"`
+	User struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	ResponseURL string   `json:"response_url"`
+	Actions     []Action `json:"actions"`
+}
+
+// Action is a clicked element of an interaction.
+type Action struct {
+	ActionID string `json:"action_id"`
+	Value    string `json:"value"`
+}
+
+// ParseInteraction reads an interaction from the payload field of its form
+// encoded body.
+func ParseInteraction(form url.Values) (Interaction, error) {
+	var interaction Interaction
+	err := json.Unmarshal([]byte(form.Get("payload")), &interaction)
+	return interaction, err
+}
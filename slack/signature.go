@@ -0,0 +1,37 @@
+package slack
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ezerw/wheel/signing"
+)
+
+// Headers Slack signs its requests with.
+const (
+	TimestampHeader = "X-Slack-Request-Timestamp"
+	SignatureHeader = "X-Slack-Signature"
+)
+
+// signatureVersion prefixes the signed content and the signature.
+const signatureVersion = "v0"
+
+// Sign returns the signature header value of a request: the hex encoded
+// HMAC-SHA256 of "v0:<timestamp>:<body>" keyed with the signing secret.
+func Sign(secret string, timestamp string, body []byte) string {
+	return signatureVersion + "=" + signing.HMAC(secret, []byte(signatureVersion+":"+timestamp+":"), body)
+}
+
+// Verify checks the signature headers of a request sent by Slack within
+// tolerance.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	if secret == "" {
+		return errors.New("no signing secret configured")
+	}
+
+	return signing.Verify(header.Get(TimestampHeader), header.Get(SignatureHeader), tolerance, func(timestamp string) string {
+		return Sign(secret, timestamp, body)
+	})
+}
@@ -0,0 +1,45 @@
+package slack
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// The example of https://api.slack.com/authentication/verifying-requests-from-slack.
+	body := "token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V" +
+		"&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=" +
+		"&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN" +
+		"&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c"
+	want := "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503"
+
+	if got := Sign("8f742231b10e8888abcd99yyyzzz85a5", "1531420618", []byte(body)); got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte("command=%2Fwheel&text=spin")
+	signed := func(at time.Time) http.Header {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		header := http.Header{}
+		header.Set(TimestampHeader, timestamp)
+		header.Set(SignatureHeader, Sign("secret", timestamp, body))
+		return header
+	}
+
+	if err := Verify("secret", signed(time.Now()), body, time.Minute); err != nil {
+		t.Errorf("Verify rejected a valid request: %v", err)
+	}
+	if err := Verify("secret", signed(time.Now()), []byte("command=%2Fwheel&text=who"), time.Minute); err == nil {
+		t.Error("Verify accepted a tampered body")
+	}
+	if err := Verify("", signed(time.Now()), body, time.Minute); err == nil {
+		t.Error("Verify accepted a request without a signing secret configured")
+	}
+	if err := Verify("secret", signed(time.Now().Add(-time.Hour)), body, time.Minute); err == nil {
+		t.Error("Verify accepted a stale timestamp")
+	}
+}
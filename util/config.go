@@ -1,10 +1,26 @@
 package util
 
-import "github.com/spf13/viper"
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
 
 // Config stores all configuration of the application.
 // The values are read by viper from a config file or environment variable.
 type Config struct {
+	// File is the config file read, if any.
+	File string `mapstructure:"-"`
+
 	AppAddress  string `mapstructure:"APP_ADDRESS"`
 	AppPort     string `mapstructure:"APP_PORT"`
 	AppDebug    bool   `mapstructure:"APP_DEBUG"`
@@ -14,21 +30,243 @@ type Config struct {
 	DBUser      string `mapstructure:"DB_USER"`
 	DBPassword  string `mapstructure:"DB_PASSWORD"`
 	DBName      string `mapstructure:"DB_NAME"`
+
+	DBMaxOpenConns       int           `mapstructure:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns       int           `mapstructure:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime    time.Duration `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime    time.Duration `mapstructure:"DB_CONN_MAX_IDLE_TIME"`
+	DBConnectAttempts    int           `mapstructure:"DB_CONNECT_ATTEMPTS"`
+	DBConnectBackoff     time.Duration `mapstructure:"DB_CONNECT_BACKOFF"`
+	DBQueryTimeout       time.Duration `mapstructure:"DB_QUERY_TIMEOUT"`
+	DBSlowQueryThreshold time.Duration `mapstructure:"DB_SLOW_QUERY_THRESHOLD"`
+
+	HTTPReadHeaderTimeout time.Duration `mapstructure:"HTTP_READ_HEADER_TIMEOUT"`
+	HTTPReadTimeout       time.Duration `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPWriteTimeout      time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout       time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	HTTPMaxHeaderBytes    int           `mapstructure:"HTTP_MAX_HEADER_BYTES"`
+	ShutdownTimeout       time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	TLSCertFile           string        `mapstructure:"TLS_CERT_FILE"`
+	TLSKeyFile            string        `mapstructure:"TLS_KEY_FILE"`
+
+	CorsAllowedOrigins []string      `mapstructure:"CORS_ALLOWED_ORIGINS"`
+	CorsAllowedMethods []string      `mapstructure:"CORS_ALLOWED_METHODS"`
+	CorsAllowedHeaders []string      `mapstructure:"CORS_ALLOWED_HEADERS"`
+	CorsMaxAge         time.Duration `mapstructure:"CORS_MAX_AGE"`
+
+	RateLimitReads      int   `mapstructure:"RATE_LIMIT_READS"`
+	RateLimitReadBurst  int   `mapstructure:"RATE_LIMIT_READ_BURST"`
+	RateLimitWrites     int   `mapstructure:"RATE_LIMIT_WRITES"`
+	RateLimitWriteBurst int   `mapstructure:"RATE_LIMIT_WRITE_BURST"`
+	MaxBodyBytes        int64 `mapstructure:"MAX_BODY_BYTES"`
+
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+
+	WebhookMaxAttempts int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookBackoff     time.Duration `mapstructure:"WEBHOOK_BACKOFF"`
+	WebhookTimeout     time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookWorkers     int           `mapstructure:"WEBHOOK_WORKERS"`
+
+	OutboxPollInterval time.Duration `mapstructure:"OUTBOX_POLL_INTERVAL"`
+	OutboxBatchSize    int           `mapstructure:"OUTBOX_BATCH_SIZE"`
+	OutboxMaxAttempts  int           `mapstructure:"OUTBOX_MAX_ATTEMPTS"`
+	OutboxConcurrency  int           `mapstructure:"OUTBOX_CONCURRENCY"`
+
+	SlackTimeout       time.Duration `mapstructure:"SLACK_TIMEOUT"`
+	SlackSigningSecret string        `mapstructure:"SLACK_SIGNING_SECRET"`
+
+	SMTPHost          string        `mapstructure:"SMTP_HOST"`
+	SMTPPort          string        `mapstructure:"SMTP_PORT"`
+	SMTPUsername      string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword      string        `mapstructure:"SMTP_PASSWORD"`
+	SMTPFrom          string        `mapstructure:"SMTP_FROM"`
+	SMTPTimeout       time.Duration `mapstructure:"SMTP_TIMEOUT"`
+	EmailReminderTime string        `mapstructure:"EMAIL_REMINDER_TIME"`
+
+	CalendarSecret string `mapstructure:"CALENDAR_SECRET"`
+
+	SchedulerInterval time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	PurgeAfterDays    int           `mapstructure:"PURGE_AFTER_DAYS"`
+
+	SpinDuration time.Duration `mapstructure:"SPIN_DURATION"`
+
+	ReadinessTimeout time.Duration `mapstructure:"READINESS_TIMEOUT"`
+
+	TracingExporter    string  `mapstructure:"TRACING_EXPORTER"`
+	TracingEndpoint    string  `mapstructure:"TRACING_ENDPOINT"`
+	TracingInsecure    bool    `mapstructure:"TRACING_INSECURE"`
+	TracingSampleRatio float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+}
+
+// configName is the name of the config file, app.env or app.yaml.
+const configName = "app"
+
+// defaults are the values of the keys unset in the config file and the
+// environment which the API can't start without.
+var defaults = map[string]interface{}{
+	"APP_PORT":     "8080",
+	"APP_TIMEZONE": "UTC",
+	"DB_PORT":      "3306",
 }
 
-// LoadConfig reads configuration from file or environment variables.
+// LoadConfig reads configuration from the environment and the app.env or
+// app.yaml file in path, if any. The environment wins over the file, and a
+// KEY_FILE variable sets KEY to the content of the file it names, for the
+// secrets mounted as files. The config is validated once loaded.
 func LoadConfig(path string) (config Config, err error) {
-	viper.AddConfigPath(path)
-	viper.SetConfigName("app")
-	viper.SetConfigType("env")
+	v := viper.New()
+	v.AddConfigPath(path)
+	v.SetConfigName(configName)
+
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+	// AutomaticEnv alone doesn't make Unmarshal see the keys missing from
+	// the file.
+	for _, key := range configKeys() {
+		if err = v.BindEnv(key); err != nil {
+			return
+		}
+	}
+	v.AutomaticEnv()
 
-	viper.AutomaticEnv()
+	if err = v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return config, errors.Wrap(err, "cannot read the config file")
+		}
+	}
+	if err = readSecretFiles(v); err != nil {
+		return
+	}
 
-	err = viper.ReadInConfig()
-	if err != nil {
+	if err = v.Unmarshal(&config); err != nil {
 		return
 	}
+	config.File = v.ConfigFileUsed()
 
-	err = viper.Unmarshal(&config)
+	err = config.Validate()
 	return
 }
+
+// readSecretFiles sets the keys whose KEY_FILE variable is set to the
+// content of the file it names, less the trailing newline.
+func readSecretFiles(v *viper.Viper) error {
+	for _, key := range configKeys() {
+		file, ok := os.LookupEnv(key + "_FILE")
+		if !ok {
+			continue
+		}
+		if _, ok := os.LookupEnv(key); ok {
+			return errors.Errorf("%s and %s_FILE are both set", key, key)
+		}
+
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Wrapf(err, "cannot read %s_FILE", key)
+		}
+		v.Set(key, strings.TrimRight(string(content), "\r\n"))
+	}
+	return nil
+}
+
+// configKeys lists the keys of the config.
+func configKeys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("mapstructure"); key != "" && key != "-" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ConfigError lists the problems found validating a config.
+type ConfigError []string
+
+func (e ConfigError) Error() string {
+	return "invalid config: " + strings.Join(e, "; ")
+}
+
+// Validate checks the config, returning a ConfigError listing every
+// problem found.
+func (config Config) Validate() error {
+	var problems ConfigError
+	invalid := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if _, err := time.LoadLocation(config.AppTimezone); err != nil {
+		invalid("APP_TIMEZONE: %v", err)
+	}
+	if _, _, err := net.SplitHostPort(config.AppAddress); err == nil {
+		invalid("APP_ADDRESS: %q has a port, set it in APP_PORT", config.AppAddress)
+	}
+	ports := []struct{ key, value string }{
+		{"APP_PORT", config.AppPort},
+		{"DB_PORT", config.DBPort},
+		{"SMTP_PORT", config.SMTPPort},
+	}
+	for _, port := range ports {
+		if port.value == "" && port.key == "SMTP_PORT" {
+			continue
+		}
+		if n, err := strconv.Atoi(port.value); err != nil || n < 1 || n > 65535 {
+			invalid("%s: %q isn't a port number", port.key, port.value)
+		}
+	}
+	required := []struct{ key, value string }{
+		{"DB_HOST", config.DBHost},
+		{"DB_USER", config.DBUser},
+		{"DB_NAME", config.DBName},
+	}
+	for _, field := range required {
+		if field.value == "" {
+			invalid("%s: required", field.key)
+		}
+	}
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		invalid("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	for _, origin := range config.CorsAllowedOrigins {
+		if !validOrigin(strings.TrimSpace(origin)) {
+			invalid("CORS_ALLOWED_ORIGINS: %q isn't an origin like https://*.example.com", origin)
+		}
+	}
+	if _, err := ParseNetworks(config.TrustedProxies); err != nil {
+		invalid("TRUSTED_PROXIES: %v", err)
+	}
+	if config.EmailReminderTime != "" {
+		if _, err := time.Parse("15:04", config.EmailReminderTime); err != nil {
+			invalid("EMAIL_REMINDER_TIME: %q isn't a time like 09:30", config.EmailReminderTime)
+		}
+	}
+	switch config.TracingExporter {
+	case "", "stdout", "otlp":
+	default:
+		invalid("TRACING_EXPORTER: %q isn't stdout nor otlp", config.TracingExporter)
+	}
+	if config.TracingSampleRatio < 0 || config.TracingSampleRatio > 1 {
+		invalid("TRACING_SAMPLE_RATIO: %v isn't between 0 and 1", config.TracingSampleRatio)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
+
+// validOrigin reports whether origin is *, or a scheme and host whose
+// first label may be a wildcard.
+func validOrigin(origin string) bool {
+	if origin == "*" || origin == "" {
+		return true
+	}
+	if strings.Contains(origin, "://*.") {
+		origin = strings.Replace(origin, "://*.", "://wildcard.", 1)
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && parsed.Scheme != "" && parsed.Host != "" &&
+		!strings.Contains(parsed.Host, "*") && parsed.User == nil &&
+		parsed.Path == "" && parsed.RawQuery == "" && parsed.Fragment == ""
+}
@@ -0,0 +1,65 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	yaml := "db_host: db.internal\ndb_user: wheel\ndb_name: wheel\napp_timezone: Pacific/Auckland\n"
+	if err := os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(dir, "db_password")
+	if err := os.WriteFile(secret, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DB_NAME", "wheel_test")
+	t.Setenv("DB_PASSWORD_FILE", secret)
+
+	config, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if config.DBHost != "db.internal" || config.AppTimezone != "Pacific/Auckland" {
+		t.Errorf("got DB host %q and timezone %q, want the file's", config.DBHost, config.AppTimezone)
+	}
+	if config.DBName != "wheel_test" {
+		t.Errorf("got DB name %q, want the environment's", config.DBName)
+	}
+	if config.DBPassword != "s3cret" {
+		t.Errorf("got DB password %q, want the secret file's", config.DBPassword)
+	}
+	if config.AppPort != "8080" {
+		t.Errorf("got app port %q, want the default", config.AppPort)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	config := Config{
+		AppAddress:  "0.0.0.0:8080",
+		AppPort:     "http",
+		AppTimezone: "Mars/Olympus",
+		DBPort:      "3306",
+		DBUser:      "wheel",
+		DBName:      "wheel",
+	}
+
+	err := config.Validate()
+	problems, ok := err.(ConfigError)
+	if !ok {
+		t.Fatalf("got error %v, want a ConfigError", err)
+	}
+	want := ConfigError{
+		`APP_TIMEZONE: unknown time zone Mars/Olympus`,
+		`APP_ADDRESS: "0.0.0.0:8080" has a port, set it in APP_PORT`,
+		`APP_PORT: "http" isn't a port number`,
+		`DB_HOST: required`,
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("got problems %q, want %q", problems, want)
+	}
+}
@@ -1,6 +1,13 @@
 package util
 
-import "github.com/sirupsen/logrus"
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// loggerKey is the context key of the request-scoped logger.
+type loggerKey struct{}
 
 // NewLogger creates a new instance of logrus with project specific
 // configuration
@@ -14,3 +21,24 @@ func NewLogger() *logrus.Logger {
 
 	return log
 }
+
+// WithLogger returns a copy of ctx carrying logger, so whatever handles the
+// request logs with its fields, such as the request ID.
+func WithLogger(ctx context.Context, logger *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFrom returns the logger carried by ctx, or one of the standard
+// logger when there is none.
+func LoggerFrom(ctx context.Context) *logrus.Entry {
+	return LoggerOr(ctx, logrus.StandardLogger())
+}
+
+// LoggerOr returns the logger carried by ctx, or one of fallback when there
+// is none.
+func LoggerOr(ctx context.Context, fallback *logrus.Logger) *logrus.Entry {
+	if logger, ok := ctx.Value(loggerKey{}).(*logrus.Entry); ok {
+		return logger
+	}
+	return logrus.NewEntry(fallback)
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which the
+// net package doesn't classify.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPublicIP reports whether ip is reachable on the internet, rather than
+// being a loopback, private, link-local or otherwise internal address like
+// the 169.254.169.254 of cloud metadata services.
+func IsPublicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() &&
+		!ip.IsPrivate() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// IsLocalHostname reports whether host names the machine itself.
+func IsLocalHostname(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	return host == "localhost" || strings.HasSuffix(host, ".localhost")
+}
+
+// ParseNetworks parses a list of IPs and CIDR ranges, an IP standing for
+// the network of that address alone.
+func ParseNetworks(list []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(list))
+	for _, value := range list {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if ip := net.ParseIP(value); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, errors.Errorf("%q isn't an IP nor a CIDR range", value)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
@@ -0,0 +1,312 @@
+// Package webhook delivers events to the webhooks teams subscribe.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
+	"github.com/ezerw/wheel/service"
+	"github.com/ezerw/wheel/util"
+)
+
+// Defaults of the webhook deliveries unless set in the config: the attempts
+// of a delivery, the wait after its first failure, the timeout of an attempt
+// and the number of workers.
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = time.Second
+	defaultTimeout     = 10 * time.Second
+	defaultWorkers     = 8
+)
+
+// maxErrorLength is the size of the error column of webhook_deliveries.
+const maxErrorLength = 500
+
+// errClosed is returned by Deliver once the dispatcher is closed.
+var errClosed = errors.New("webhook dispatcher is closed")
+
+// errPrivateAddress is returned when a webhook resolves to an address which
+// isn't public.
+var errPrivateAddress = errors.New("webhook address isn't public")
+
+// Dispatcher delivers events to the webhooks subscribed to them, retrying
+// failed deliveries with exponential backoff and logging every attempt. It's
+// an outbox.Sink, so events failing every attempt are retried by the outbox.
+// Deliveries are made by a fixed number of workers, however many webhooks
+// the events go to.
+type Dispatcher struct {
+	store       db.Store
+	client      *http.Client
+	logger      *logrus.Logger
+	maxAttempts int
+	backoff     time.Duration
+	queue       chan delivery
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// delivery is the delivery of an event to a webhook queued for the workers,
+// which send its outcome to done.
+type delivery struct {
+	ctx     context.Context
+	webhook db.Webhook
+	event   event.Event
+	body    []byte
+	done    chan<- error
+}
+
+// NewDispatcher creates a Dispatcher configured by the WEBHOOK_* settings and
+// starts its workers.
+func NewDispatcher(store db.Store, config util.Config, logger *logrus.Logger) *Dispatcher {
+	d := &Dispatcher{
+		store:       store,
+		client:      &http.Client{Timeout: defaultTimeout, Transport: publicTransport()},
+		logger:      logger,
+		maxAttempts: defaultMaxAttempts,
+		backoff:     defaultBackoff,
+	}
+	if config.WebhookTimeout > 0 {
+		d.client.Timeout = config.WebhookTimeout
+	}
+	if config.WebhookMaxAttempts > 0 {
+		d.maxAttempts = config.WebhookMaxAttempts
+	}
+	if config.WebhookBackoff > 0 {
+		d.backoff = config.WebhookBackoff
+	}
+	workers := defaultWorkers
+	if config.WebhookWorkers > 0 {
+		workers = config.WebhookWorkers
+	}
+
+	d.queue = make(chan delivery)
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// publicTransport is an http.Transport which only connects to public
+// addresses. The check is made on the address being dialled, once host names
+// are resolved, so a webhook can't reach the internal network by resolving to
+// it after being validated. Proxies aren't used as they'd be dialled instead.
+func publicTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_ string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !util.IsPublicIP(ip) {
+				return errors.Wrapf(errPrivateAddress, "cannot dial %s", host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// Deliver delivers e to every webhook of its team subscribed to its type,
+// waiting for the workers to be done with all of them. It fails when a
+// webhook still fails once its attempts run out, the ones which succeeded get
+// the event again when it's retried.
+func (d *Dispatcher) Deliver(ctx context.Context, e event.Event) error {
+	webhooks, err := d.store.ListWebhooks(ctx, e.TeamID)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	subscribed := []db.Webhook{}
+	for _, webhook := range webhooks {
+		if service.Subscribed(webhook, e.Type) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+
+	// Buffered so the workers never wait for Deliver to read the outcomes.
+	done := make(chan error, len(subscribed))
+	queued, err := d.enqueue(ctx, subscribed, e, body, done)
+
+	var (
+		failed int
+		last   error
+	)
+	for i := 0; i < queued; i++ {
+		if deliveryErr := <-done; deliveryErr != nil {
+			failed++
+			last = deliveryErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d webhook(s) failed: %w", failed, last)
+	}
+	return nil
+}
+
+// Close stops taking deliveries, abandons pending retries and waits for the
+// workers to finish the attempts they're making.
+func (d *Dispatcher) Close() {
+	// Cancelling first gets the workers and enqueue out of their waits.
+	d.cancel()
+
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+// enqueue hands the deliveries of e to the workers until ctx is done or the
+// dispatcher is closed. It returns how many were queued.
+func (d *Dispatcher) enqueue(ctx context.Context, webhooks []db.Webhook, e event.Event, body []byte, done chan<- error) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return 0, errClosed
+	}
+
+	for i, webhook := range webhooks {
+		select {
+		case d.queue <- delivery{ctx: ctx, webhook: webhook, event: e, body: body, done: done}:
+		case <-ctx.Done():
+			return i, ctx.Err()
+		case <-d.ctx.Done():
+			return i, errClosed
+		}
+	}
+	return len(webhooks), nil
+}
+
+// work makes the deliveries of the queue until it's closed.
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for job := range d.queue {
+		job.done <- d.deliver(job.ctx, job.webhook, job.event, job.body)
+	}
+}
+
+// deliver posts body to the webhook until it succeeds, attempts run out or
+// the dispatcher is closed. Waits double after every failed attempt.
+func (d *Dispatcher) deliver(ctx context.Context, webhook db.Webhook, e event.Event, body []byte) error {
+	wait := d.backoff
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		statusCode, err := d.send(ctx, webhook, e, body)
+		d.record(ctx, webhook, e, attempt, statusCode, err, time.Since(start))
+
+		if err == nil {
+			return nil
+		}
+
+		util.LoggerOr(ctx, d.logger).WithError(err).WithFields(logrus.Fields{
+			"webhook_id": webhook.ID,
+			"event_id":   e.ID,
+			"attempt":    attempt,
+		}).Warn("webhook delivery failed")
+
+		if attempt == d.maxAttempts {
+			return err
+		}
+
+		select {
+		case <-time.After(wait):
+			wait *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.ctx.Done():
+			return d.ctx.Err()
+		}
+	}
+}
+
+// send makes one signed delivery attempt, non 2xx responses are errors.
+func (d *Dispatcher) send(ctx context.Context, webhook db.Webhook, e event.Event, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wheel-webhooks")
+	req.Header.Set(EventHeader, string(e.Type))
+	req.Header.Set(DeliveryHeader, e.ID)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// record stores a delivery attempt in the delivery log.
+func (d *Dispatcher) record(ctx context.Context, webhook db.Webhook, e event.Event, attempt int, statusCode int, err error, duration time.Duration) {
+	args := db.CreateWebhookDeliveryParams{
+		WebhookID:  webhook.ID,
+		EventID:    e.ID,
+		EventType:  string(e.Type),
+		Attempt:    int32(attempt),
+		Succeeded:  err == nil,
+		StatusCode: sql.NullInt32{Int32: int32(statusCode), Valid: statusCode != 0},
+		DurationMs: int32(duration.Milliseconds()),
+	}
+	if err != nil {
+		message := err.Error()
+		if len(message) > maxErrorLength {
+			message = message[:maxErrorLength]
+		}
+		args.Error = sql.NullString{String: message, Valid: true}
+	}
+
+	// The log must be written even when the dispatcher is closing.
+	if _, err = d.store.CreateWebhookDelivery(context.Background(), args); err != nil {
+		util.LoggerOr(ctx, d.logger).WithError(err).WithField("webhook_id", webhook.ID).Error("cannot record webhook delivery")
+	}
+}
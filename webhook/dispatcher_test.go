@@ -0,0 +1,211 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ezerw/wheel/db"
+	"github.com/ezerw/wheel/event"
+	"github.com/ezerw/wheel/util"
+)
+
+func TestDispatcherRetriesSignedDeliveries(t *testing.T) {
+	const secret = "0123456789abcdef"
+
+	var (
+		mu       sync.Mutex
+		requests int
+		errs     []error
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if err := Verify(secret, r.Header, body, time.Minute); err != nil {
+			errs = append(errs, err)
+		}
+		if r.Header.Get(EventHeader) != string(event.TurnAssigned) {
+			t.Errorf("%s = %q, want %q", EventHeader, r.Header.Get(EventHeader), event.TurnAssigned)
+		}
+
+		// Fail the first attempt to exercise the retry.
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	store := &deliveryStore{webhooks: []db.Webhook{
+		{ID: 1, TeamID: 7, Url: receiver.URL, Secret: secret, Events: "turn.assigned"},
+		{ID: 2, TeamID: 7, Url: receiver.URL, Secret: secret, Events: "person.added"},
+	}}
+	config := util.Config{WebhookMaxAttempts: 3, WebhookBackoff: time.Millisecond}
+	dispatcher := NewDispatcher(store, config, util.NewLogger())
+	// The receiver listens on the loopback, which deliveries refuse to dial.
+	dispatcher.client.Transport = http.DefaultTransport
+
+	e, err := event.New(event.TurnAssigned, 7, map[string]int64{"person_id": 1})
+	if err != nil {
+		t.Fatalf("cannot create event: %v", err)
+	}
+	if err = dispatcher.Deliver(context.Background(), e); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	dispatcher.Close()
+
+	if requests != 2 {
+		t.Errorf("receiver got %d requests, want 2", requests)
+	}
+	for _, err := range errs {
+		t.Errorf("signature verification failed: %v", err)
+	}
+
+	if len(store.deliveries) != 2 {
+		t.Fatalf("recorded %d deliveries, want 2", len(store.deliveries))
+	}
+	first, second := store.deliveries[0], store.deliveries[1]
+	if first.Succeeded || first.StatusCode.Int32 != http.StatusServiceUnavailable || first.Attempt != 1 {
+		t.Errorf("first delivery = %+v, want failed attempt 1 with status 503", first)
+	}
+	if !second.Succeeded || second.WebhookID != 1 || second.EventID != e.ID || second.Attempt != 2 {
+		t.Errorf("second delivery = %+v, want successful attempt 2 of webhook 1", second)
+	}
+}
+
+func TestDispatcherBoundsConcurrentDeliveries(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		inFlight int
+		most     int
+		requests int
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		requests++
+		if inFlight > most {
+			most = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	store := &deliveryStore{}
+	for id := int64(1); id <= 6; id++ {
+		store.webhooks = append(store.webhooks, db.Webhook{ID: id, TeamID: 7, Url: receiver.URL, Secret: "0123456789abcdef", Events: "*"})
+	}
+	config := util.Config{WebhookMaxAttempts: 1, WebhookWorkers: 2}
+	dispatcher := NewDispatcher(store, config, util.NewLogger())
+	dispatcher.client.Transport = http.DefaultTransport
+
+	e, err := event.New(event.TurnAssigned, 7, map[string]int64{"person_id": 1})
+	if err != nil {
+		t.Fatalf("cannot create event: %v", err)
+	}
+	if err = dispatcher.Deliver(context.Background(), e); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	dispatcher.Close()
+
+	if requests != 6 {
+		t.Errorf("receiver got %d requests, want 6", requests)
+	}
+	if most > 2 {
+		t.Errorf("receiver got %d concurrent requests, want at most 2", most)
+	}
+	if err = dispatcher.Deliver(context.Background(), e); !errors.Is(err, errClosed) {
+		t.Errorf("Deliver after Close = %v, want %v", err, errClosed)
+	}
+}
+
+func TestDispatcherRefusesPrivateAddresses(t *testing.T) {
+	var requests int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer receiver.Close()
+
+	store := &deliveryStore{webhooks: []db.Webhook{
+		{ID: 1, TeamID: 7, Url: receiver.URL, Secret: "0123456789abcdef", Events: "*"},
+	}}
+	config := util.Config{WebhookMaxAttempts: 1}
+	dispatcher := NewDispatcher(store, config, util.NewLogger())
+	defer dispatcher.Close()
+
+	e, err := event.New(event.TurnAssigned, 7, map[string]int64{"person_id": 1})
+	if err != nil {
+		t.Fatalf("cannot create event: %v", err)
+	}
+	if err = dispatcher.Deliver(context.Background(), e); !errors.Is(err, errPrivateAddress) {
+		t.Errorf("Deliver = %v, want %v", err, errPrivateAddress)
+	}
+	if requests != 0 {
+		t.Errorf("receiver got %d requests, want none", requests)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	signed := func(at time.Time) http.Header {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		header := http.Header{}
+		header.Set(TimestampHeader, timestamp)
+		header.Set(SignatureHeader, Sign("secret", timestamp, body))
+		return header
+	}
+
+	if err := Verify("secret", signed(time.Now()), body, time.Minute); err != nil {
+		t.Errorf("Verify rejected a valid delivery: %v", err)
+	}
+	if err := Verify("secret", signed(time.Now()), []byte(`{"id":"2"}`), time.Minute); err == nil {
+		t.Error("Verify accepted a tampered body")
+	}
+	if err := Verify("other", signed(time.Now()), body, time.Minute); err == nil {
+		t.Error("Verify accepted the wrong secret")
+	}
+	if err := Verify("secret", signed(time.Now().Add(-time.Hour)), body, time.Minute); err == nil {
+		t.Error("Verify accepted a stale timestamp")
+	}
+}
+
+// deliveryStore is an in-memory db.Store implementing the queries used by the Dispatcher.
+type deliveryStore struct {
+	db.Store
+
+	mu         sync.Mutex
+	webhooks   []db.Webhook
+	deliveries []db.CreateWebhookDeliveryParams
+}
+
+func (s *deliveryStore) ListWebhooks(_ context.Context, teamID int64) ([]db.Webhook, error) {
+	webhooks := []db.Webhook{}
+	for _, webhook := range s.webhooks {
+		if webhook.TeamID == teamID {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (s *deliveryStore) CreateWebhookDelivery(_ context.Context, arg db.CreateWebhookDeliveryParams) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, arg)
+	return nil, nil
+}
@@ -0,0 +1,33 @@
+package webhook
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ezerw/wheel/signing"
+)
+
+// Headers sent with every delivery.
+const (
+	EventHeader     = "X-Wheel-Event"
+	DeliveryHeader  = "X-Wheel-Delivery"
+	TimestampHeader = "X-Wheel-Timestamp"
+	SignatureHeader = "X-Wheel-Signature"
+)
+
+// signaturePrefix names the algorithm of the signature header value.
+const signaturePrefix = "sha256="
+
+// Sign returns the signature header value of a delivery: the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+func Sign(secret string, timestamp string, body []byte) string {
+	return signaturePrefix + signing.HMAC(secret, []byte(timestamp+"."), body)
+}
+
+// Verify checks the signature headers of a delivery received by a webhook
+// within tolerance.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	return signing.Verify(header.Get(TimestampHeader), header.Get(SignatureHeader), tolerance, func(timestamp string) string {
+		return Sign(secret, timestamp, body)
+	})
+}